@@ -0,0 +1,329 @@
+package multiresource
+
+import (
+	"context"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// collectInterval controls how often collectClusterWideStats runs,
+// configurable via MULTIRESOURCE_COLLECT_INTERVAL (default 15s).
+func collectInterval() time.Duration {
+	if val := os.Getenv("MULTIRESOURCE_COLLECT_INTERVAL"); val != "" {
+		if secs, err := strconv.Atoi(val); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return 15 * time.Second
+}
+
+// maxStatsAge bounds how old a node's cached stats may be before Filter/
+// Score treat them as missing and fall back to the on-demand per-node
+// query path, configurable via MULTIRESOURCE_MAX_AGE (default 60s).
+func maxStatsAge() time.Duration {
+	if val := os.Getenv("MULTIRESOURCE_MAX_AGE"); val != "" {
+		if secs, err := strconv.Atoi(val); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return 60 * time.Second
+}
+
+// diskNetSmoothWindow/diskNetSmoothStep control the range query used to
+// smooth bursty disk/network throughput instead of trusting a single
+// instant sample.
+const (
+	diskNetSmoothWindow = 2 * time.Minute
+	diskNetSmoothStep   = 30 * time.Second
+)
+
+// collectResourceQueries returns the raw per-node results for every
+// resource, keyed by spec name. Both collectClusterWideStats (all nodes, on
+// a timer) and getNodeStats (one node, on a cache miss) build a NodeStats
+// from this same data via buildClusterNodeStats, so they agree on defaults
+// and Kind handling.
+//
+// When the configured metrics backend accepts arbitrary PromQL (Prometheus,
+// Thanos; see PromQLQuerier), every spec.PromQL/CapacityPromQL in
+// p.resourceSpecs runs as-is, so operator-defined resources work exactly as
+// before the backend became pluggable. Backends that only expose the
+// MetricsClient interface (metrics-server, Elasticsearch) fall back to
+// collectResourceQueriesGeneric, which can only populate the 6 resources
+// the Node* methods cover (cpu/mem/disk-read/disk-write/net-up/net-down);
+// "gpu" and any custom resourceSpecs entry are simply absent from the
+// result and fall back to that spec's Default, the same as a missing
+// Prometheus sample always has.
+func (p *Plugin) collectResourceQueries(ctx context.Context) (instant, capacity, smoothed map[string]map[string]float64) {
+	querier, ok := p.metricsClient.(PromQLQuerier)
+	if !ok {
+		return p.collectResourceQueriesGeneric(ctx)
+	}
+
+	instant = make(map[string]map[string]float64, len(p.resourceSpecs))
+	capacity = make(map[string]map[string]float64, len(p.resourceSpecs))
+	smoothed = make(map[string]map[string]float64, len(p.resourceSpecs))
+
+	end := time.Now()
+	start := end.Add(-diskNetSmoothWindow)
+
+	for _, spec := range p.resourceSpecs {
+		if spec.Smoothed {
+			series, err := querier.QueryRange(ctx, spec.PromQL, start, end, diskNetSmoothStep)
+			if err != nil {
+				klog.Warningf("Error collecting cluster-wide %s history: %v", spec.Name, err)
+				continue
+			}
+			avg := make(map[string]float64, len(series))
+			for node, samples := range series {
+				avg[node] = mean(samples)
+			}
+			smoothed[spec.Name] = avg
+		} else {
+			result, err := querier.Query(ctx, spec.PromQL)
+			if err != nil {
+				klog.Warningf("Error collecting cluster-wide %s metrics: %v", spec.Name, err)
+				continue
+			}
+			instant[spec.Name] = result
+		}
+
+		if spec.CapacityPromQL == "" {
+			continue
+		}
+		capResult, err := querier.Query(ctx, spec.CapacityPromQL)
+		if err != nil {
+			klog.Warningf("Error collecting cluster-wide %s capacity: %v", spec.Name, err)
+			continue
+		}
+		capacity[spec.Name] = capResult
+	}
+
+	return instant, capacity, smoothed
+}
+
+// collectResourceQueriesGeneric collects the 6 built-in resources through
+// MetricsClient's Node* methods instead of PromQL, one call per node per
+// resource, for backends (metrics-server, Elasticsearch) that can't run
+// arbitrary queries. disk-read/disk-write/net-up/net-down land in smoothed
+// rather than instant to match those specs' Smoothed:true (see
+// defaultMetricQuerySpecs); a MetricsClient method's error just leaves that
+// node/resource absent, same as collectResourceQueries does on a query error.
+func (p *Plugin) collectResourceQueriesGeneric(ctx context.Context) (instant, capacity, smoothed map[string]map[string]float64) {
+	instant = map[string]map[string]float64{"cpu": {}, "mem": {}}
+	capacity = map[string]map[string]float64{"cpu": {}, "mem": {}}
+	smoothed = map[string]map[string]float64{"disk-read": {}, "disk-write": {}, "net-up": {}, "net-down": {}}
+
+	if p.handle == nil || p.handle.ClientSet() == nil {
+		return instant, capacity, smoothed
+	}
+	nodes, err := p.handle.ClientSet().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Warningf("Error listing nodes for generic metrics collection: %v", err)
+		return instant, capacity, smoothed
+	}
+
+	for _, node := range nodes.Items {
+		nodeName := node.Name
+
+		if usage, total, err := p.metricsClient.NodeCPUUsage(ctx, nodeName); err != nil {
+			klog.Warningf("Error collecting CPU usage for node %s: %v", nodeName, err)
+		} else {
+			instant["cpu"][nodeName] = usage
+			capacity["cpu"][nodeName] = total
+		}
+
+		if free, total, err := p.metricsClient.NodeMemUsage(ctx, nodeName); err != nil {
+			klog.Warningf("Error collecting memory usage for node %s: %v", nodeName, err)
+		} else {
+			instant["mem"][nodeName] = free
+			capacity["mem"][nodeName] = total
+		}
+
+		if readBps, writeBps, err := p.metricsClient.NodeIO(ctx, nodeName); err != nil {
+			klog.V(4).Infof("Error collecting disk I/O for node %s: %v", nodeName, err)
+		} else {
+			smoothed["disk-read"][nodeName] = readBps
+			smoothed["disk-write"][nodeName] = writeBps
+		}
+
+		if up, down, err := p.metricsClient.NodeNet(ctx, nodeName); err != nil {
+			klog.V(4).Infof("Error collecting network throughput for node %s: %v", nodeName, err)
+		} else {
+			smoothed["net-up"][nodeName] = up
+			smoothed["net-down"][nodeName] = down
+		}
+	}
+
+	return instant, capacity, smoothed
+}
+
+// collectClusterWideStats replaces the old per-node fan-out (one query per
+// resource per node every cycle) with one cluster-wide query per
+// spec.PromQL/CapacityPromQL on a PromQLQuerier backend, fanning the
+// results out into p.nodeStats under a single lock. Per-device GPU stats
+// (withGPUDevices) and the generic MetricsClient path still query once
+// per node.
+func (p *Plugin) collectClusterWideStats(ctx context.Context) {
+	instant, capacity, smoothed := p.collectResourceQueries(ctx)
+
+	nodes := make(map[string]bool)
+	for _, m := range instant {
+		for node := range m {
+			nodes[node] = true
+		}
+	}
+	for _, m := range smoothed {
+		for node := range m {
+			nodes[node] = true
+		}
+	}
+
+	now := time.Now()
+	stats := make(map[string]NodeStats, len(nodes))
+	for node := range nodes {
+		stats[node] = p.withGPUDevices(ctx, node, p.buildClusterNodeStats(instant, capacity, smoothed, node))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for node, s := range stats {
+		p.nodeStats[node] = s
+		p.nodeStatsTime[node] = now
+	}
+}
+
+// withGPUDevices fills in stats.GPUCount/GPUDevices from the metrics
+// backend's per-device breakdown, when it has one (see GPUStatsQuerier). A
+// backend without GPUStatsQuerier, or a node with no GPUs, leaves stats
+// unchanged.
+func (p *Plugin) withGPUDevices(ctx context.Context, node string, stats NodeStats) NodeStats {
+	querier, ok := p.metricsClient.(GPUStatsQuerier)
+	if !ok {
+		return stats
+	}
+
+	devices, err := querier.NodeGPUStats(ctx, node)
+	if err != nil {
+		klog.Warningf("Error collecting GPU stats for node %s: %v", node, err)
+		return stats
+	}
+
+	stats.GPUDevices = devices
+	stats.GPUCount = len(devices)
+	return stats
+}
+
+// mean returns the arithmetic mean of samples, or 0 for an empty slice.
+func mean(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	return sum / float64(len(samples))
+}
+
+// buildClusterNodeStats assembles a NodeStats for node out of the batched
+// query results, one resource per spec in p.resourceSpecs, applying a
+// per-Kind default (spec.Default capacity, or an assumed 80% usage) when a
+// metric is missing for that node.
+func (p *Plugin) buildClusterNodeStats(instant, capacity, smoothed map[string]map[string]float64, node string) NodeStats {
+	stats := NodeStats{Resources: make(map[string]ResourceAvailability, len(p.resourceSpecs))}
+
+	for _, spec := range p.resourceSpecs {
+		values := instant[spec.Name]
+		if spec.Smoothed {
+			values = smoothed[spec.Name]
+		}
+		value, haveValue := values[node]
+		capVal, haveCapacity := capacity[spec.Name][node]
+
+		switch spec.Kind {
+		case KindRatio:
+			if !haveCapacity {
+				capVal = spec.Default
+			}
+			usage := math.Max(0, math.Min(1, value))
+			if !haveValue {
+				usage = 0.8
+			}
+			stats.Resources[spec.Name] = ResourceAvailability{Total: capVal, Free: capVal * (1.0 - usage)}
+		case KindDirect:
+			if !haveCapacity {
+				capVal = spec.Default
+			}
+			free := value
+			if !haveValue {
+				free = capVal * 0.2
+			}
+			stats.Resources[spec.Name] = ResourceAvailability{Total: capVal, Free: free}
+		default: // KindRate
+			if haveCapacity {
+				stats.Resources[spec.Name] = ResourceAvailability{Total: capVal, Free: capVal - value}
+			} else if haveValue {
+				total := value / rateAssumedUtilization
+				stats.Resources[spec.Name] = ResourceAvailability{Total: total, Free: total - value}
+			} else {
+				stats.Resources[spec.Name] = ResourceAvailability{Total: spec.Default, Free: spec.Default * 0.2}
+			}
+		}
+	}
+
+	stats.CPULimitUtilization = limitUtilization(stats.Resources["cpu"])
+	stats.MemLimitUtilization = limitUtilization(stats.Resources["mem"])
+
+	return p.smoothNodeStats(node, stats)
+}
+
+// limitUtilization returns avail's used-vs-total ratio (1 - Free/Total), the
+// way the kubeletstats receiver reports a node's limit utilization, or 0 if
+// Total is 0 (resource not tracked).
+func limitUtilization(avail ResourceAvailability) float64 {
+	if avail.Total <= 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, 1-avail.Free/avail.Total))
+}
+
+// smoothNodeStats blends raw's Free value for every resource into node's
+// running exponentially-weighted moving average (new = alpha*raw +
+// (1-alpha)*old, see Plugin.emaAlpha), replacing the raw instantaneous
+// sample with a steady-state estimate so a transient spike doesn't thrash
+// scheduling decisions. The first sample seen for a node/resource seeds the
+// EMA instead of blending against a zero baseline.
+func (p *Plugin) smoothNodeStats(node string, raw NodeStats) NodeStats {
+	if p.emaAlpha >= 1.0 {
+		return raw
+	}
+
+	p.emaMu.Lock()
+	defer p.emaMu.Unlock()
+
+	ema, ok := p.nodeStatsEMA[node]
+	if !ok {
+		ema = make(map[string]float64, len(raw.Resources))
+		p.nodeStatsEMA[node] = ema
+	}
+
+	smoothed := raw
+	smoothed.Resources = make(map[string]ResourceAvailability, len(raw.Resources))
+	for name, avail := range raw.Resources {
+		prev, seen := ema[name]
+		if !seen {
+			prev = avail.Free
+		}
+		smoothedFree := p.emaAlpha*avail.Free + (1-p.emaAlpha)*prev
+		ema[name] = smoothedFree
+		smoothed.Resources[name] = ResourceAvailability{Total: avail.Total, Free: smoothedFree}
+	}
+	smoothed.CPULimitUtilization = limitUtilization(smoothed.Resources["cpu"])
+	smoothed.MemLimitUtilization = limitUtilization(smoothed.Resources["mem"])
+	return smoothed
+}
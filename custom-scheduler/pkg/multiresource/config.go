@@ -0,0 +1,223 @@
+package multiresource
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Backend* name the metrics backends Args.Backend accepts.
+const (
+	BackendPrometheus    = "prometheus"
+	BackendThanos        = "thanos"
+	BackendMetricsServer = "metrics-server"
+	BackendElasticsearch = "elasticsearch"
+
+	defaultBackend        = BackendPrometheus
+	defaultElasticIndex   = "metricbeat-*"
+	defaultPrometheusAddr = "http://prometheus-server.default.svc.cluster.local:80"
+)
+
+// Args is MultiResource's KubeSchedulerConfiguration pluginConfig.args
+// payload: which metrics backend New should build and its connection
+// parameters, plus the scoring/collection knobs that New previously
+// hardcoded (alpha, maxScore, the scrape interval, per-resource weights),
+// loaded once at startup instead of requiring an env var per setting.
+//
+// This repo doesn't vendor the versioned kube-scheduler config scheme (no
+// k8s.io/kube-scheduler/config/{v1,v1beta3} conversion machinery is
+// checked in here), so unlike a real in-tree plugin Args has no
+// v1/v1beta3 GroupVersion pair to convert between; decodeArgs' two
+// branches (typed passthrough vs. unstructured conversion) are this
+// plugin's stand-in for "decode whatever shape the scheduler config
+// loader handed us".
+type Args struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Backend selects the metrics source: "prometheus" (default), "thanos",
+	// "metrics-server", or "elasticsearch".
+	Backend string `json:"backend,omitempty"`
+
+	// URL is the backend's query endpoint (the Prometheus/Thanos query URL,
+	// or the Elasticsearch base URL). Ignored for "metrics-server", which
+	// always talks to the in-cluster Metrics API via the scheduler's own
+	// Kubernetes client.
+	URL string `json:"url,omitempty"`
+
+	// ThanosPartialResponse/ThanosDedup set the Thanos query-frontend params
+	// of the same name on every query (see thanosRoundTripper); only
+	// meaningful when Backend is "thanos".
+	ThanosPartialResponse bool `json:"thanosPartialResponse,omitempty"`
+	ThanosDedup           bool `json:"thanosDedup,omitempty"`
+
+	// ElasticsearchIndex is the index (or index pattern, e.g. "metricbeat-*")
+	// NodeCPUUsage/NodeMemUsage/etc query against; only meaningful when
+	// Backend is "elasticsearch".
+	ElasticsearchIndex string `json:"elasticsearchIndex,omitempty"`
+
+	// Alpha is the bin-packing/spreading tradeoff StrategyBalanced (and the
+	// weighted-sum fallback) uses; see resourceToScore. Must be in [0, 1].
+	Alpha float64 `json:"alpha,omitempty"`
+	// MaxScore is the ceiling every scoring strategy normalizes to. Must be
+	// positive.
+	MaxScore int `json:"maxScore,omitempty"`
+
+	// ScrapeIntervalSeconds overrides how often collectClusterWideStats
+	// runs (see collectInterval); must be positive.
+	ScrapeIntervalSeconds int `json:"scrapeIntervalSeconds,omitempty"`
+	// PrometheusTimeoutSeconds overrides the per-query timeout Query/
+	// QueryRange apply; must be positive. Only meaningful for the
+	// "prometheus"/"thanos" backends.
+	PrometheusTimeoutSeconds int `json:"prometheusTimeoutSeconds,omitempty"`
+
+	// ResourceWeights overrides StrategyWeightedSum's per-resource weights,
+	// keyed by MetricQuerySpec.Name (e.g. "cpu", "mem", "disk-read"). A
+	// resource with no entry here falls back to its
+	// MULTIRESOURCE_WEIGHT_<NAME> env var, then its built-in default. Every
+	// value must be non-negative.
+	ResourceWeights map[string]float64 `json:"resourceWeights,omitempty"`
+
+	// EnabledResources restricts resourceSpecs to this subset of names,
+	// e.g. ["cpu", "mem"] to disable disk/network tracking entirely. Empty
+	// means all resources from metricQuerySpecsFromEnv are tracked.
+	EnabledResources []string `json:"enabledResources,omitempty"`
+
+	// PreemptionDryRun gates PostFilter's eviction step: when true, a
+	// priority-preemption plan is only logged (see Plugin.PostFilter),
+	// never acted on, so an operator can see what it would do before
+	// turning it loose.
+	PreemptionDryRun bool `json:"preemptionDryRun,omitempty"`
+
+	// Clusters lists the member cluster names (matching the values the
+	// backend's cluster label takes, e.g. PrometheusClient's
+	// clusterLabelName) a central scheduler federates node stats across;
+	// see Plugin.updateAllNodeStats/GetNodeStatsForCluster. Empty disables
+	// federated collection, leaving the existing single-cluster Filter/
+	// Score path unaffected.
+	Clusters []string `json:"clusters,omitempty"`
+}
+
+// DeepCopyObject implements runtime.Object.
+func (a *Args) DeepCopyObject() runtime.Object {
+	if a == nil {
+		return nil
+	}
+	cp := *a
+	return &cp
+}
+
+// GroupVersionKind is the scheme identity New looks for when decoding a
+// generic runtime.Object into Args (see decodeArgs).
+func (a *Args) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "multiresource.config.k8s.io", Version: "v1", Kind: "Args"}
+}
+
+// decodeArgs converts the runtime.Object the scheduler framework passes
+// New (from pluginConfig.args in KubeSchedulerConfiguration) into an *Args.
+// obj is already *Args when the in-process scheduler builds its config
+// programmatically; a config file loaded off disk instead decodes into an
+// *unstructured.Unstructured that needs converting via the unstructured
+// machinery. Any other type (including *runtime.Unknown) is an error.
+// nil (no pluginConfig entry for MultiResource) returns a zero Args, which
+// resolveArgs below fills with the original hardcoded Prometheus default.
+func decodeArgs(obj runtime.Object) (*Args, error) {
+	if obj == nil {
+		return &Args{}, nil
+	}
+	if args, ok := obj.(*Args); ok {
+		return args, nil
+	}
+
+	unstructuredArgs, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("MultiResource plugin args have unsupported type %T", obj)
+	}
+	args := &Args{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredArgs.Object, args); err != nil {
+		return nil, fmt.Errorf("decoding MultiResource plugin args: %w", err)
+	}
+	return args, nil
+}
+
+// defaultAlpha/defaultMaxScore reproduce the values New hardcoded before
+// Args existed.
+const (
+	defaultAlpha    = 0.5
+	defaultMaxScore = 100
+)
+
+// resolveArgs fills in Args' defaults, preserving the plugin's original
+// hardcoded-Prometheus behavior for any field a cluster's
+// KubeSchedulerConfiguration doesn't set.
+func resolveArgs(args *Args) *Args {
+	resolved := *args
+	if resolved.Backend == "" {
+		resolved.Backend = defaultBackend
+	}
+	if resolved.URL == "" {
+		resolved.URL = defaultPrometheusAddr
+	}
+	if resolved.ElasticsearchIndex == "" {
+		resolved.ElasticsearchIndex = defaultElasticIndex
+	}
+	if resolved.Alpha == 0 {
+		resolved.Alpha = defaultAlpha
+	}
+	if resolved.MaxScore == 0 {
+		resolved.MaxScore = defaultMaxScore
+	}
+	return &resolved
+}
+
+// validateArgs rejects Args combinations New can't safely act on: an alpha
+// outside resourceToScore's expected [0, 1] domain, a negative
+// maxScore/scrapeInterval/timeout (0 means unset and takes the default), or
+// a negative resource weight.
+func validateArgs(args *Args) error {
+	if args.Alpha < 0 || args.Alpha > 1 {
+		return fmt.Errorf("alpha must be in [0, 1], got %v", args.Alpha)
+	}
+	if args.MaxScore < 0 {
+		return fmt.Errorf("maxScore must be non-negative, got %v", args.MaxScore)
+	}
+	if args.ScrapeIntervalSeconds < 0 {
+		return fmt.Errorf("scrapeIntervalSeconds must be non-negative, got %v", args.ScrapeIntervalSeconds)
+	}
+	if args.PrometheusTimeoutSeconds < 0 {
+		return fmt.Errorf("prometheusTimeoutSeconds must be non-negative, got %v", args.PrometheusTimeoutSeconds)
+	}
+	for name, weight := range args.ResourceWeights {
+		if weight < 0 {
+			return fmt.Errorf("resourceWeights[%s] must be non-negative, got %v", name, weight)
+		}
+	}
+	switch args.Backend {
+	case "", BackendPrometheus, BackendThanos, BackendMetricsServer, BackendElasticsearch:
+	default:
+		return fmt.Errorf("unknown backend %q (want one of %s/%s/%s/%s)",
+			args.Backend, BackendPrometheus, BackendThanos, BackendMetricsServer, BackendElasticsearch)
+	}
+	return nil
+}
+
+// filterEnabledResources restricts specs to args.EnabledResources, keeping
+// every spec (the original behavior) when EnabledResources is empty.
+func filterEnabledResources(specs []MetricQuerySpec, args *Args) []MetricQuerySpec {
+	if len(args.EnabledResources) == 0 {
+		return specs
+	}
+	enabled := make(map[string]bool, len(args.EnabledResources))
+	for _, name := range args.EnabledResources {
+		enabled[name] = true
+	}
+	filtered := make([]MetricQuerySpec, 0, len(specs))
+	for _, spec := range specs {
+		if enabled[spec.Name] {
+			filtered = append(filtered, spec)
+		}
+	}
+	return filtered
+}
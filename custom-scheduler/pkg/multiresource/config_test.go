@@ -0,0 +1,146 @@
+package multiresource
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// unsupportedArgsType is a runtime.Object decodeArgs has no conversion path
+// for (neither *Args nor *unstructured.Unstructured), used to exercise its
+// error path.
+type unsupportedArgsType struct{}
+
+func (*unsupportedArgsType) GetObjectKind() schema.ObjectKind { return schema.EmptyObjectKind }
+func (o *unsupportedArgsType) DeepCopyObject() runtime.Object { return o }
+
+// TestDecodeArgs_TypedPassthrough covers the path an in-process scheduler
+// build takes, passing New an already-typed *Args directly.
+func TestDecodeArgs_TypedPassthrough(t *testing.T) {
+	want := &Args{Backend: BackendThanos, Alpha: 0.2}
+
+	got, err := decodeArgs(want)
+	if err != nil {
+		t.Fatalf("decodeArgs() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("decodeArgs() with a typed *Args = %v, want the same pointer back", got)
+	}
+}
+
+// TestDecodeArgs_Unstructured covers the path a KubeSchedulerConfiguration
+// file loaded off disk takes: pluginConfig.args decodes to
+// *unstructured.Unstructured before reaching New.
+func TestDecodeArgs_Unstructured(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"backend":          "elasticsearch",
+		"alpha":            0.75,
+		"maxScore":         float64(50),
+		"enabledResources": []interface{}{"cpu", "mem"},
+	}}
+
+	got, err := decodeArgs(obj)
+	if err != nil {
+		t.Fatalf("decodeArgs() error = %v", err)
+	}
+	if got.Backend != BackendElasticsearch || got.Alpha != 0.75 || got.MaxScore != 50 {
+		t.Errorf("decodeArgs() = %+v, want backend=elasticsearch alpha=0.75 maxScore=50", got)
+	}
+	if len(got.EnabledResources) != 2 || got.EnabledResources[0] != "cpu" || got.EnabledResources[1] != "mem" {
+		t.Errorf("decodeArgs() EnabledResources = %v, want [cpu mem]", got.EnabledResources)
+	}
+}
+
+// TestDecodeArgs_NilIsZeroValue covers a KubeSchedulerConfiguration with no
+// pluginConfig entry for MultiResource at all.
+func TestDecodeArgs_NilIsZeroValue(t *testing.T) {
+	got, err := decodeArgs(nil)
+	if err != nil {
+		t.Fatalf("decodeArgs(nil) error = %v", err)
+	}
+	if got.Backend != "" || got.Alpha != 0 {
+		t.Errorf("decodeArgs(nil) = %+v, want a zero Args", got)
+	}
+}
+
+// TestDecodeArgs_UnsupportedType covers a runtime.Object decodeArgs has no
+// conversion path for, which should fail loudly rather than silently
+// falling back to defaults.
+func TestDecodeArgs_UnsupportedType(t *testing.T) {
+	if _, err := decodeArgs(&unsupportedArgsType{}); err == nil {
+		t.Error("decodeArgs() with an unsupported runtime.Object type = nil error, want an error")
+	}
+}
+
+func TestResolveArgs_FillsDefaults(t *testing.T) {
+	got := resolveArgs(&Args{})
+
+	if got.Backend != defaultBackend {
+		t.Errorf("resolveArgs() Backend = %q, want %q", got.Backend, defaultBackend)
+	}
+	if got.URL != defaultPrometheusAddr {
+		t.Errorf("resolveArgs() URL = %q, want %q", got.URL, defaultPrometheusAddr)
+	}
+	if got.Alpha != defaultAlpha {
+		t.Errorf("resolveArgs() Alpha = %v, want %v", got.Alpha, defaultAlpha)
+	}
+	if got.MaxScore != defaultMaxScore {
+		t.Errorf("resolveArgs() MaxScore = %v, want %v", got.MaxScore, defaultMaxScore)
+	}
+}
+
+func TestResolveArgs_PreservesExplicitValues(t *testing.T) {
+	got := resolveArgs(&Args{Alpha: 0.9, MaxScore: 10})
+
+	if got.Alpha != 0.9 || got.MaxScore != 10 {
+		t.Errorf("resolveArgs() = %+v, want explicit alpha/maxScore preserved", got)
+	}
+}
+
+func TestValidateArgs_RejectsOutOfRangeAlpha(t *testing.T) {
+	if err := validateArgs(&Args{Alpha: 1.5}); err == nil {
+		t.Error("validateArgs() with alpha=1.5 = nil error, want an error")
+	}
+}
+
+func TestValidateArgs_RejectsNegativeResourceWeight(t *testing.T) {
+	args := &Args{ResourceWeights: map[string]float64{"cpu": -0.1}}
+	if err := validateArgs(args); err == nil {
+		t.Error("validateArgs() with a negative resource weight = nil error, want an error")
+	}
+}
+
+func TestValidateArgs_RejectsUnknownBackend(t *testing.T) {
+	if err := validateArgs(&Args{Backend: "graphite"}); err == nil {
+		t.Error("validateArgs() with an unknown backend = nil error, want an error")
+	}
+}
+
+func TestValidateArgs_AcceptsZeroValueArgs(t *testing.T) {
+	if err := validateArgs(&Args{}); err != nil {
+		t.Errorf("validateArgs() on a zero-value Args = %v, want nil", err)
+	}
+}
+
+func TestFilterEnabledResources_EmptyKeepsAll(t *testing.T) {
+	specs := defaultMetricQuerySpecs()
+	got := filterEnabledResources(specs, &Args{})
+	if len(got) != len(specs) {
+		t.Errorf("filterEnabledResources() with no EnabledResources = %d specs, want %d", len(got), len(specs))
+	}
+}
+
+func TestFilterEnabledResources_RestrictsToSubset(t *testing.T) {
+	got := filterEnabledResources(defaultMetricQuerySpecs(), &Args{EnabledResources: []string{"cpu", "mem"}})
+
+	if len(got) != 2 {
+		t.Fatalf("filterEnabledResources() = %d specs, want 2", len(got))
+	}
+	for _, spec := range got {
+		if spec.Name != "cpu" && spec.Name != "mem" {
+			t.Errorf("filterEnabledResources() returned unexpected spec %q", spec.Name)
+		}
+	}
+}
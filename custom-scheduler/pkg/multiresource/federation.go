@@ -0,0 +1,184 @@
+package multiresource
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// clustersFromArgs resolves the member clusters updateAllNodeStats federates
+// across: Args.Clusters if set, otherwise the comma-separated
+// PROMETHEUS_CLUSTERS env var, otherwise none, which leaves federated
+// collection disabled and the existing single-cluster Filter/Score path
+// unaffected.
+func clustersFromArgs(args *Args) []string {
+	if len(args.Clusters) > 0 {
+		return args.Clusters
+	}
+	val := os.Getenv("PROMETHEUS_CLUSTERS")
+	if val == "" {
+		return nil
+	}
+	var clusters []string
+	for _, c := range strings.Split(val, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			clusters = append(clusters, c)
+		}
+	}
+	return clusters
+}
+
+// clusterScopedQuerier is the subset of PromQLQuerier backends that can also
+// report which label names their samples' cluster by (PrometheusClient, via
+// its clusterLabelName), letting updateAllNodeStats build a per-cluster
+// label selector for each of p.clusters without overriding the client's own
+// single clusterLabelValue.
+type clusterScopedQuerier interface {
+	PromQLQuerier
+	ClusterLabelName() string
+}
+
+// clusterNodeKey is the composite key updateAllNodeStats/GetNodeStatsForCluster
+// use to namespace a node's federated stats by member cluster, so the same
+// node name scraped from two clusters behind one Thanos/Cortex endpoint
+// doesn't collide the way the plain node-keyed nodeStats cache would.
+func clusterNodeKey(cluster, node string) string {
+	return cluster + "/" + node
+}
+
+// runFederatedCollector refreshes p.clusterNodeStats on its own
+// collectInterval-spaced tick, independent of the per-node statsQueue that
+// drives the local cluster's Filter/Score cache; only started when
+// Args.Clusters is non-empty (see New).
+func (p *Plugin) runFederatedCollector(ctx context.Context) {
+	p.updateAllNodeStats(ctx)
+
+	ticker := time.NewTicker(p.collectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.updateAllNodeStats(ctx)
+		}
+	}
+}
+
+// updateAllNodeStats refreshes p.clusterNodeStats for every cluster in
+// p.clusters, scoping each cluster's queries to it via the metrics
+// backend's clusterScopedQuerier label selector (the same mechanism a
+// single clusterLabelValue already scopes NewPrometheusClient/
+// NewThanosClient to one cluster; see PrometheusClient.clusterSelector). A
+// backend that isn't a clusterScopedQuerier (metrics-server,
+// Elasticsearch) has no cluster label to scope by, so federated collection
+// is a no-op for it.
+func (p *Plugin) updateAllNodeStats(ctx context.Context) {
+	querier, ok := p.metricsClient.(clusterScopedQuerier)
+	if !ok {
+		klog.Warningf("MultiResource federated mode configured with %d cluster(s), but backend doesn't support cluster-scoped queries", len(p.clusters))
+		return
+	}
+
+	now := time.Now()
+	for _, cluster := range p.clusters {
+		instant, capacity, smoothed := p.collectResourceQueriesForCluster(ctx, querier, cluster)
+
+		nodes := make(map[string]bool)
+		for _, m := range instant {
+			for node := range m {
+				nodes[node] = true
+			}
+		}
+		for _, m := range smoothed {
+			for node := range m {
+				nodes[node] = true
+			}
+		}
+
+		p.mu.Lock()
+		for node := range nodes {
+			stats := p.buildClusterNodeStats(instant, capacity, smoothed, node)
+			stats.Cluster = cluster
+			key := clusterNodeKey(cluster, node)
+			p.clusterNodeStats[key] = stats
+			p.clusterNodeStatsTime[key] = now
+		}
+		p.mu.Unlock()
+	}
+}
+
+// collectResourceQueriesForCluster is collectResourceQueries scoped to one
+// federated member cluster: every spec.PromQL/CapacityPromQL gets an extra
+// `<ClusterLabelName>="<cluster>"` selector (see injectLabelSelectors),
+// instead of running cluster-wide the way the local-cluster collector does.
+func (p *Plugin) collectResourceQueriesForCluster(ctx context.Context, querier clusterScopedQuerier, cluster string) (instant, capacity, smoothed map[string]map[string]float64) {
+	selector := fmt.Sprintf(`%s="%s"`, querier.ClusterLabelName(), cluster)
+
+	instant = make(map[string]map[string]float64, len(p.resourceSpecs))
+	capacity = make(map[string]map[string]float64, len(p.resourceSpecs))
+	smoothed = make(map[string]map[string]float64, len(p.resourceSpecs))
+
+	end := time.Now()
+	start := end.Add(-diskNetSmoothWindow)
+
+	for _, spec := range p.resourceSpecs {
+		if spec.Smoothed {
+			series, err := querier.QueryRange(ctx, spec.PromQL, start, end, diskNetSmoothStep, selector)
+			if err != nil {
+				klog.Warningf("Error collecting cluster %s %s history: %v", cluster, spec.Name, err)
+				continue
+			}
+			avg := make(map[string]float64, len(series))
+			for node, samples := range series {
+				avg[node] = mean(samples)
+			}
+			smoothed[spec.Name] = avg
+		} else {
+			result, err := querier.Query(ctx, spec.PromQL, selector)
+			if err != nil {
+				klog.Warningf("Error collecting cluster %s %s metrics: %v", cluster, spec.Name, err)
+				continue
+			}
+			instant[spec.Name] = result
+		}
+
+		if spec.CapacityPromQL == "" {
+			continue
+		}
+		capResult, err := querier.Query(ctx, spec.CapacityPromQL, selector)
+		if err != nil {
+			klog.Warningf("Error collecting cluster %s %s capacity: %v", cluster, spec.Name, err)
+			continue
+		}
+		capacity[spec.Name] = capResult
+	}
+
+	return instant, capacity, smoothed
+}
+
+// GetNodeStatsForCluster returns the federated cache entry for node in
+// cluster (see updateAllNodeStats), letting a downstream plugin in a
+// scheduler-in-scheduler setup score nodes belonging to a specific member
+// cluster instead of only the ones in the local Filter/Score cache. An
+// entry older than maxStatsAge is treated as missing, same as
+// getNodeStatsFromCache.
+func (p *Plugin) GetNodeStatsForCluster(cluster, node string) (NodeStats, error) {
+	key := clusterNodeKey(cluster, node)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats, ok := p.clusterNodeStats[key]
+	if !ok {
+		return NodeStats{}, fmt.Errorf("no stats found for node %s in cluster %s", node, cluster)
+	}
+	if age := time.Since(p.clusterNodeStatsTime[key]); age > maxStatsAge() {
+		return NodeStats{}, fmt.Errorf("stats for node %s in cluster %s are stale (%s old)", node, cluster, age)
+	}
+	return stats, nil
+}
@@ -0,0 +1,67 @@
+package multiresource
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClustersFromArgs_PrefersArgsOverEnv(t *testing.T) {
+	t.Setenv("PROMETHEUS_CLUSTERS", "env-a,env-b")
+	got := clustersFromArgs(&Args{Clusters: []string{"us-east", "us-west"}})
+	if len(got) != 2 || got[0] != "us-east" || got[1] != "us-west" {
+		t.Errorf("clustersFromArgs() = %v, want [us-east us-west]", got)
+	}
+}
+
+func TestClustersFromArgs_FallsBackToEnv(t *testing.T) {
+	t.Setenv("PROMETHEUS_CLUSTERS", "env-a, env-b ,,env-c")
+	got := clustersFromArgs(&Args{})
+	want := []string{"env-a", "env-b", "env-c"}
+	if len(got) != len(want) {
+		t.Fatalf("clustersFromArgs() = %v, want %v", got, want)
+	}
+	for i, c := range want {
+		if got[i] != c {
+			t.Errorf("clustersFromArgs()[%d] = %q, want %q", i, got[i], c)
+		}
+	}
+}
+
+func TestClustersFromArgs_EmptyWhenUnset(t *testing.T) {
+	t.Setenv("PROMETHEUS_CLUSTERS", "")
+	if got := clustersFromArgs(&Args{}); got != nil {
+		t.Errorf("clustersFromArgs() = %v, want nil when neither Args.Clusters nor the env var is set", got)
+	}
+}
+
+func TestClusterNodeKey_NamespacesByCluster(t *testing.T) {
+	a := clusterNodeKey("us-east", "node-1")
+	b := clusterNodeKey("us-west", "node-1")
+	if a == b {
+		t.Errorf("clusterNodeKey() collided for the same node name in different clusters: %q == %q", a, b)
+	}
+}
+
+func TestGetNodeStatsForCluster_MissingEntry(t *testing.T) {
+	p := &Plugin{
+		clusterNodeStats:     make(map[string]NodeStats),
+		clusterNodeStatsTime: make(map[string]time.Time),
+	}
+	if _, err := p.GetNodeStatsForCluster("us-east", "node-1"); err == nil {
+		t.Error("GetNodeStatsForCluster() err = nil, want error for an uncollected node/cluster pair")
+	}
+}
+
+func TestGetNodeStatsForCluster_ReturnsFreshEntry(t *testing.T) {
+	p := &Plugin{
+		clusterNodeStats:     map[string]NodeStats{"us-east/node-1": {Resources: map[string]ResourceAvailability{"cpu": {Total: 4, Free: 2}}}},
+		clusterNodeStatsTime: map[string]time.Time{"us-east/node-1": time.Now()},
+	}
+	stats, err := p.GetNodeStatsForCluster("us-east", "node-1")
+	if err != nil {
+		t.Fatalf("GetNodeStatsForCluster() err = %v, want nil", err)
+	}
+	if stats.Resources["cpu"].Free != 2 {
+		t.Errorf("GetNodeStatsForCluster() = %+v, want cpu.Free = 2", stats)
+	}
+}
@@ -14,84 +14,67 @@ import (
 // Filter checks if a pod can fit on a node based on its resource requirements
 func (p *Plugin) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
 	nodeName := nodeInfo.Node().Name
+	lh := klog.FromContext(ctx).WithValues("pod", fmt.Sprintf("%s/%s", pod.Namespace, pod.Name), "node", nodeName)
+
+	if p.healthMgr != nil {
+		if ruleName, cordoned := p.healthMgr.IsCordoned(nodeName); cordoned {
+			reason := fmt.Sprintf("Node %s cordoned by healthmgr rule %s", nodeName, ruleName)
+			lh.V(3).Info(reason)
+			return framework.NewStatus(framework.Unschedulable, reason)
+		}
+	}
 
 	// Extract pod requirements
 	podReq := p.extractPodRequirements(pod)
 
-	// Get node stats from cache
-	nodeStats, err := p.getNodeStatsFromCache(nodeName)
+	// Get node stats from cache, refreshing on a miss (see
+	// getOrRefreshNodeStats).
+	nodeStats, err := p.getOrRefreshNodeStats(ctx, nodeName)
 	if err != nil {
-		klog.Warningf("Error getting node stats for %s: %v", nodeName, err)
-
-		// Attempt to refresh the stats for this node
-		klog.Infof("Refreshing node stats for %s", nodeName)
-		nodeStats, err = p.getNodeStats(nodeName)
-		if err != nil {
-			klog.Warningf("Still error getting node stats for %s: %v", nodeName, err)
-
-			// If we still can't get node stats, reject the node
-			return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Failed to get node %s stats", nodeName))
-		}
-
-		// Cache the updated stats
-		p.mu.Lock()
-		p.nodeStats[nodeName] = nodeStats
-		p.mu.Unlock()
+		lh.Error(err, "Failed to get node stats")
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Failed to get node %s stats", nodeName))
 	}
 
 	// Check if the pod fits on this node
 	if !p.canScheduleMulti(podReq, nodeStats) {
 		reason := fmt.Sprintf("Node %s doesn't have enough resources for pod %s/%s",
 			nodeName, pod.Namespace, pod.Name)
-		klog.V(3).Infof(reason)
+		lh.V(3).Info(reason)
 		return framework.NewStatus(framework.Unschedulable, reason)
 	}
 
 	return nil
 }
 
-// canScheduleMulti checks if a pod can be scheduled on a node based on
-// CPU, memory, disk I/O, and network bandwidth requirements
-func (p *Plugin) canScheduleMulti(podReq PodRequest, nodeStats NodeStats) bool {
-	// Check CPU
-	if podReq.CPU > nodeStats.CPUFree {
-		klog.V(4).Infof("Not enough CPU. Requested: %v, Available: %v",
-			podReq.CPU, nodeStats.CPUFree)
-		return false
-	}
-
-	// Check Memory
-	if podReq.Mem > nodeStats.MemFree {
-		klog.V(4).Infof("Not enough Memory. Requested: %v, Available: %v",
-			podReq.Mem, nodeStats.MemFree)
-		return false
-	}
-
-	// Check Disk Read
-	if podReq.DiskRead > nodeStats.DiskReadFree {
-		klog.V(4).Infof("Not enough Disk Read. Requested: %v, Available: %v",
-			podReq.DiskRead, nodeStats.DiskReadFree)
-		return false
-	}
-
-	// Check Disk Write
-	if podReq.DiskWrite > nodeStats.DiskWriteFree {
-		klog.V(4).Infof("Not enough Disk Write. Requested: %v, Available: %v",
-			podReq.DiskWrite, nodeStats.DiskWriteFree)
-		return false
-	}
+// resourceAnnotationDefaults gives the original annotation-derived
+// resources (disk/network) a default proportional to CPU demand, matching
+// the values extractPodRequirements always used before resources became
+// spec-driven. Any resource not listed here (including operator-added ones)
+// defaults to a per-pod demand of 0 when its annotation is absent.
+var resourceAnnotationDefaults = map[string]func(cpu float64) float64{
+	"disk-read":  func(cpu float64) float64 { return cpu * 10 * 1024 * 1024 },
+	"disk-write": func(cpu float64) float64 { return cpu * 5 * 1024 * 1024 },
+	"net-up":     func(cpu float64) float64 { return cpu * 5 * 1024 * 1024 },
+	"net-down":   func(cpu float64) float64 { return cpu * 10 * 1024 * 1024 },
+}
 
-	// Check Network Upload
-	if podReq.NetUp > nodeStats.NetUpFree {
-		klog.V(4).Infof("Not enough Network Upload. Requested: %v, Available: %v",
-			podReq.NetUp, nodeStats.NetUpFree)
-		return false
+// canScheduleMulti checks if a pod can be scheduled on a node by comparing
+// its demand against free availability for every resource in the plugin's
+// resourceSpecs registry.
+func (p *Plugin) canScheduleMulti(podReq PodRequest, nodeStats NodeStats) bool {
+	for _, spec := range p.resourceSpecs {
+		demand := podReq.Resources[spec.Name]
+		available := nodeStats.Resources[spec.Name]
+		if demand > available.Free {
+			klog.V(4).Infof("Not enough %s. Requested: %v, Available: %v",
+				spec.Name, demand, available.Free)
+			return false
+		}
 	}
 
-	// Check Network Download
-	if podReq.NetDown > nodeStats.NetDownFree {
-		klog.V(4).Infof("Not enough Network Download. Requested: %v, Available: %v",
-			podReq.NetDown, nodeStats.NetDownFree)
+	if podReq.GPURequest > 0 && gpuDevicesFree(nodeStats) < podReq.GPURequest {
+		klog.V(4).Infof("Not enough free GPUs. Requested: %d, Available: %d",
+			podReq.GPURequest, gpuDevicesFree(nodeStats))
 		return false
 	}
 
@@ -100,33 +83,74 @@ func (p *Plugin) canScheduleMulti(podReq PodRequest, nodeStats NodeStats) bool {
 	return true
 }
 
-// extractPodRequirements extracts resource requirements from a pod
-func (p *Plugin) extractPodRequirements(pod *v1.Pod) PodRequest {
-	var req PodRequest
+// gpuIdleThreshold is the DCGM utilization fraction below which a device
+// counts as free for fit-checking; whole-GPU requests can't share a device
+// the way CPU/mem demand shares a node's pooled capacity, so this uses each
+// device's own utilization rather than nodeStats.Resources["gpu"].Free.
+const gpuIdleThreshold = 0.1
+
+// gpuDevicesFree counts the node's GPU devices idle enough to hand to a new
+// pod. A node with no GPUDevices breakdown (non-Prometheus backend, or no
+// GPUs) reports 0.
+func gpuDevicesFree(nodeStats NodeStats) int {
+	free := 0
+	for _, dev := range nodeStats.GPUDevices {
+		if dev.UtilFraction < gpuIdleThreshold {
+			free++
+		}
+	}
+	return free
+}
 
-	// Set default priority
-	req.Priority = 1
+// gpuResourceName is the extended resource name the NVIDIA device plugin
+// registers; a container's request for it is how many whole GPUs it wants.
+const gpuResourceName = v1.ResourceName("nvidia.com/gpu")
 
-	// Extract CPU and memory from container resource requirements
+// extractPodRequirements extracts resource requirements from a pod. CPU and
+// memory come from the container resource requests; every other resource in
+// the plugin's resourceSpecs registry is read from its
+// scheduler.extender/<name> annotation. GPURequest comes from the
+// nvidia.com/gpu extended resource request, same as CPU/memory.
+func (p *Plugin) extractPodRequirements(pod *v1.Pod) PodRequest {
+	req := PodRequest{
+		Priority:  podPriority(pod),
+		Resources: make(map[string]float64, len(p.resourceSpecs)),
+	}
+
+	var cpu float64
 	for _, container := range pod.Spec.Containers {
-		// CPU
 		cpuReq := container.Resources.Requests.Cpu()
 		if !cpuReq.IsZero() {
-			req.CPU += float64(cpuReq.MilliValue()) / 1000.0
+			cpu += float64(cpuReq.MilliValue()) / 1000.0
 		}
-
-		// Memory
-		memReq := container.Resources.Requests.Memory()
-		if !memReq.IsZero() {
-			req.Mem += float64(memReq.Value())
+		if gpuReq, ok := container.Resources.Requests[gpuResourceName]; ok {
+			req.GPURequest += int(gpuReq.Value())
 		}
 	}
+	req.Resources["cpu"] = cpu
 
-	// Extract disk I/O and network bandwidth from annotations
-	req.DiskRead = parseResourceAnnotation(pod, "scheduler.extender/disk-read", req.CPU*10*1024*1024)
-	req.DiskWrite = parseResourceAnnotation(pod, "scheduler.extender/disk-write", req.CPU*5*1024*1024)
-	req.NetUp = parseResourceAnnotation(pod, "scheduler.extender/net-up", req.CPU*5*1024*1024)
-	req.NetDown = parseResourceAnnotation(pod, "scheduler.extender/net-down", req.CPU*10*1024*1024)
+	for _, spec := range p.resourceSpecs {
+		if spec.Name == "cpu" {
+			continue
+		}
+		if spec.Name == "mem" {
+			var mem float64
+			for _, container := range pod.Spec.Containers {
+				memReq := container.Resources.Requests.Memory()
+				if !memReq.IsZero() {
+					mem += float64(memReq.Value())
+				}
+			}
+			req.Resources["mem"] = mem
+			continue
+		}
+
+		def := 0.0
+		if f, ok := resourceAnnotationDefaults[spec.Name]; ok {
+			def = f(cpu)
+		}
+		req.Resources[spec.Name] = parseResourceAnnotation(pod, "scheduler.extender/"+spec.Name, def)
+	}
 
 	klog.V(4).Infof("Extracted requirements for pod %s/%s: %+v", pod.Namespace, pod.Name, req)
 	return req
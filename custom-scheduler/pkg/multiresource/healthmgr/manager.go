@@ -0,0 +1,276 @@
+package healthmgr
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// QueryFunc runs a PromQL query and returns a value per node, matching the
+// shape of PrometheusClient.Query so the Manager can be driven by the same
+// client the Plugin already uses.
+type QueryFunc func(query string) (map[string]float64, error)
+
+// counters tracks how many consecutive checks a node has spent tripping a
+// given rule's down/recovery thresholds.
+type counters struct {
+	Down     int  `json:"down"`
+	Recovery int  `json:"recovery"`
+	Cordoned bool `json:"cordoned"`
+}
+
+// snapshot is the on-disk state used to survive a Manager restart without
+// flip-flopping nodes that were already mid-count or already cordoned.
+type snapshot struct {
+	// Counters[ruleName][nodeName]
+	Counters map[string]map[string]counters `json:"counters"`
+}
+
+// Manager runs configurable PromQL downtime/recovery rules against every
+// node and cordons/uncordons them accordingly, independent of the
+// resource-capacity checks in Filter.
+type Manager struct {
+	rules        []Rule
+	queryFn      QueryFunc
+	client       kubernetes.Interface
+	snapshotPath string
+
+	mu       sync.RWMutex
+	counters map[string]map[string]counters // [ruleName][nodeName]
+	// cordonedBy maps a cordoned node to the rule name that cordoned it, so
+	// Filter can report a specific reason and so uncordon only applies once
+	// the same rule recovers.
+	cordonedBy map[string]string
+}
+
+// NewManager builds a Manager. snapshotPath may be empty to disable
+// persistence (state resets on restart).
+func NewManager(rules []Rule, queryFn QueryFunc, client kubernetes.Interface, snapshotPath string) *Manager {
+	m := &Manager{
+		rules:        rules,
+		queryFn:      queryFn,
+		client:       client,
+		snapshotPath: snapshotPath,
+		counters:     make(map[string]map[string]counters),
+		cordonedBy:   make(map[string]string),
+	}
+	m.loadSnapshot()
+	return m
+}
+
+// Run evaluates all rules every interval until ctx is canceled.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.checkOnce()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkOnce()
+		}
+	}
+}
+
+// IsCordoned reports whether healthmgr currently considers nodeName down,
+// and by which rule, for use by Filter.
+func (m *Manager) IsCordoned(nodeName string) (ruleName string, cordoned bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ruleName, cordoned = m.cordonedBy[nodeName]
+	return ruleName, cordoned
+}
+
+// checkOnce runs every rule once, updating counters and cordoning/
+// uncordoning nodes as thresholds are crossed.
+func (m *Manager) checkOnce() {
+	for _, rule := range m.rules {
+		m.checkRule(rule)
+	}
+	m.saveSnapshot()
+}
+
+func (m *Manager) checkRule(rule Rule) {
+	downValues, err := m.queryFn(rule.DownQL)
+	if err != nil {
+		klog.Warningf("healthmgr: error evaluating downQL for rule %s: %v", rule.Name, err)
+		downValues = map[string]float64{}
+	}
+	recoveryValues, err := m.queryFn(rule.RecoveryQL)
+	if err != nil {
+		klog.Warningf("healthmgr: error evaluating recoveryQL for rule %s: %v", rule.Name, err)
+		recoveryValues = map[string]float64{}
+	}
+
+	m.mu.Lock()
+	ruleCounters := m.counters[rule.Name]
+	if ruleCounters == nil {
+		ruleCounters = make(map[string]counters)
+		m.counters[rule.Name] = ruleCounters
+	}
+
+	seen := make(map[string]bool, len(downValues)+len(recoveryValues))
+	for node := range downValues {
+		seen[node] = true
+	}
+	for node := range recoveryValues {
+		seen[node] = true
+	}
+	for node := range ruleCounters {
+		seen[node] = true
+	}
+
+	var toCordon, toUncordon []string
+	for node := range seen {
+		c := ruleCounters[node]
+
+		if v, ok := downValues[node]; ok && v >= rule.DownThreshold {
+			c.Down++
+		} else {
+			c.Down = 0
+		}
+
+		if v, ok := recoveryValues[node]; ok && v >= rule.RecoveryThreshold {
+			c.Recovery++
+		} else {
+			c.Recovery = 0
+		}
+
+		if !c.Cordoned && c.CordonAfter(rule) {
+			c.Cordoned = true
+			toCordon = append(toCordon, node)
+		} else if c.Cordoned && c.UncordonAfter(rule) {
+			c.Cordoned = false
+			c.Down = 0
+			c.Recovery = 0
+			toUncordon = append(toUncordon, node)
+		}
+
+		ruleCounters[node] = c
+	}
+	m.mu.Unlock()
+
+	for _, node := range toCordon {
+		m.markCordoned(node, rule.Name)
+		m.cordonNode(node, rule.Name)
+	}
+	for _, node := range toUncordon {
+		m.clearCordoned(node, rule.Name)
+		m.uncordonNode(node, rule.Name)
+	}
+}
+
+// CordonAfter reports whether this node's Down streak has reached the
+// rule's cordon threshold.
+func (c counters) CordonAfter(rule Rule) bool {
+	return c.Down >= rule.CordonAfter
+}
+
+// UncordonAfter reports whether this node's Recovery streak has reached the
+// rule's uncordon threshold.
+func (c counters) UncordonAfter(rule Rule) bool {
+	return c.Recovery >= rule.UncordonAfter
+}
+
+func (m *Manager) markCordoned(node, ruleName string) {
+	m.mu.Lock()
+	m.cordonedBy[node] = ruleName
+	m.mu.Unlock()
+}
+
+func (m *Manager) clearCordoned(node, ruleName string) {
+	m.mu.Lock()
+	if m.cordonedBy[node] == ruleName {
+		delete(m.cordonedBy, node)
+	}
+	m.mu.Unlock()
+}
+
+func (m *Manager) cordonNode(nodeName, ruleName string) {
+	klog.Warningf("healthmgr: cordoning node %s (rule %s)", nodeName, ruleName)
+	if m.client == nil {
+		return
+	}
+	node, err := m.client.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("healthmgr: error getting node %s to cordon: %v", nodeName, err)
+		return
+	}
+	node.Spec.Unschedulable = true
+	if _, err := m.client.CoreV1().Nodes().Update(context.Background(), node, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("healthmgr: error cordoning node %s: %v", nodeName, err)
+	}
+}
+
+func (m *Manager) uncordonNode(nodeName, ruleName string) {
+	klog.Infof("healthmgr: uncordoning node %s (rule %s recovered)", nodeName, ruleName)
+	if m.client == nil {
+		return
+	}
+	node, err := m.client.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("healthmgr: error getting node %s to uncordon: %v", nodeName, err)
+		return
+	}
+	node.Spec.Unschedulable = false
+	if _, err := m.client.CoreV1().Nodes().Update(context.Background(), node, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("healthmgr: error uncordoning node %s: %v", nodeName, err)
+	}
+}
+
+// saveSnapshot persists counters to disk so a Manager restart resumes
+// counting instead of resetting every node to healthy.
+func (m *Manager) saveSnapshot() {
+	if m.snapshotPath == "" {
+		return
+	}
+	m.mu.RLock()
+	snap := snapshot{Counters: m.counters}
+	data, err := json.Marshal(snap)
+	m.mu.RUnlock()
+	if err != nil {
+		klog.Errorf("healthmgr: error marshaling snapshot: %v", err)
+		return
+	}
+	if err := os.WriteFile(m.snapshotPath, data, 0644); err != nil {
+		klog.Errorf("healthmgr: error writing snapshot to %s: %v", m.snapshotPath, err)
+	}
+}
+
+// loadSnapshot restores counters (and derived cordon state) from disk, if a
+// snapshot exists.
+func (m *Manager) loadSnapshot() {
+	if m.snapshotPath == "" {
+		return
+	}
+	data, err := os.ReadFile(m.snapshotPath)
+	if err != nil {
+		return
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		klog.Errorf("healthmgr: error parsing snapshot %s: %v", m.snapshotPath, err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if snap.Counters != nil {
+		m.counters = snap.Counters
+	}
+	for ruleName, nodes := range m.counters {
+		for node, c := range nodes {
+			if c.Cordoned {
+				m.cordonedBy[node] = ruleName
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package healthmgr
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule defines one downtime/recovery check the Manager evaluates per node.
+// DownQL and RecoveryQL are PromQL expressions returning a value per node
+// (keyed by instance/node label, same convention as PrometheusClient.Query);
+// a node's down counter for this rule increments on each check where
+// DownQL's value for it reaches DownThreshold and resets to 0 on any check
+// where it doesn't; the node is cordoned once that counter reaches
+// CordonAfter, and uncordoned (both counters reset) once RecoveryQL has
+// reached RecoveryThreshold for UncordonAfter consecutive checks.
+type Rule struct {
+	Name              string  `yaml:"name"`
+	DownQL            string  `yaml:"downQL"`
+	DownThreshold     float64 `yaml:"downThreshold"`
+	RecoveryQL        string  `yaml:"recoveryQL"`
+	RecoveryThreshold float64 `yaml:"recoveryThreshold"`
+	CordonAfter       int     `yaml:"cordonAfter"`
+	UncordonAfter     int     `yaml:"uncordonAfter"`
+}
+
+// rulesFile is the top-level shape of the YAML rules config.
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// LoadRules reads a list of Rules from a YAML file.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading health rules file %s: %v", path, err)
+	}
+	var parsed rulesFile
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing health rules file %s: %v", path, err)
+	}
+	return parsed.Rules, nil
+}
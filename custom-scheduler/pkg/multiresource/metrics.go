@@ -3,8 +3,10 @@ package multiresource
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,20 +16,91 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// defaultClusterLabel is the label Thanos/federated Prometheus deployments
+// conventionally attach to identify the source cluster of a sample.
+const defaultClusterLabel = "cluster"
+
 // PrometheusClient handles communication with the Prometheus API
 type PrometheusClient struct {
 	api v1.API
 	url string
+
+	// clusterLabelName/clusterLabelValue scope every query this client
+	// issues to one cluster when pointed at a federated/Thanos endpoint
+	// that aggregates samples from several. Both empty means no scoping
+	// is applied, matching single-cluster deployments.
+	clusterLabelName  string
+	clusterLabelValue string
+
+	// logLevel gates the client's own verbose/debug logging (e.g. the
+	// curl-equivalent command QueryByCluster prints) independently of the
+	// process-wide klog -v flag; see Plugin.logLevel.
+	logLevel int
+
+	// timeout overrides Query/QueryRange's per-request context timeout when
+	// positive; 0 keeps their original hardcoded 10s/30s defaults.
+	timeout time.Duration
 }
 
-// NewPrometheusClient creates a new PrometheusClient
-func NewPrometheusClient(url string) *PrometheusClient {
+// thanosRoundTripper adds the Thanos query-frontend params partial_response
+// and dedup to every outgoing request, since the upstream Prometheus client
+// library has no first-class option for them.
+type thanosRoundTripper struct {
+	next            http.RoundTripper
+	partialResponse bool
+	dedup           bool
+}
+
+func (t *thanosRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.partialResponse || t.dedup {
+		q := req.URL.Query()
+		if t.partialResponse {
+			q.Set("partial_response", "true")
+		}
+		if t.dedup {
+			q.Set("dedup", "true")
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	return t.next.RoundTrip(req)
+}
+
+// NewPrometheusClient creates a new PrometheusClient, reading its URL
+// override and Thanos/cluster-label options from PROMETHEUS_* env vars;
+// timeoutSeconds (see Args.PrometheusTimeoutSeconds) sets the per-query
+// timeout, 0 keeping the built-in defaults.
+// logLevel gates the client's own verbose logging; see
+// Plugin.logLevel/logLevelFromEnv.
+func NewPrometheusClient(url string, timeoutSeconds, logLevel int) *PrometheusClient {
 	// If URL is provided via environment variable, use that instead
-	envURL := os.Getenv("PROMETHEUS_URL")
-	if envURL != "" {
+	if envURL := os.Getenv("PROMETHEUS_URL"); envURL != "" {
 		url = envURL
 	}
 
+	thanosPartial, _ := strconv.ParseBool(os.Getenv("PROMETHEUS_THANOS_PARTIAL"))
+	thanosDedup, _ := strconv.ParseBool(os.Getenv("PROMETHEUS_THANOS_DEDUP"))
+
+	clusterLabelName := os.Getenv("PROMETHEUS_CLUSTER_LABEL_NAME")
+	clusterLabelValue := os.Getenv("PROMETHEUS_CLUSTER_LABEL_VALUE")
+
+	return newPrometheusClient(url, logLevel, timeoutSeconds, clusterLabelName, clusterLabelValue, thanosPartial, thanosDedup)
+}
+
+// NewThanosClient builds a PrometheusClient pointed at a Thanos querier,
+// with partial_response/dedup applied to every request the way gocrane's
+// federated mode does (see thanosRoundTripper), configured explicitly
+// instead of via the PROMETHEUS_THANOS_PARTIAL/PROMETHEUS_THANOS_DEDUP env
+// vars NewPrometheusClient reads.
+func NewThanosClient(url string, partialResponse, dedup bool, timeoutSeconds, logLevel int) *PrometheusClient {
+	clusterLabelName := os.Getenv("PROMETHEUS_CLUSTER_LABEL_NAME")
+	clusterLabelValue := os.Getenv("PROMETHEUS_CLUSTER_LABEL_VALUE")
+	return newPrometheusClient(url, logLevel, timeoutSeconds, clusterLabelName, clusterLabelValue, partialResponse, dedup)
+}
+
+// newPrometheusClient is the shared constructor NewPrometheusClient and
+// NewThanosClient both build on, taking every option explicitly instead of
+// reading the environment itself.
+func newPrometheusClient(url string, logLevel, timeoutSeconds int, clusterLabelName, clusterLabelValue string, thanosPartial, thanosDedup bool) *PrometheusClient {
 	klog.Infof("Creating Prometheus client with base URL: %s", url)
 
 	// Remove any API path that might be included in the URL
@@ -36,39 +109,143 @@ func NewPrometheusClient(url string) *PrometheusClient {
 		klog.Infof("Removed API path from URL, using base URL: %s", url)
 	}
 
+	if clusterLabelValue != "" && clusterLabelName == "" {
+		clusterLabelName = defaultClusterLabel
+	}
+
+	rt := api.DefaultRoundTripper
+	if thanosPartial {
+		rt = &thanosRoundTripper{next: rt, partialResponse: true, dedup: false}
+	}
+	if thanosDedup {
+		if existing, ok := rt.(*thanosRoundTripper); ok {
+			existing.dedup = true
+		} else {
+			rt = &thanosRoundTripper{next: rt, dedup: true}
+		}
+	}
+
+	var timeout time.Duration
+	if timeoutSeconds > 0 {
+		timeout = time.Duration(timeoutSeconds) * time.Second
+	}
+
 	client, err := api.NewClient(api.Config{
-		Address: url,
+		Address:      url,
+		RoundTripper: rt,
 	})
 	if err != nil {
 		klog.Errorf("Error creating Prometheus client: %v", err)
-		return &PrometheusClient{url: url}
+		return &PrometheusClient{url: url, clusterLabelName: clusterLabelName, clusterLabelValue: clusterLabelValue, logLevel: logLevel, timeout: timeout}
 	}
 
 	return &PrometheusClient{
-		api: v1.NewAPI(client),
-		url: url,
+		api:               v1.NewAPI(client),
+		url:               url,
+		clusterLabelName:  clusterLabelName,
+		clusterLabelValue: clusterLabelValue,
+		logLevel:          logLevel,
+		timeout:           timeout,
 	}
 }
 
-// Query executes a Prometheus query and returns the results as a map of node names to values
-func (c *PrometheusClient) Query(query string) (map[string]float64, error) {
+// injectLabelSelectors merges extra PromQL label matchers (e.g.
+// `cluster="prod"`) into query's first `{...}` selector block.
+func injectLabelSelectors(query string, selectors ...string) string {
+	if len(selectors) == 0 {
+		return query
+	}
+	idx := strings.Index(query, "{")
+	if idx == -1 {
+		return query
+	}
+	insert := strings.Join(selectors, ",") + ","
+	return query[:idx+1] + insert + query[idx+1:]
+}
+
+// clusterSelector returns the client's configured cluster label matcher, or
+// "" if none is set.
+func (c *PrometheusClient) clusterSelector() string {
+	if c.clusterLabelName == "" || c.clusterLabelValue == "" {
+		return ""
+	}
+	return fmt.Sprintf(`%s="%s"`, c.clusterLabelName, c.clusterLabelValue)
+}
+
+// ClusterLabelName returns the label name QueryByCluster keys results by,
+// defaultClusterLabel if the client has none configured explicitly. Used by
+// updateAllNodeStats to build a per-cluster label selector when federating
+// across Args.Clusters, so it agrees with QueryByCluster on which label
+// names a sample's cluster.
+func (c *PrometheusClient) ClusterLabelName() string {
+	if c.clusterLabelName == "" {
+		return defaultClusterLabel
+	}
+	return c.clusterLabelName
+}
+
+// Query executes a Prometheus query and returns the results as a map of
+// node names to values. Extra PromQL label selectors (e.g. `cluster="a"`)
+// can be passed to scope the query further; they're merged with the
+// client's own configured cluster label, if any. When results span more
+// than one cluster (a federated endpoint with no cluster selector applied),
+// nodes with the same name in different clusters clobber each other in this
+// flattened map — use QueryByCluster when that distinction matters.
+func (c *PrometheusClient) Query(ctx context.Context, query string, labelSelectors ...string) (map[string]float64, error) {
+	lh := klog.FromContext(ctx)
+	if c.logLevel >= 4 {
+		lh.Info("Executing Prometheus query", "query", query, "url", c.url)
+	}
+
+	byCluster, err := c.QueryByCluster(ctx, query, labelSelectors...)
+	if err != nil {
+		return nil, err
+	}
+
+	resultMap := make(map[string]float64)
+	for _, nodes := range byCluster {
+		for nodeName, value := range nodes {
+			resultMap[nodeName] = value
+		}
+	}
+	return resultMap, nil
+}
+
+// QueryByCluster is the federated-aware variant of Query: results are keyed
+// first by cluster (the client's clusterLabelName, or defaultClusterLabel
+// if unset, read off each sample), then by node name, so the same node name
+// scraped from two clusters behind one Thanos query endpoint doesn't
+// collide.
+func (c *PrometheusClient) QueryByCluster(ctx context.Context, query string, labelSelectors ...string) (map[string]map[string]float64, error) {
+	lh := klog.FromContext(ctx)
 	if c.api == nil {
 		return nil, fmt.Errorf("prometheus API client not initialized")
 	}
 
-	// Create a curl-equivalent command for debugging
-	curlCmd := fmt.Sprintf("curl -s \"%s/api/v1/query?query=%s\"", c.url, url.QueryEscape(query))
-	klog.Infof("Equivalent curl command: %s", curlCmd)
+	selectors := labelSelectors
+	if cs := c.clusterSelector(); cs != "" {
+		selectors = append([]string{cs}, selectors...)
+	}
+	query = injectLabelSelectors(query, selectors...)
 
-	// Debug log to print the query and URL
-	klog.Infof("Executing Prometheus query: %s using client with URL: %s", query, c.url)
+	// The curl-equivalent command is only worth building (and logging) when
+	// someone has asked for this plugin's most verbose level, not on every
+	// query at the scheduler's default verbosity.
+	if c.logLevel >= 6 {
+		curlCmd := fmt.Sprintf("curl -s \"%s/api/v1/query?query=%s\"", c.url, url.QueryEscape(query))
+		lh.V(6).Info("Equivalent curl command", "curl", curlCmd)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	timeout := c.timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	result, warnings, err := c.api.Query(ctx, query, time.Now())
+	result, warnings, err := c.api.Query(queryCtx, query, time.Now())
 	if err != nil {
-		klog.Errorf("Error querying Prometheus with query '%s' at URL '%s': %v", query, c.url, err)
+		lh.Error(err, "Error querying Prometheus", "query", query, "url", c.url)
 		return nil, err
 	}
 
@@ -76,12 +253,19 @@ func (c *PrometheusClient) Query(query string) (map[string]float64, error) {
 		klog.Warningf("Warnings from Prometheus query: %v", warnings)
 	}
 
-	resultMap := make(map[string]float64)
+	clusterLabel := c.clusterLabelName
+	if clusterLabel == "" {
+		clusterLabel = defaultClusterLabel
+	}
+
+	byCluster := make(map[string]map[string]float64)
 
 	switch resultType := result.(type) {
 	case model.Vector:
 		vector := result.(model.Vector)
-		klog.Infof("Query '%s' returned %d samples", query, len(vector))
+		if c.logLevel >= 4 {
+			lh.Info("Query returned samples", "query", query, "count", len(vector))
+		}
 		for _, sample := range vector {
 			nodeName := string(sample.Metric["instance"])
 			// Remove port number if present
@@ -94,156 +278,210 @@ func (c *PrometheusClient) Query(query string) (map[string]float64, error) {
 				nodeName = string(node)
 			}
 
-			resultMap[nodeName] = float64(sample.Value)
+			cluster := c.clusterLabelValue
+			if cluster == "" {
+				cluster = string(sample.Metric[model.LabelName(clusterLabel)])
+			}
+
+			if byCluster[cluster] == nil {
+				byCluster[cluster] = make(map[string]float64)
+			}
+			byCluster[cluster][nodeName] = float64(sample.Value)
 		}
 	default:
 		return nil, fmt.Errorf("unsupported result type: %T", resultType)
 	}
 
-	klog.Infof("Query '%s' result map: %+v", query, resultMap)
-	return resultMap, nil
+	if c.logLevel >= 6 {
+		lh.V(6).Info("Query result", "query", query, "byCluster", byCluster)
+	}
+	return byCluster, nil
 }
 
-// lastIndex returns the index of the last instance of sep in s, or -1 if sep is not present in s.
-func lastIndex(s, sep string) int {
-	for i := len(s) - len(sep); i >= 0; i-- {
-		if s[i:i+len(sep)] == sep {
-			return i
-		}
+// QueryRange executes a Prometheus range query over [start, end] at the
+// given step and returns each node's sample series in chronological order,
+// using the same cluster-label scoping and node-name resolution as Query.
+// It lets callers smooth bursty metrics (disk/net throughput) over a
+// window instead of trusting a single instant sample.
+func (c *PrometheusClient) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration, labelSelectors ...string) (map[string][]float64, error) {
+	lh := klog.FromContext(ctx)
+	if c.api == nil {
+		return nil, fmt.Errorf("prometheus API client not initialized")
 	}
-	return -1
-}
 
-// getNodeStats retrieves all metrics for a node from Prometheus
-func (p *Plugin) getNodeStats(nodeName string) (NodeStats, error) {
-	var stats NodeStats
-	var err error
+	selectors := labelSelectors
+	if cs := c.clusterSelector(); cs != "" {
+		selectors = append([]string{cs}, selectors...)
+	}
+	query = injectLabelSelectors(query, selectors...)
 
-	klog.Infof("Getting node stats for node: %s", nodeName)
-	if p.promClient == nil {
-		klog.Errorf("Prometheus client is nil for node %s", nodeName)
-		return stats, fmt.Errorf("prometheus client not initialized")
+	if c.logLevel >= 4 {
+		lh.Info("Executing Prometheus range query", "query", query, "url", c.url)
 	}
 
-	// Get CPU metrics
-	cpuQuery := fmt.Sprintf(`count(node_cpu_seconds_total{mode="idle",instance=~"%s.*"})`, nodeName)
-	klog.Infof("Executing CPU metrics query for node %s: %s", nodeName, cpuQuery)
-	cpuResult, err := p.promClient.Query(cpuQuery)
-	if err != nil {
-		klog.Errorf("Error querying CPU metrics for node %s: %v", nodeName, err)
-	} else if value, ok := cpuResult[nodeName]; ok {
-		stats.CPUTotal = value
-		klog.Infof("Found CPU total for node %s: %v", nodeName, value)
-	} else {
-		klog.Warningf("No CPU metrics found for node %s", nodeName)
-		stats.CPUTotal = 1.0 // Default to 1 core if not found
-	}
-
-	// Get CPU usage (non-idle)
-	cpuUsageQuery := fmt.Sprintf(`1 - avg(rate(node_cpu_seconds_total{mode="idle",instance=~"%s.*"}[5m]))`, nodeName)
-	klog.Infof("Executing CPU usage query for node %s: %s", nodeName, cpuUsageQuery)
-	cpuUsageResult, err := p.promClient.Query(cpuUsageQuery)
-	if err != nil {
-		klog.Errorf("Error querying CPU usage metrics for node %s: %v", nodeName, err)
-		stats.CPUFree = stats.CPUTotal * 0.2 // Default to 20% free if error
-	} else if value, ok := cpuUsageResult[nodeName]; ok {
-		usageRatio := value
-		if usageRatio > 1.0 {
-			usageRatio = 1.0
-		}
-		stats.CPUFree = stats.CPUTotal * (1.0 - usageRatio)
-		klog.Infof("Found CPU usage for node %s: %v, calculated free: %v", nodeName, usageRatio, stats.CPUFree)
-	} else {
-		klog.Warningf("No CPU usage metrics found for node %s", nodeName)
-		stats.CPUFree = stats.CPUTotal * 0.2 // Default to 20% free if not found
+	timeout := c.timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
 	}
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	// Get memory metrics
-	memTotalQuery := fmt.Sprintf(`node_memory_MemTotal_bytes{instance=~"%s.*"}`, nodeName)
-	memTotalResult, err := p.promClient.Query(memTotalQuery)
+	result, warnings, err := c.api.QueryRange(queryCtx, query, v1.Range{Start: start, End: end, Step: step})
 	if err != nil {
-		klog.Errorf("Error querying memory total metrics: %v", err)
-	} else if value, ok := memTotalResult[nodeName]; ok {
-		stats.MemTotal = value
-	} else {
-		klog.Warningf("No memory total metrics found for node %s", nodeName)
-		stats.MemTotal = 4 * 1024 * 1024 * 1024 // Default to 4GB if not found
+		lh.Error(err, "Error querying Prometheus range", "query", query, "url", c.url)
+		return nil, err
+	}
+	if len(warnings) > 0 {
+		klog.Warningf("Warnings from Prometheus range query: %v", warnings)
 	}
 
-	memAvailableQuery := fmt.Sprintf(`node_memory_MemAvailable_bytes{instance=~"%s.*"}`, nodeName)
-	memAvailableResult, err := p.promClient.Query(memAvailableQuery)
-	if err != nil {
-		klog.Errorf("Error querying memory available metrics: %v", err)
-		stats.MemFree = stats.MemTotal * 0.2 // Default to 20% free if error
-	} else if value, ok := memAvailableResult[nodeName]; ok {
-		stats.MemFree = value
-	} else {
-		klog.Warningf("No memory available metrics found for node %s", nodeName)
-		stats.MemFree = stats.MemTotal * 0.2 // Default to 20% free if not found
-	}
-
-	// Get disk I/O metrics
-	// For disk read throughput - use a 5-minute rate
-	diskReadQuery := fmt.Sprintf(`sum(rate(node_disk_read_bytes_total{instance=~"%s.*"}[5m]))`, nodeName)
-	diskReadResult, err := p.promClient.Query(diskReadQuery)
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %T", result)
+	}
+
+	series := make(map[string][]float64, len(matrix))
+	for _, stream := range matrix {
+		nodeName := string(stream.Metric["instance"])
+		if idx := lastIndex(nodeName, ":"); idx != -1 {
+			nodeName = nodeName[:idx]
+		}
+		if node, ok := stream.Metric["node"]; ok {
+			nodeName = string(node)
+		}
+		samples := make([]float64, 0, len(stream.Values))
+		for _, v := range stream.Values {
+			samples = append(samples, float64(v.Value))
+		}
+		series[nodeName] = samples
+	}
+	return series, nil
+}
+
+// NodeGPUStats implements GPUStatsQuerier by running DCGM-exporter's
+// DCGM_FI_DEV_GPU_UTIL/DCGM_FI_DEV_FB_FREE/DCGM_FI_DEV_FB_TOTAL against node
+// and keying the result by each device's "UUID" label, so a multi-GPU node's
+// devices don't collide the way a plain node-keyed map would.
+func (c *PrometheusClient) NodeGPUStats(ctx context.Context, node string) (map[string]GPUDeviceStats, error) {
+	util, err := c.gpuVectorForNode(ctx, "DCGM_FI_DEV_GPU_UTIL", node)
 	if err != nil {
-		klog.Errorf("Error querying disk read metrics: %v", err)
-	} else if value, ok := diskReadResult[nodeName]; ok {
-		// Current read rate, we'll consider this as 80% of capacity
-		stats.DiskReadTotal = value / 0.8
-		stats.DiskReadFree = stats.DiskReadTotal - value
-	} else {
-		klog.Warningf("No disk read metrics found for node %s", nodeName)
-		stats.DiskReadTotal = 100 * 1024 * 1024        // Default to 100MB/s if not found
-		stats.DiskReadFree = stats.DiskReadTotal * 0.2 // Default to 20% free
-	}
-
-	// For disk write throughput
-	diskWriteQuery := fmt.Sprintf(`sum(rate(node_disk_written_bytes_total{instance=~"%s.*"}[5m]))`, nodeName)
-	diskWriteResult, err := p.promClient.Query(diskWriteQuery)
+		return nil, fmt.Errorf("querying GPU utilization for node %s: %w", node, err)
+	}
+	memFree, err := c.gpuVectorForNode(ctx, "DCGM_FI_DEV_FB_FREE", node)
 	if err != nil {
-		klog.Errorf("Error querying disk write metrics: %v", err)
-	} else if value, ok := diskWriteResult[nodeName]; ok {
-		// Current write rate, we'll consider this as 80% of capacity
-		stats.DiskWriteTotal = value / 0.8
-		stats.DiskWriteFree = stats.DiskWriteTotal - value
-	} else {
-		klog.Warningf("No disk write metrics found for node %s", nodeName)
-		stats.DiskWriteTotal = 50 * 1024 * 1024          // Default to 50MB/s if not found
-		stats.DiskWriteFree = stats.DiskWriteTotal * 0.2 // Default to 20% free
-	}
-
-	// Get network metrics
-	// For network upload throughput
-	netUpQuery := fmt.Sprintf(`sum(rate(node_network_transmit_bytes_total{instance=~"%s.*"}[5m]))`, nodeName)
-	netUpResult, err := p.promClient.Query(netUpQuery)
+		return nil, fmt.Errorf("querying GPU free memory for node %s: %w", node, err)
+	}
+	memTotal, err := c.gpuVectorForNode(ctx, "DCGM_FI_DEV_FB_TOTAL", node)
 	if err != nil {
-		klog.Errorf("Error querying network upload metrics: %v", err)
-	} else if value, ok := netUpResult[nodeName]; ok {
-		// Current upload rate, we'll consider this as 80% of capacity
-		stats.NetUpTotal = value / 0.8
-		stats.NetUpFree = stats.NetUpTotal - value
-	} else {
-		klog.Warningf("No network upload metrics found for node %s", nodeName)
-		stats.NetUpTotal = 125 * 1024 * 1024     // Default to 1Gbps if not found
-		stats.NetUpFree = stats.NetUpTotal * 0.2 // Default to 20% free
-	}
-
-	// For network download throughput
-	netDownQuery := fmt.Sprintf(`sum(rate(node_network_receive_bytes_total{instance=~"%s.*"}[5m]))`, nodeName)
-	netDownResult, err := p.promClient.Query(netDownQuery)
+		return nil, fmt.Errorf("querying GPU total memory for node %s: %w", node, err)
+	}
+
+	devices := make(map[string]GPUDeviceStats, len(util))
+	for uuid, sample := range util {
+		devices[uuid] = GPUDeviceStats{
+			Index:        sample.index,
+			UtilFraction: sample.value / 100,
+		}
+	}
+	for uuid, sample := range memFree {
+		dev := devices[uuid]
+		dev.Index = sample.index
+		dev.MemFreeBytes = sample.value
+		devices[uuid] = dev
+	}
+	for uuid, sample := range memTotal {
+		dev := devices[uuid]
+		dev.Index = sample.index
+		dev.MemTotalBytes = sample.value
+		devices[uuid] = dev
+	}
+	return devices, nil
+}
+
+// gpuSample is one DCGM vector sample's value alongside its device index,
+// before the three per-metric queries NodeGPUStats runs are merged by UUID.
+type gpuSample struct {
+	index string
+	value float64
+}
+
+// gpuVectorForNode runs query scoped to node's instance label and returns its
+// result keyed by the sample's "UUID" label, the device identifier DCGM
+// attaches to every GPU metric.
+func (c *PrometheusClient) gpuVectorForNode(ctx context.Context, query, node string) (map[string]gpuSample, error) {
+	if c.api == nil {
+		return nil, fmt.Errorf("prometheus API client not initialized")
+	}
+
+	selectors := []string{fmt.Sprintf(`instance=~"%s(:.*)?"`, node)}
+	if cs := c.clusterSelector(); cs != "" {
+		selectors = append(selectors, cs)
+	}
+	query = injectLabelSelectors(query, selectors...)
+
+	timeout := c.timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, warnings, err := c.api.Query(queryCtx, query, time.Now())
 	if err != nil {
-		klog.Errorf("Error querying network download metrics: %v", err)
-	} else if value, ok := netDownResult[nodeName]; ok {
-		// Current download rate, we'll consider this as 80% of capacity
-		stats.NetDownTotal = value / 0.8
-		stats.NetDownFree = stats.NetDownTotal - value
-	} else {
-		klog.Warningf("No network download metrics found for node %s", nodeName)
-		stats.NetDownTotal = 125 * 1024 * 1024       // Default to 1Gbps if not found
-		stats.NetDownFree = stats.NetDownTotal * 0.2 // Default to 20% free
-	}
-
-	klog.V(4).Infof("Node stats for %s: %+v", nodeName, stats)
+		return nil, err
+	}
+	if len(warnings) > 0 {
+		klog.Warningf("Warnings from Prometheus GPU query: %v", warnings)
+	}
+
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unsupported result type: %T", result)
+	}
+
+	samples := make(map[string]gpuSample, len(vector))
+	for _, sample := range vector {
+		uuid := string(sample.Metric["UUID"])
+		if uuid == "" {
+			continue
+		}
+		samples[uuid] = gpuSample{
+			index: string(sample.Metric["gpu"]),
+			value: float64(sample.Value),
+		}
+	}
+	return samples, nil
+}
+
+// lastIndex returns the index of the last instance of sep in s, or -1 if sep is not present in s.
+func lastIndex(s, sep string) int {
+	for i := len(s) - len(sep); i >= 0; i-- {
+		if s[i:i+len(sep)] == sep {
+			return i
+		}
+	}
+	return -1
+}
+
+// getNodeStats retrieves nodeName's availability for every resource in the
+// plugin's resourceSpecs registry from the configured metrics backend. It
+// runs the same cluster-wide queries collectClusterWideStats does and just
+// reads this node's entry back out, so the on-demand refresh path (a
+// Filter/Score cache miss) and the periodic background collector always
+// agree.
+func (p *Plugin) getNodeStats(ctx context.Context, nodeName string) (NodeStats, error) {
+	lh := klog.FromContext(ctx).WithValues("node", nodeName)
+	lh.Info("Getting node stats")
+	if p.metricsClient == nil {
+		lh.Error(nil, "Metrics client is nil")
+		return NodeStats{}, fmt.Errorf("metrics client not initialized")
+	}
+
+	instant, capacity, smoothed := p.collectResourceQueries(ctx)
+	stats := p.withGPUDevices(ctx, nodeName, p.buildClusterNodeStats(instant, capacity, smoothed, nodeName))
+
+	if p.logLevel >= 4 {
+		lh.Info("Computed node stats", "stats", stats)
+	}
 	return stats, nil
 }
@@ -0,0 +1,396 @@
+package multiresource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// MetricsClient is the common interface every metrics backend (Prometheus,
+// Thanos, Kubernetes metrics-server, Elasticsearch) implements, so Plugin
+// doesn't hardcode Prometheus the way it used to. The four Node* methods
+// cover the plugin's original 6 built-in resources (cpu/mem/disk-read/
+// disk-write/net-up/net-down); a backend that can't report one of them
+// (e.g. metrics-server has no disk/network throughput) returns an error
+// from that method and collectResourceQueriesGeneric falls back to that
+// spec's Default, same as a missing Prometheus sample always has.
+type MetricsClient interface {
+	// NodeCPUUsage returns node's CPU usage ratio in [0,1] and its total
+	// core count.
+	NodeCPUUsage(ctx context.Context, node string) (usage, totalCores float64, err error)
+	// NodeMemUsage returns node's free and total memory in bytes.
+	NodeMemUsage(ctx context.Context, node string) (freeBytes, totalBytes float64, err error)
+	// NodeIO returns node's current disk read/write throughput in bytes/sec.
+	NodeIO(ctx context.Context, node string) (readBps, writeBps float64, err error)
+	// NodeNet returns node's current network transmit/receive throughput in
+	// bytes/sec.
+	NodeNet(ctx context.Context, node string) (upBps, downBps float64, err error)
+	// Close releases any resources (connections, watches) the client holds.
+	Close() error
+}
+
+// PromQLQuerier is the subset of MetricsClient backends that also accept
+// arbitrary PromQL, which is what lets collectResourceQueries run operator-
+// defined resourceSpecs instead of being limited to the 6 built-ins.
+// PrometheusClient (and the Thanos client, which is the same type with
+// Thanos-aware options) implement it; MetricsServerClient and
+// ElasticsearchClient don't.
+type PromQLQuerier interface {
+	Query(ctx context.Context, query string, labelSelectors ...string) (map[string]float64, error)
+	QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration, labelSelectors ...string) (map[string][]float64, error)
+}
+
+// GPUStatsQuerier is the subset of MetricsClient backends that can report
+// per-device GPU utilization and memory, keyed by device UUID. Only
+// PrometheusClient implements it, via DCGM-exporter metrics; a backend
+// without it (MetricsServerClient, ElasticsearchClient) simply leaves
+// NodeStats.GPUDevices unpopulated, the same way PromQLQuerier's absence
+// limits collectResourceQueriesGeneric to the 6 scalar built-ins.
+type GPUStatsQuerier interface {
+	NodeGPUStats(ctx context.Context, node string) (map[string]GPUDeviceStats, error)
+}
+
+// builtinQuerySpecsByName indexes defaultMetricQuerySpecs by Name so the
+// Node* MetricsClient methods below can reuse the exact same PromQL the
+// generic resourceSpecs path runs, instead of a second hand-maintained copy.
+var builtinQuerySpecsByName = func() map[string]MetricQuerySpec {
+	specs := make(map[string]MetricQuerySpec, 6)
+	for _, spec := range defaultMetricQuerySpecs() {
+		specs[spec.Name] = spec
+	}
+	return specs
+}()
+
+// NodeCPUUsage implements MetricsClient by running the built-in "cpu" spec's
+// PromQL/CapacityPromQL and reading node's entry back out.
+func (c *PrometheusClient) NodeCPUUsage(ctx context.Context, node string) (usage, totalCores float64, err error) {
+	spec := builtinQuerySpecsByName["cpu"]
+	usageByNode, err := c.Query(ctx, spec.PromQL)
+	if err != nil {
+		return 0, 0, err
+	}
+	capByNode, err := c.Query(ctx, spec.CapacityPromQL)
+	if err != nil {
+		return 0, 0, err
+	}
+	return usageByNode[node], capByNode[node], nil
+}
+
+// NodeMemUsage implements MetricsClient by running the built-in "mem" spec's
+// PromQL/CapacityPromQL and reading node's entry back out.
+func (c *PrometheusClient) NodeMemUsage(ctx context.Context, node string) (freeBytes, totalBytes float64, err error) {
+	spec := builtinQuerySpecsByName["mem"]
+	freeByNode, err := c.Query(ctx, spec.PromQL)
+	if err != nil {
+		return 0, 0, err
+	}
+	totalByNode, err := c.Query(ctx, spec.CapacityPromQL)
+	if err != nil {
+		return 0, 0, err
+	}
+	return freeByNode[node], totalByNode[node], nil
+}
+
+// NodeIO implements MetricsClient by running the built-in "disk-read"/
+// "disk-write" specs' PromQL and reading node's entry back out.
+func (c *PrometheusClient) NodeIO(ctx context.Context, node string) (readBps, writeBps float64, err error) {
+	readByNode, err := c.Query(ctx, builtinQuerySpecsByName["disk-read"].PromQL)
+	if err != nil {
+		return 0, 0, err
+	}
+	writeByNode, err := c.Query(ctx, builtinQuerySpecsByName["disk-write"].PromQL)
+	if err != nil {
+		return 0, 0, err
+	}
+	return readByNode[node], writeByNode[node], nil
+}
+
+// NodeNet implements MetricsClient by running the built-in "net-up"/
+// "net-down" specs' PromQL and reading node's entry back out.
+func (c *PrometheusClient) NodeNet(ctx context.Context, node string) (upBps, downBps float64, err error) {
+	upByNode, err := c.Query(ctx, builtinQuerySpecsByName["net-up"].PromQL)
+	if err != nil {
+		return 0, 0, err
+	}
+	downByNode, err := c.Query(ctx, builtinQuerySpecsByName["net-down"].PromQL)
+	if err != nil {
+		return 0, 0, err
+	}
+	return upByNode[node], downByNode[node], nil
+}
+
+// Close is a no-op: PrometheusClient holds no long-lived connection beyond
+// the stdlib HTTP client api.NewClient wraps.
+func (c *PrometheusClient) Close() error { return nil }
+
+// metricsServerNodeMetrics mirrors the subset of the metrics.k8s.io
+// v1beta1.NodeMetrics this client needs.
+type metricsServerNodeMetrics struct {
+	Usage struct {
+		CPU string `json:"cpu"`
+		Mem string `json:"memory"`
+	} `json:"usage"`
+}
+
+// MetricsServerClient implements MetricsClient against the Kubernetes
+// metrics.k8s.io API (metrics-server), proxied through the regular
+// apiserver client the same way kubeletSummaryMetricsSource in
+// multi_resource_sched reads the kubelet Summary API, so no separate
+// metrics-server clientset dependency is needed. It only covers CPU/memory:
+// metrics-server has no disk or network throughput signal, so NodeIO/
+// NodeNet always return an error and the caller falls back to that
+// resource's spec Default.
+type MetricsServerClient struct {
+	client kubernetes.Interface
+}
+
+// NewMetricsServerClient builds a MetricsServerClient using client to reach
+// both Node.Status.Allocatable (for totals) and the metrics.k8s.io API (for
+// usage).
+func NewMetricsServerClient(client kubernetes.Interface) *MetricsServerClient {
+	return &MetricsServerClient{client: client}
+}
+
+func (m *MetricsServerClient) NodeCPUUsage(ctx context.Context, node string) (usage, totalCores float64, err error) {
+	n, err := m.client.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("fetching node %s: %w", node, err)
+	}
+	cpuTotal, _ := allocatableCPUMem(n)
+
+	metrics, err := m.nodeMetrics(ctx, node)
+	if err != nil {
+		return 0, cpuTotal, err
+	}
+	usedCores, err := parseCPUQuantity(metrics.Usage.CPU)
+	if err != nil {
+		return 0, cpuTotal, err
+	}
+	if cpuTotal <= 0 {
+		return 0, cpuTotal, nil
+	}
+	return usedCores / cpuTotal, cpuTotal, nil
+}
+
+func (m *MetricsServerClient) NodeMemUsage(ctx context.Context, node string) (freeBytes, totalBytes float64, err error) {
+	n, err := m.client.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("fetching node %s: %w", node, err)
+	}
+	_, memTotal := allocatableCPUMem(n)
+
+	metrics, err := m.nodeMetrics(ctx, node)
+	if err != nil {
+		return memTotal, memTotal, err
+	}
+	usedBytes, err := parseMemQuantity(metrics.Usage.Mem)
+	if err != nil {
+		return memTotal, memTotal, err
+	}
+	free := memTotal - usedBytes
+	if free < 0 {
+		free = 0
+	}
+	return free, memTotal, nil
+}
+
+func (m *MetricsServerClient) NodeIO(ctx context.Context, node string) (readBps, writeBps float64, err error) {
+	return 0, 0, fmt.Errorf("metrics-server backend has no disk I/O signal")
+}
+
+func (m *MetricsServerClient) NodeNet(ctx context.Context, node string) (upBps, downBps float64, err error) {
+	return 0, 0, fmt.Errorf("metrics-server backend has no network throughput signal")
+}
+
+func (m *MetricsServerClient) Close() error { return nil }
+
+// allocatableCPUMem returns node's allocatable CPU (cores) and memory
+// (bytes), the same totals kubeletSummaryMetricsSource in multi_resource_sched
+// derives a node's capacity from.
+func allocatableCPUMem(node *v1.Node) (cpuCores, memBytes float64) {
+	cpuQty := node.Status.Allocatable[v1.ResourceCPU]
+	memQty := node.Status.Allocatable[v1.ResourceMemory]
+	return float64(cpuQty.MilliValue()) / 1000.0, float64(memQty.Value())
+}
+
+// parseCPUQuantity parses a metrics.k8s.io usage.cpu string (e.g. "123m")
+// into cores.
+func parseCPUQuantity(s string) (float64, error) {
+	qty, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0, fmt.Errorf("parsing cpu quantity %q: %w", s, err)
+	}
+	return float64(qty.MilliValue()) / 1000.0, nil
+}
+
+// parseMemQuantity parses a metrics.k8s.io usage.memory string (e.g. "512Ki")
+// into bytes.
+func parseMemQuantity(s string) (float64, error) {
+	qty, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0, fmt.Errorf("parsing memory quantity %q: %w", s, err)
+	}
+	return float64(qty.Value()), nil
+}
+
+// nodeMetrics fetches node's NodeMetrics by proxying to the metrics.k8s.io
+// API through the regular apiserver client, the same AbsPath/DoRaw pattern
+// kubeletSummaryMetricsSource uses for the kubelet Summary API.
+func (m *MetricsServerClient) nodeMetrics(ctx context.Context, node string) (metricsServerNodeMetrics, error) {
+	raw, err := m.client.CoreV1().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/nodes", node).
+		DoRaw(ctx)
+	if err != nil {
+		return metricsServerNodeMetrics{}, fmt.Errorf("fetching metrics-server data for %s: %w", node, err)
+	}
+	var metrics metricsServerNodeMetrics
+	if err := json.Unmarshal(raw, &metrics); err != nil {
+		return metricsServerNodeMetrics{}, fmt.Errorf("parsing metrics-server data for %s: %w", node, err)
+	}
+	return metrics, nil
+}
+
+// ElasticsearchClient implements MetricsClient against a Metricbeat-style
+// Elasticsearch index: a terms aggregation on "host.name" with an avg
+// sub-aggregation on the requested field, over the last 5 minutes.
+type ElasticsearchClient struct {
+	url        string
+	index      string
+	httpClient *http.Client
+	logLevel   int
+}
+
+// NewElasticsearchClient builds an ElasticsearchClient querying index on the
+// cluster at url (e.g. "http://elasticsearch:9200").
+func NewElasticsearchClient(url, index string, logLevel int) *ElasticsearchClient {
+	return &ElasticsearchClient{
+		url:        url,
+		index:      index,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logLevel:   logLevel,
+	}
+}
+
+type esAggBucket struct {
+	Key      string `json:"key"`
+	ByMetric struct {
+		Value float64 `json:"value"`
+	} `json:"by_metric"`
+}
+
+type esSearchResponse struct {
+	Aggregations struct {
+		ByNode struct {
+			Buckets []esAggBucket `json:"buckets"`
+		} `json:"by_node"`
+	} `json:"aggregations"`
+}
+
+// queryByNode runs a terms-by-host.name/avg-by-field aggregation over field
+// and returns the average value per node.
+func (e *ElasticsearchClient) queryByNode(ctx context.Context, field string) (map[string]float64, error) {
+	body := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{
+				"@timestamp": map[string]interface{}{"gte": "now-5m"},
+			},
+		},
+		"aggs": map[string]interface{}{
+			"by_node": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "host.name", "size": 1000},
+				"aggs": map[string]interface{}{
+					"by_metric": map[string]interface{}{"avg": map[string]interface{}{"field": field}},
+				},
+			},
+		},
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling elasticsearch query: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/_search", e.url, e.index)
+	if e.logLevel >= 4 {
+		klog.FromContext(ctx).Info("Executing Elasticsearch query", "url", url, "field", field)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("building elasticsearch request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying elasticsearch at %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("elasticsearch query to %s returned status %d", url, resp.StatusCode)
+	}
+
+	var parsed esSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("parsing elasticsearch response from %s: %w", url, err)
+	}
+
+	result := make(map[string]float64, len(parsed.Aggregations.ByNode.Buckets))
+	for _, bucket := range parsed.Aggregations.ByNode.Buckets {
+		result[bucket.Key] = bucket.ByMetric.Value
+	}
+	return result, nil
+}
+
+func (e *ElasticsearchClient) NodeCPUUsage(ctx context.Context, node string) (usage, totalCores float64, err error) {
+	byNode, err := e.queryByNode(ctx, "system.cpu.total.pct")
+	if err != nil {
+		return 0, 0, err
+	}
+	// system.cpu.total.pct is already a [0,1] ratio (Metricbeat's system
+	// module); Elasticsearch has no node capacity signal of its own, so
+	// totalCores always falls back to the "cpu" spec's Default.
+	return byNode[node], builtinQuerySpecsByName["cpu"].Default, nil
+}
+
+func (e *ElasticsearchClient) NodeMemUsage(ctx context.Context, node string) (freeBytes, totalBytes float64, err error) {
+	byNode, err := e.queryByNode(ctx, "system.memory.actual.free")
+	if err != nil {
+		return 0, 0, err
+	}
+	return byNode[node], builtinQuerySpecsByName["mem"].Default, nil
+}
+
+func (e *ElasticsearchClient) NodeIO(ctx context.Context, node string) (readBps, writeBps float64, err error) {
+	reads, err := e.queryByNode(ctx, "system.diskio.read.bytes")
+	if err != nil {
+		return 0, 0, err
+	}
+	writes, err := e.queryByNode(ctx, "system.diskio.write.bytes")
+	if err != nil {
+		return 0, 0, err
+	}
+	return reads[node], writes[node], nil
+}
+
+func (e *ElasticsearchClient) NodeNet(ctx context.Context, node string) (upBps, downBps float64, err error) {
+	out, err := e.queryByNode(ctx, "system.network.out.bytes")
+	if err != nil {
+		return 0, 0, err
+	}
+	in, err := e.queryByNode(ctx, "system.network.in.bytes")
+	if err != nil {
+		return 0, 0, err
+	}
+	return out[node], in[node], nil
+}
+
+func (e *ElasticsearchClient) Close() error { return nil }
@@ -0,0 +1,197 @@
+package multiresource
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// statsRefreshWorkers is how many goroutines drain p.statsQueue concurrently.
+const statsRefreshWorkers = 4
+
+// statsRefreshJitter bounds the random delay added on top of collectInterval
+// before a node reschedules its own next refresh, so nodes that joined the
+// cluster at the same instant don't all hit the metrics backend on the same
+// tick forever after.
+const statsRefreshJitter = 5 * time.Second
+
+var (
+	statsRefreshTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "multiresource_stats_refresh_total",
+		Help: "Total number of node stats refreshes the MultiResource plugin has run.",
+	})
+	statsRefreshErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "multiresource_stats_refresh_errors_total",
+		Help: "Total number of node stats refreshes that failed.",
+	})
+	cacheMissTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "multiresource_cache_miss_total",
+		Help: "Total number of Filter/Score calls that found no fresh cache entry for a node.",
+	})
+)
+
+// startNodeInformer replaces the plugin's old fixed-interval full-cluster
+// poll with an event-driven one: the node informer enqueues a node the
+// moment it's added or updated and evicts it the moment it's deleted, and
+// statsRefreshWorkers goroutines drain p.statsQueue, each refresh
+// rescheduling its own node for the next cycle (see processNextWorkItem).
+// The scheduler framework owns starting/stopping the informer factory
+// itself; New only registers a handler on it.
+func (p *Plugin) startNodeInformer(ctx context.Context, h framework.Handle) error {
+	informer := h.SharedInformerFactory().Core().V1().Nodes().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if node, ok := obj.(*v1.Node); ok {
+				p.statsQueue.Add(node.Name)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if node, ok := newObj.(*v1.Node); ok {
+				p.statsQueue.Add(node.Name)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			node, ok := obj.(*v1.Node)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					return
+				}
+				node, ok = tombstone.Obj.(*v1.Node)
+				if !ok {
+					return
+				}
+			}
+			p.evictNodeStats(node.Name)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("adding node event handler: %w", err)
+	}
+
+	for i := 0; i < statsRefreshWorkers; i++ {
+		go p.runStatsWorker(ctx)
+	}
+	return nil
+}
+
+// evictNodeStats drops node's cache entries and forgets its queue item,
+// called when the node informer observes a deletion so Filter/Score stop
+// scoring a node that's already gone instead of scheduling onto stale stats
+// until maxStatsAge expires.
+func (p *Plugin) evictNodeStats(node string) {
+	p.statsQueue.Forget(node)
+
+	p.mu.Lock()
+	delete(p.nodeStats, node)
+	delete(p.nodeStatsTime, node)
+	p.mu.Unlock()
+
+	p.emaMu.Lock()
+	delete(p.nodeStatsEMA, node)
+	p.emaMu.Unlock()
+}
+
+// runStatsWorker drains p.statsQueue until it's shut down, refreshing one
+// node's stats per item.
+func (p *Plugin) runStatsWorker(ctx context.Context) {
+	for p.processNextWorkItem(ctx) {
+	}
+}
+
+// processNextWorkItem pops one node off p.statsQueue, refreshes its stats,
+// and either retries it with the queue's own backoff on error or
+// reschedules it for its next regular cycle on success. It returns false
+// once the queue is shut down, ending the calling worker's loop.
+func (p *Plugin) processNextWorkItem(ctx context.Context) bool {
+	item, shutdown := p.statsQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer p.statsQueue.Done(item)
+
+	node, ok := item.(string)
+	if !ok {
+		p.statsQueue.Forget(item)
+		return true
+	}
+
+	if err := p.refreshNodeStats(ctx, node); err != nil {
+		klog.Warningf("Error refreshing stats for node %s, retrying with backoff: %v", node, err)
+		p.statsQueue.AddRateLimited(node)
+		return true
+	}
+
+	p.statsQueue.Forget(node)
+	p.statsQueue.AddAfter(node, p.collectInterval+jitter())
+	return true
+}
+
+// refreshNodeStats re-runs the plugin's batched cluster-wide queries (see
+// collectClusterWideStats) and reports whether node itself came back with
+// an entry. Concurrent refreshes share one run of the underlying queries
+// via p.sfGroup rather than issuing their own, so near-simultaneous queue
+// items for different nodes collapse into one round of requests instead of
+// one per node.
+func (p *Plugin) refreshNodeStats(ctx context.Context, node string) error {
+	statsRefreshTotal.Inc()
+
+	// The key can't collide with a node name, which getOrRefreshNodeStats
+	// uses as its own sfGroup key.
+	const clusterWideRefreshKey = "\x00cluster-wide"
+	p.sfGroup.Do(clusterWideRefreshKey, func() (interface{}, error) {
+		p.collectClusterWideStats(ctx)
+		return nil, nil
+	})
+
+	p.mu.RLock()
+	_, ok := p.nodeStats[node]
+	p.mu.RUnlock()
+	if !ok {
+		statsRefreshErrorsTotal.Inc()
+		return fmt.Errorf("no stats collected for node %s", node)
+	}
+	return nil
+}
+
+// jitter returns a random delay in [0, statsRefreshJitter), spreading
+// otherwise-simultaneous per-node refreshes across time.
+func jitter() time.Duration {
+	return time.Duration(rand.Int63n(int64(statsRefreshJitter)))
+}
+
+// getOrRefreshNodeStats is Filter/Score's shared cache-miss path: a cache
+// hit returns immediately, and a miss fetches via p.sfGroup so several
+// concurrent Filter/Score calls missing on the same node (e.g. a burst of
+// pods landing on a node that just joined) share one on-demand query
+// instead of each issuing its own.
+func (p *Plugin) getOrRefreshNodeStats(ctx context.Context, nodeName string) (NodeStats, error) {
+	if stats, err := p.getNodeStatsFromCache(nodeName); err == nil {
+		return stats, nil
+	}
+	cacheMissTotal.Inc()
+
+	result, err, _ := p.sfGroup.Do(nodeName, func() (interface{}, error) {
+		stats, err := p.getNodeStats(ctx, nodeName)
+		if err != nil {
+			return NodeStats{}, err
+		}
+		p.mu.Lock()
+		p.nodeStats[nodeName] = stats
+		p.nodeStatsTime[nodeName] = time.Now()
+		p.mu.Unlock()
+		return stats, nil
+	})
+	if err != nil {
+		return NodeStats{}, err
+	}
+	return result.(NodeStats), nil
+}
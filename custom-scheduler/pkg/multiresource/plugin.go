@@ -1,12 +1,21 @@
 package multiresource
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"custom-scheduler/pkg/multiresource/healthmgr"
 )
 
 // Name is the name of the plugin used in the plugin registry and configurations.
@@ -14,106 +23,365 @@ const Name = "MultiResource"
 
 // Plugin is the main implementation that contains both filter and score functionality
 type Plugin struct {
-	handle     framework.Handle
-	promClient *PrometheusClient
-	alpha      float64
-	maxScore   int
+	handle framework.Handle
+	// metricsClient is whichever backend Args.Backend selected (Prometheus,
+	// Thanos, metrics-server, or Elasticsearch); see MetricsClient.
+	metricsClient MetricsClient
+	alpha         float64
+	maxScore      int
+
+	// scoreStrategy selects which of the scoring functions in score.go
+	// Score uses; see the Strategy* constants.
+	scoreStrategy   string
+	resourceWeights ResourceWeights
+
+	// logLevel is this plugin's own verbosity threshold (see
+	// logLevelFromEnv), checked directly by getNodeStats and the
+	// Prometheus/Elasticsearch clients instead of klog's process-wide -v flag, so an
+	// operator can turn up MultiResource's logging without enabling every
+	// other plugin's.
+	logLevel int
+
+	// resourceSpecs is the registry of resources the plugin tracks, loaded
+	// once at startup (see metricQuerySpecsFromEnv). Every NodeStats and
+	// PodRequest is keyed by the Name of one of these specs.
+	resourceSpecs []MetricQuerySpec
+
+	// emaAlpha is the smoothing factor smoothNodeStats uses to blend each
+	// refresh's raw sample into the node's running exponentially-weighted
+	// moving average (see emaAlphaFromEnv); 1.0 disables smoothing,
+	// reproducing the old "snapshot" behavior.
+	emaAlpha float64
+
+	// collectInterval is how often a node's queue item reschedules itself
+	// after a successful refresh (see processNextWorkItem), set from
+	// Args.ScrapeIntervalSeconds (falling back to collectInterval()'s own
+	// MULTIRESOURCE_COLLECT_INTERVAL/15s default when unset); see
+	// collectIntervalFromArgs.
+	collectInterval time.Duration
+
+	// statsQueue drives the per-node refresh workers startNodeInformer
+	// starts: the node informer adds/removes entries as nodes join/leave,
+	// and each successful refresh re-adds its own node after
+	// collectInterval+jitter, replacing the old fixed-tick full-cluster
+	// poll with node-by-node, spread-out refreshes.
+	statsQueue workqueue.RateLimitingInterface
+
+	// sfGroup deduplicates concurrent on-demand refreshes the same node's
+	// cache miss triggers from Filter/Score, so a stampede of pods being
+	// scheduled onto the same newly-joined node issues one query instead
+	// of one per pod; see getOrRefreshNodeStats.
+	sfGroup singleflight.Group
 
 	// Cache for node stats
-	mu        sync.RWMutex
-	nodeStats map[string]NodeStats
+	mu            sync.RWMutex
+	nodeStats     map[string]NodeStats
+	nodeStatsTime map[string]time.Time
+
+	// emaMu guards nodeStatsEMA separately from mu, since smoothNodeStats
+	// is called from buildClusterNodeStats, which runs both under mu (the
+	// federated updateAllNodeStats) and without it (collectClusterWideStats
+	// and the on-demand getNodeStats path).
+	emaMu sync.Mutex
+	// nodeStatsEMA holds the current EMA for every node/resource Free
+	// value smoothNodeStats has smoothed so far, keyed by node then
+	// resource name.
+	nodeStatsEMA map[string]map[string]float64
+
+	// healthMgr independently cordons/uncordons nodes based on configurable
+	// PromQL downtime/recovery rules; nil if HEALTHMGR_RULES_CONFIG isn't
+	// set, in which case Filter only applies capacity checks.
+	healthMgr *healthmgr.Manager
+
+	// preemptionDryRun gates PostFilter's eviction step; see
+	// Args.PreemptionDryRun.
+	preemptionDryRun bool
+
+	// clusters is the set of member clusters updateAllNodeStats federates
+	// across; see Args.Clusters. Empty leaves federated collection
+	// disabled.
+	clusters []string
+	// clusterNodeStats/clusterNodeStatsTime are updateAllNodeStats' cache,
+	// keyed by clusterNodeKey(cluster, node) rather than by node name
+	// alone, so the same node name in two member clusters doesn't
+	// collide; read via GetNodeStatsForCluster. Guarded by mu, same as
+	// nodeStats/nodeStatsTime.
+	clusterNodeStats     map[string]NodeStats
+	clusterNodeStatsTime map[string]time.Time
+}
+
+// ResourceAvailability is one resource's total capacity and currently free
+// amount on a node, in the unit its MetricQuerySpec declares.
+type ResourceAvailability struct {
+	Total float64
+	Free  float64
 }
 
-// NodeStats holds statistics about node resource usage
+// NodeStats holds a node's availability for every resource in the plugin's
+// resourceSpecs registry, keyed by MetricQuerySpec.Name (e.g. "cpu", "mem",
+// "disk-read", or any operator-added resource).
 type NodeStats struct {
-	CPUTotal       float64 // Total CPU cores
-	CPUFree        float64 // Free CPU cores
-	MemTotal       float64 // Total memory in bytes
-	MemFree        float64 // Free memory in bytes
-	DiskReadTotal  float64 // Total disk read throughput capacity
-	DiskReadFree   float64 // Available disk read throughput
-	DiskWriteTotal float64 // Total disk write throughput capacity
-	DiskWriteFree  float64 // Available disk write throughput
-	NetUpTotal     float64 // Total network upload capacity
-	NetUpFree      float64 // Available network upload capacity
-	NetDownTotal   float64 // Total network download capacity
-	NetDownFree    float64 // Available network download capacity
-}
-
-// PodRequest holds resource requirements for a pod
+	Resources map[string]ResourceAvailability
+
+	// CPULimitUtilization/MemLimitUtilization are the node's used-vs-total
+	// ratio for the "cpu"/"mem" resources, computed the way the
+	// kubeletstats receiver reports limit utilization (used/allocatable),
+	// independent of whichever scoring strategy is active. They're EMA-
+	// smoothed along with the rest of NodeStats; see smoothNodeStats.
+	CPULimitUtilization float64
+	MemLimitUtilization float64
+
+	// GPUCount is the node's total device count, the number of entries in
+	// GPUDevices (set by withGPUDevices); 0 on a node with no GPUs or on a
+	// backend that doesn't implement GPUStatsQuerier.
+	GPUCount int
+	// GPUDevices holds this node's current per-device stats, keyed by GPU
+	// UUID (DCGM's "UUID" label) so a multi-GPU node's devices don't
+	// collide. Populated by withGPUDevices; nil for backends that don't
+	// implement GPUStatsQuerier (only Prometheus/Thanos, via DCGM-exporter,
+	// currently do).
+	GPUDevices map[string]GPUDeviceStats
+
+	// Cluster is the member cluster this entry was collected for, set by
+	// updateAllNodeStats when Args.Clusters is non-empty; empty for the
+	// single-cluster Filter/Score cache, which has no cluster to record.
+	Cluster string
+}
+
+// GPUDeviceStats is one GPU device's current utilization and memory
+// availability, sourced from DCGM-exporter's DCGM_FI_DEV_GPU_UTIL and
+// DCGM_FI_DEV_FB_FREE/DCGM_FI_DEV_FB_TOTAL metrics; see
+// PrometheusClient.NodeGPUStats.
+type GPUDeviceStats struct {
+	// Index is the device's DCGM "gpu" label (its index on the node), kept
+	// alongside the UUID key for logging since UUIDs aren't human-friendly.
+	Index string
+	// UtilFraction is DCGM_FI_DEV_GPU_UTIL/100, in [0, 1].
+	UtilFraction  float64
+	MemFreeBytes  float64
+	MemTotalBytes float64
+}
+
+// PodRequest holds a pod's demand for every resource in the plugin's
+// resourceSpecs registry, keyed by MetricQuerySpec.Name, plus its priority.
 type PodRequest struct {
-	CPU       float64 // CPU cores requested
-	Mem       float64 // Memory requested in bytes
-	DiskRead  float64 // Disk read demand (bytes/sec)
-	DiskWrite float64 // Disk write demand (bytes/sec)
-	NetUp     float64 // Network upload demand (bytes/sec)
-	NetDown   float64 // Network download demand (bytes/sec)
-	Priority  int     // Higher value means higher priority
+	Resources map[string]float64
+	Priority  int // Higher value means higher priority
+
+	// GPURequest is the pod's nvidia.com/gpu request, summed across
+	// containers; see extractPodRequirements.
+	GPURequest int
 }
 
-// New initializes a new plugin and returns it.
-func New(h framework.Handle) (framework.Plugin, error) {
-	// Use default values
-	alpha := 0.5
-	maxScore := 100
-	promURL := "http://prometheus-server.default.svc.cluster.local:80"
+// New initializes a new plugin from obj (the scheduler's pluginConfig.args
+// for MultiResource; see Args) and returns it. obj may be nil, in which
+// case every Args field falls back to its original hardcoded default
+// (Prometheus at defaultPrometheusAddr), matching the plugin's behavior before
+// backends became configurable.
+func New(obj runtime.Object, h framework.Handle) (framework.Plugin, error) {
+	rawArgs, err := decodeArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateArgs(rawArgs); err != nil {
+		return nil, fmt.Errorf("invalid MultiResource plugin args: %w", err)
+	}
+	args := resolveArgs(rawArgs)
+	logLevel := logLevelFromEnv()
 
-	klog.V(2).Infof("Creating MultiResource plugin with alpha=%v, maxScore=%v, prometheusURL=%v",
-		alpha, maxScore, promURL)
+	metricsClient, err := newMetricsClient(args, h, logLevel)
+	if err != nil {
+		return nil, fmt.Errorf("initializing MultiResource metrics backend %s: %w", args.Backend, err)
+	}
+
+	klog.V(2).Infof("Creating MultiResource plugin with alpha=%v, maxScore=%v, backend=%v, url=%v",
+		args.Alpha, args.MaxScore, args.Backend, args.URL)
 
+	resourceSpecs := filterEnabledResources(metricQuerySpecsFromEnv(), args)
 	plugin := &Plugin{
-		handle:     h,
-		promClient: NewPrometheusClient(promURL),
-		alpha:      alpha,
-		maxScore:   maxScore,
-		nodeStats:  make(map[string]NodeStats),
+		handle:               h,
+		metricsClient:        metricsClient,
+		alpha:                args.Alpha,
+		maxScore:             args.MaxScore,
+		nodeStats:            make(map[string]NodeStats),
+		nodeStatsTime:        make(map[string]time.Time),
+		nodeStatsEMA:         make(map[string]map[string]float64),
+		scoreStrategy:        scoreStrategyFromEnv(),
+		resourceSpecs:        resourceSpecs,
+		resourceWeights:      resourceWeightsFromArgs(resourceSpecs, args),
+		logLevel:             logLevel,
+		emaAlpha:             emaAlphaFromEnv(),
+		collectInterval:      collectIntervalFromArgs(args),
+		statsQueue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "multiresource-node-stats"),
+		preemptionDryRun:     args.PreemptionDryRun,
+		clusters:             clustersFromArgs(args),
+		clusterNodeStats:     make(map[string]NodeStats),
+		clusterNodeStatsTime: make(map[string]time.Time),
+	}
+
+	if err := plugin.startNodeInformer(context.Background(), h); err != nil {
+		return nil, fmt.Errorf("starting MultiResource node informer: %w", err)
 	}
 
-	// Start the goroutine to update node stats periodically
-	go plugin.startNodeStatsUpdater()
+	if len(plugin.clusters) > 0 {
+		go plugin.runFederatedCollector(context.Background())
+	}
+
+	if rulesPath := os.Getenv("HEALTHMGR_RULES_CONFIG"); rulesPath != "" {
+		rules, err := healthmgr.LoadRules(rulesPath)
+		if err != nil {
+			klog.Errorf("Error loading healthmgr rules from %s, node health management disabled: %v", rulesPath, err)
+		} else if querier, ok := plugin.metricsClient.(PromQLQuerier); ok {
+			queryFn := func(query string) (map[string]float64, error) {
+				return querier.Query(context.Background(), query)
+			}
+			plugin.healthMgr = healthmgr.NewManager(rules, queryFn, h.ClientSet(), os.Getenv("HEALTHMGR_SNAPSHOT_PATH"))
+			go plugin.healthMgr.Run(context.Background(), healthMgrInterval())
+		} else {
+			klog.Warningf("HEALTHMGR_RULES_CONFIG is set but backend %s doesn't support PromQL, node health management disabled", args.Backend)
+		}
+	}
 
 	return plugin, nil
 }
 
-// Name returns the name of the plugin
-func (p *Plugin) Name() string {
-	return Name
+// newMetricsClient builds the MetricsClient args.Backend selects.
+func newMetricsClient(args *Args, h framework.Handle, logLevel int) (MetricsClient, error) {
+	switch args.Backend {
+	case BackendThanos:
+		return NewThanosClient(args.URL, args.ThanosPartialResponse, args.ThanosDedup, args.PrometheusTimeoutSeconds, logLevel), nil
+	case BackendMetricsServer:
+		return NewMetricsServerClient(h.ClientSet()), nil
+	case BackendElasticsearch:
+		return NewElasticsearchClient(args.URL, args.ElasticsearchIndex, logLevel), nil
+	case BackendPrometheus:
+		return NewPrometheusClient(args.URL, args.PrometheusTimeoutSeconds, logLevel), nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q (want one of %s/%s/%s/%s)",
+			args.Backend, BackendPrometheus, BackendThanos, BackendMetricsServer, BackendElasticsearch)
+	}
 }
 
-// startNodeStatsUpdater periodically updates the node stats
-func (p *Plugin) startNodeStatsUpdater() {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+// healthMgrInterval returns how often healthmgr re-evaluates its rules,
+// configurable via HEALTHMGR_CHECK_INTERVAL (default 30s).
+func healthMgrInterval() time.Duration {
+	if val := os.Getenv("HEALTHMGR_CHECK_INTERVAL"); val != "" {
+		if secs, err := strconv.Atoi(val); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return 30 * time.Second
+}
 
-	for {
-		p.updateAllNodeStats()
-		<-ticker.C
+// logLevelFromEnv selects this plugin's own verbosity threshold, configurable
+// via MULTIRESOURCE_LOG_LEVEL (default 4, matching the klog -v level the
+// plugin's verbose logging used before it became independently gated).
+func logLevelFromEnv() int {
+	if val := os.Getenv("MULTIRESOURCE_LOG_LEVEL"); val != "" {
+		if level, err := strconv.Atoi(val); err == nil && level >= 0 {
+			return level
+		}
 	}
+	return 4
 }
 
-// updateAllNodeStats updates the stats for all nodes
-func (p *Plugin) updateAllNodeStats() {
-	nodes, err := p.handle.SnapshotSharedLister().NodeInfos().List()
-	if err != nil {
-		klog.Errorf("Error getting node list: %v", err)
-		return
+// emaAlphaFromEnv selects smoothNodeStats's EMA smoothing factor, configurable
+// via MULTIRESOURCE_EMA_ALPHA (default 0.3, weighting roughly the last
+// ~3 collection cycles). Values outside (0, 1] fall back to the default.
+func emaAlphaFromEnv() float64 {
+	if val := os.Getenv("MULTIRESOURCE_EMA_ALPHA"); val != "" {
+		if alpha, err := strconv.ParseFloat(val, 64); err == nil && alpha > 0 && alpha <= 1 {
+			return alpha
+		}
+	}
+	return 0.3
+}
+
+// scoreStrategyFromEnv selects which scoring function Score uses, configurable
+// via MULTIRESOURCE_SCORE_STRATEGY (one of the Strategy* constants in
+// score.go). Defaults to StrategyBalanced, preserving the original
+// alpha-based behavior for anyone not opting in.
+func scoreStrategyFromEnv() string {
+	switch val := os.Getenv("MULTIRESOURCE_SCORE_STRATEGY"); val {
+	case StrategyDominant, StrategyWeightedSum, StrategyLeastLoaded, StrategyBestFit:
+		return val
+	case "", StrategyBalanced:
+		return StrategyBalanced
+	default:
+		klog.Warningf("Unknown MULTIRESOURCE_SCORE_STRATEGY %q, falling back to %s", val, StrategyBalanced)
+		return StrategyBalanced
 	}
+}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// defaultResourceWeights gives the plugin's 7 built-in resources their
+// scoreMultiResource weights, summing to 1.0. A resource added via a custom
+// resourceSpecs config gets an even 1/N share in scoreMultiResource, and
+// weight 0 in StrategyWeightedSum unless its env var sets one.
+// Adding "gpu" (chunk6-4) took its weight out of the other six (cpu/mem
+// and disk/net alike) rather than
+// on top of the original 0.4/0.3/0.075x4 split, so a GPU-less cluster's
+// scores stay comparable to before GPU scoring existed.
+var defaultResourceWeights = map[string]float64{
+	"cpu":        0.35,
+	"mem":        0.25,
+	"disk-read":  0.05,
+	"disk-write": 0.05,
+	"net-up":     0.05,
+	"net-down":   0.05,
+	"gpu":        0.2,
+}
 
-	for _, nodeInfo := range nodes {
-		nodeName := nodeInfo.Node().Name
-		stats, err := p.getNodeStats(nodeName)
-		if err != nil {
-			klog.Errorf("Error getting stats for node %s: %v", nodeName, err)
-			continue
+// resourceWeightsFromEnv reads the per-resource weights StrategyWeightedSum
+// uses from MULTIRESOURCE_WEIGHT_<NAME> (name upper-cased, "-" -> "_"), one
+// per spec in resourceSpecs.
+func resourceWeightsFromEnv(specs []MetricQuerySpec) ResourceWeights {
+	weights := make(ResourceWeights, len(specs))
+	for _, spec := range specs {
+		envKey := "MULTIRESOURCE_WEIGHT_" + strings.ToUpper(strings.ReplaceAll(spec.Name, "-", "_"))
+		weights[spec.Name] = weightFromEnv(envKey, defaultResourceWeights[spec.Name])
+	}
+	return weights
+}
+
+// resourceWeightsFromArgs is resourceWeightsFromEnv with args.ResourceWeights
+// taking priority over the MULTIRESOURCE_WEIGHT_<NAME> env var for any
+// resource it sets explicitly.
+func resourceWeightsFromArgs(specs []MetricQuerySpec, args *Args) ResourceWeights {
+	weights := resourceWeightsFromEnv(specs)
+	for name, weight := range args.ResourceWeights {
+		if _, tracked := weights[name]; tracked {
+			weights[name] = weight
 		}
-		p.nodeStats[nodeName] = stats
 	}
+	return weights
 }
 
-// getNodeStatsFromCache returns node stats from the cache
+// collectIntervalFromArgs returns args.ScrapeIntervalSeconds as a Duration
+// when set, otherwise collectInterval()'s own env-var/default fallback.
+func collectIntervalFromArgs(args *Args) time.Duration {
+	if args.ScrapeIntervalSeconds > 0 {
+		return time.Duration(args.ScrapeIntervalSeconds) * time.Second
+	}
+	return collectInterval()
+}
+
+func weightFromEnv(key string, def float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if weight, err := strconv.ParseFloat(val, 64); err == nil && weight >= 0 {
+			return weight
+		}
+	}
+	return def
+}
+
+// Name returns the name of the plugin
+func (p *Plugin) Name() string {
+	return Name
+}
+
+// getNodeStatsFromCache returns node stats from the cache, treating an
+// entry older than maxStatsAge as missing so callers fall back to the
+// on-demand per-node query path rather than scheduling on stale data.
 func (p *Plugin) getNodeStatsFromCache(nodeName string) (NodeStats, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -123,5 +391,9 @@ func (p *Plugin) getNodeStatsFromCache(nodeName string) (NodeStats, error) {
 		return NodeStats{}, fmt.Errorf("no stats found for node %s", nodeName)
 	}
 
+	if age := time.Since(p.nodeStatsTime[nodeName]); age > maxStatsAge() {
+		return NodeStats{}, fmt.Errorf("stats for node %s are stale (%s old)", nodeName, age)
+	}
+
 	return stats, nil
 }
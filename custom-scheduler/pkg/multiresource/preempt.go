@@ -0,0 +1,218 @@
+package multiresource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// systemClusterCriticalPriorityClass names the PriorityClass
+// selectPreemptionVictims never evicts, matching the PriorityClass
+// Kubernetes ships system-critical add-ons with.
+const systemClusterCriticalPriorityClass = "system-cluster-critical"
+
+// podPriority returns pod's effective Priority (the apiserver-assigned
+// pod.Spec.Priority, derived from its PriorityClassName), 0 if unset.
+func podPriority(pod *v1.Pod) int {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return int(*pod.Spec.Priority)
+}
+
+// preemptionCandidate is one node's cheapest eviction plan: evicting
+// victims frees enough of every deficient resource for the preemptor to
+// fit, per resourceDeficit/selectPreemptionVictims.
+type preemptionCandidate struct {
+	node          string
+	victims       []*v1.Pod
+	evictedWeight int
+}
+
+// betterPreemptionCandidate reports whether a causes less disruption than
+// b: lower total evicted priority-weight wins, ties broken by fewer
+// victims, matching the generic scheduler's own preference for leaving
+// higher-priority/fewer pods standing.
+func betterPreemptionCandidate(a, b preemptionCandidate) bool {
+	if a.evictedWeight != b.evictedWeight {
+		return a.evictedWeight < b.evictedWeight
+	}
+	return len(a.victims) < len(b.victims)
+}
+
+// resourceDeficit returns, for every resource in p.resourceSpecs other than
+// "gpu" (whole-device fit is handled separately by gpuDevicesFree, not by
+// a freeable amount), how much more of it podReq needs than nodeStats
+// currently has free. A resource already satisfied is omitted, so an empty
+// map means the node only failed Filter over GPUs, which eviction can't fix.
+func (p *Plugin) resourceDeficit(podReq PodRequest, nodeStats NodeStats) map[string]float64 {
+	deficit := make(map[string]float64)
+	for _, spec := range p.resourceSpecs {
+		if spec.Name == "gpu" {
+			continue
+		}
+		demand := podReq.Resources[spec.Name]
+		free := nodeStats.Resources[spec.Name].Free
+		if demand > free {
+			deficit[spec.Name] = demand - free
+		}
+	}
+	return deficit
+}
+
+// deficitCovered reports whether every entry in remaining has reached 0.
+func deficitCovered(remaining map[string]float64) bool {
+	for _, amount := range remaining {
+		if amount > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// selectPreemptionVictims greedily builds the cheapest eviction set among
+// running (pods already assigned to the node pod failed Filter on):
+// candidates strictly lower-priority than pod, excluding
+// systemClusterCriticalPriorityClass pods and ones already terminating,
+// are tried in ascending-priority order and added to victims until their
+// summed measured demand (extractPodRequirements, the same figure
+// canScheduleMulti compares against free capacity) covers deficit in every
+// dimension. ok is false if evicting every eligible candidate still
+// wouldn't cover it.
+func (p *Plugin) selectPreemptionVictims(pod *v1.Pod, deficit map[string]float64, running []*v1.Pod) (victims []*v1.Pod, ok bool) {
+	eligible := make([]*v1.Pod, 0, len(running))
+	podPrio := podPriority(pod)
+	for _, candidate := range running {
+		if candidate.Spec.PriorityClassName == systemClusterCriticalPriorityClass {
+			continue
+		}
+		if candidate.DeletionTimestamp != nil {
+			continue
+		}
+		if podPriority(candidate) >= podPrio {
+			continue
+		}
+		eligible = append(eligible, candidate)
+	}
+	sort.Slice(eligible, func(i, j int) bool {
+		return podPriority(eligible[i]) < podPriority(eligible[j])
+	})
+
+	remaining := make(map[string]float64, len(deficit))
+	for name, amount := range deficit {
+		remaining[name] = amount
+	}
+
+	for _, candidate := range eligible {
+		if deficitCovered(remaining) {
+			break
+		}
+		victims = append(victims, candidate)
+		freed := p.extractPodRequirements(candidate)
+		for name := range remaining {
+			remaining[name] -= freed.Resources[name]
+		}
+	}
+	return victims, deficitCovered(remaining)
+}
+
+// nodeRunningPods returns the pods currently assigned to nodeName in the
+// scheduler's cached snapshot, the same source topologyConcentration reads
+// from; nil if the plugin has no handle (e.g. a unit test) or the snapshot
+// has no entry for nodeName yet.
+func (p *Plugin) nodeRunningPods(nodeName string) []*v1.Pod {
+	if p.handle == nil {
+		return nil
+	}
+	lister := p.handle.SnapshotSharedLister()
+	if lister == nil {
+		return nil
+	}
+	nodeInfo, err := lister.NodeInfos().Get(nodeName)
+	if err != nil || nodeInfo == nil {
+		return nil
+	}
+	pods := make([]*v1.Pod, 0, len(nodeInfo.Pods))
+	for _, podInfo := range nodeInfo.Pods {
+		pods = append(pods, podInfo.Pod)
+	}
+	return pods
+}
+
+// evictPod evicts victim via the eviction subresource, the same mechanism
+// preemptive_sched's evictPod uses, so a covering PodDisruptionBudget still
+// gets to veto it.
+func (p *Plugin) evictPod(ctx context.Context, victim *v1.Pod) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      victim.Name,
+			Namespace: victim.Namespace,
+		},
+	}
+	return p.handle.ClientSet().PolicyV1().Evictions(eviction.Namespace).Evict(ctx, eviction)
+}
+
+// PostFilter implements framework.PostFilterPlugin. pod reaches here only
+// after failing Filter on every node; PostFilter looks for the cheapest
+// priority-preemption plan across those nodes (see resourceDeficit,
+// selectPreemptionVictims, betterPreemptionCandidate) and, unless
+// Args.PreemptionDryRun is set, evicts the chosen victims and nominates
+// their node so the next scheduling cycle retries pod there once they've
+// left.
+func (p *Plugin) PostFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, filteredNodeStatusMap framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
+	lh := klog.FromContext(ctx).WithValues("pod", fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+	podReq := p.extractPodRequirements(pod)
+
+	var best *preemptionCandidate
+	for nodeName := range filteredNodeStatusMap {
+		nodeStats, err := p.getNodeStatsFromCache(nodeName)
+		if err != nil {
+			continue
+		}
+		deficit := p.resourceDeficit(podReq, nodeStats)
+		if len(deficit) == 0 {
+			continue
+		}
+
+		victims, ok := p.selectPreemptionVictims(pod, deficit, p.nodeRunningPods(nodeName))
+		if !ok {
+			continue
+		}
+
+		var weight int
+		for _, v := range victims {
+			weight += podPriority(v)
+		}
+		candidate := preemptionCandidate{node: nodeName, victims: victims, evictedWeight: weight}
+		if best == nil || betterPreemptionCandidate(candidate, *best) {
+			best = &candidate
+		}
+	}
+
+	if best == nil {
+		return nil, framework.NewStatus(framework.Unschedulable, "no priority-preemption candidate found")
+	}
+
+	if p.preemptionDryRun {
+		for _, victim := range best.victims {
+			lh.Info("Preemption dry run: would evict pod", "victim", fmt.Sprintf("%s/%s", victim.Namespace, victim.Name), "node", best.node)
+		}
+		return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("preemption dry run: would evict %d pod(s) on node %s", len(best.victims), best.node))
+	}
+
+	for _, victim := range best.victims {
+		if err := p.evictPod(ctx, victim); err != nil {
+			lh.Error(err, "Failed to evict preemption victim", "victim", fmt.Sprintf("%s/%s", victim.Namespace, victim.Name), "node", best.node)
+			return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("evicting preemption victim %s/%s: %v", victim.Namespace, victim.Name, err))
+		}
+		lh.Info("Evicted preemption victim", "victim", fmt.Sprintf("%s/%s", victim.Namespace, victim.Name), "node", best.node)
+	}
+
+	return framework.NewPostFilterResultWithNominatedNode(best.node), framework.NewStatus(framework.Success)
+}
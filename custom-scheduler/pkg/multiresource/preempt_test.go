@@ -0,0 +1,128 @@
+package multiresource
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podWithPriority builds a minimal pod with the given priority and CPU
+// request, used as a preemption candidate.
+func podWithPriority(name string, priority int32, cpu float64) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: v1.PodSpec{
+			Priority: &priority,
+			Containers: []v1.Container{{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU: resource.MustParse(fmt.Sprintf("%g", cpu)),
+					},
+				},
+			}},
+		},
+	}
+}
+
+func TestResourceDeficit_OmitsSatisfiedResources(t *testing.T) {
+	p := &Plugin{resourceSpecs: []MetricQuerySpec{{Name: "cpu"}, {Name: "mem"}, {Name: "gpu"}}}
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 4, "mem": 1}}
+	stats := NodeStats{Resources: map[string]ResourceAvailability{
+		"cpu": {Total: 4, Free: 2},
+		"mem": {Total: 4, Free: 4},
+	}}
+
+	got := p.resourceDeficit(podReq, stats)
+	if len(got) != 1 {
+		t.Fatalf("resourceDeficit() = %v, want exactly one deficient resource", got)
+	}
+	if got["cpu"] != 2 {
+		t.Errorf("resourceDeficit()[cpu] = %v, want 2", got["cpu"])
+	}
+}
+
+func TestResourceDeficit_IgnoresGPU(t *testing.T) {
+	p := &Plugin{resourceSpecs: []MetricQuerySpec{{Name: "gpu"}}}
+	podReq := PodRequest{GPURequest: 1}
+	stats := NodeStats{Resources: map[string]ResourceAvailability{"gpu": {Total: 1, Free: 0}}}
+
+	if got := p.resourceDeficit(podReq, stats); len(got) != 0 {
+		t.Errorf("resourceDeficit() = %v, want empty (GPU fit isn't eviction-driven)", got)
+	}
+}
+
+func TestSelectPreemptionVictims_SkipsSystemClusterCritical(t *testing.T) {
+	p := &Plugin{resourceSpecs: []MetricQuerySpec{{Name: "cpu"}}}
+	preemptor := podWithPriority("preemptor", 100, 2)
+	critical := podWithPriority("critical", 10, 4)
+	critical.Spec.PriorityClassName = systemClusterCriticalPriorityClass
+
+	victims, ok := p.selectPreemptionVictims(preemptor, map[string]float64{"cpu": 2}, []*v1.Pod{critical})
+	if ok {
+		t.Errorf("selectPreemptionVictims() = %v, true, want no feasible set when the only candidate is system-cluster-critical", victims)
+	}
+}
+
+func TestSelectPreemptionVictims_SkipsEqualOrHigherPriority(t *testing.T) {
+	p := &Plugin{resourceSpecs: []MetricQuerySpec{{Name: "cpu"}}}
+	preemptor := podWithPriority("preemptor", 100, 2)
+	peer := podWithPriority("peer", 100, 4)
+
+	victims, ok := p.selectPreemptionVictims(preemptor, map[string]float64{"cpu": 2}, []*v1.Pod{peer})
+	if ok {
+		t.Errorf("selectPreemptionVictims() = %v, true, want no feasible set when the only candidate is equal priority", victims)
+	}
+}
+
+func TestSelectPreemptionVictims_GreedyAscendingPriority(t *testing.T) {
+	p := &Plugin{resourceSpecs: []MetricQuerySpec{{Name: "cpu"}}}
+	preemptor := podWithPriority("preemptor", 100, 1)
+	low := podWithPriority("low", 1, 1)
+	mid := podWithPriority("mid", 10, 1)
+
+	victims, ok := p.selectPreemptionVictims(preemptor, map[string]float64{"cpu": 1}, []*v1.Pod{mid, low})
+	if !ok {
+		t.Fatalf("selectPreemptionVictims() ok = false, want true")
+	}
+	if len(victims) != 1 || victims[0].Name != "low" {
+		t.Errorf("selectPreemptionVictims() = %v, want just [low] (the lowest-priority candidate)", victims)
+	}
+}
+
+func TestSelectPreemptionVictims_InfeasibleReturnsFalse(t *testing.T) {
+	p := &Plugin{resourceSpecs: []MetricQuerySpec{{Name: "cpu"}}}
+	preemptor := podWithPriority("preemptor", 100, 10)
+	low := podWithPriority("low", 1, 1)
+
+	victims, ok := p.selectPreemptionVictims(preemptor, map[string]float64{"cpu": 10}, []*v1.Pod{low})
+	if ok {
+		t.Errorf("selectPreemptionVictims() = %v, true, want false when no eligible set covers the deficit", victims)
+	}
+}
+
+func TestBetterPreemptionCandidate_PrefersLowerWeight(t *testing.T) {
+	cheap := preemptionCandidate{evictedWeight: 5, victims: []*v1.Pod{{}}}
+	costly := preemptionCandidate{evictedWeight: 50, victims: []*v1.Pod{{}}}
+
+	if !betterPreemptionCandidate(cheap, costly) {
+		t.Error("betterPreemptionCandidate() = false, want true for the lower evicted-weight candidate")
+	}
+}
+
+func TestBetterPreemptionCandidate_TiesBrokenByFewerVictims(t *testing.T) {
+	fewer := preemptionCandidate{evictedWeight: 10, victims: []*v1.Pod{{}}}
+	more := preemptionCandidate{evictedWeight: 10, victims: []*v1.Pod{{}, {}}}
+
+	if !betterPreemptionCandidate(fewer, more) {
+		t.Error("betterPreemptionCandidate() = false, want true for the candidate with fewer victims")
+	}
+}
+
+func TestPodPriority_NilIsZero(t *testing.T) {
+	if got := podPriority(&v1.Pod{}); got != 0 {
+		t.Errorf("podPriority() on a pod with no Priority set = %d, want 0", got)
+	}
+}
@@ -0,0 +1,197 @@
+package multiresource
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+)
+
+// Resource kinds select how a MetricQuerySpec's PromQL/CapacityPromQL
+// combine into a resource's Total/Free. The built-in CPU/disk/network
+// resources each compose their queries differently, so a spec can't
+// assume a single formula.
+const (
+	// KindRatio: PromQL returns a usage ratio in [0,1]; CapacityPromQL
+	// returns total capacity. Free = Total * (1 - ratio).
+	KindRatio = "ratio"
+	// KindDirect: PromQL returns the free amount directly; CapacityPromQL
+	// returns total capacity directly.
+	KindDirect = "direct"
+	// KindRate: PromQL returns a current usage rate; capacity is assumed
+	// to be the rate divided by a fixed headroom fraction (matching the
+	// original hard-coded "current rate is 80% of capacity" heuristic)
+	// unless CapacityPromQL is set.
+	KindRate = "rate"
+)
+
+// rateAssumedUtilization is the fraction of capacity KindRate assumes the
+// current rate represents when CapacityPromQL isn't set, preserving the
+// original getNodeStats behavior for disk/network resources.
+const rateAssumedUtilization = 0.8
+
+// MetricQuerySpec describes one resource the plugin tracks: which PromQL
+// query (or pair of queries) to run, how to turn the result(s) into a
+// Total/Free pair, and the pod-annotation key used to request it.
+// Loading specs from a config file is what lets operators add a new
+// resource (GPU memory pressure, PSI, a custom exporter counter) without a
+// code change; see LoadMetricQuerySpecs.
+//
+// PromQL/CapacityPromQL are always cluster-wide, grouped by
+// AggregationLabel (e.g. "sum by (instance) (...)") rather than scoped to
+// one node, so both the batched collector and the on-demand per-node
+// lookup in getNodeStats run the exact same query and just index into the
+// result by node name.
+type MetricQuerySpec struct {
+	// Name identifies the resource everywhere it's threaded through the
+	// plugin: the key in NodeStats.Resources/PodRequest.Resources, and the
+	// suffix of the scheduler.extender/<name> pod annotation.
+	Name string `yaml:"name"`
+	// PromQL is the query whose result Kind interprets as either a usage
+	// ratio, a free amount, or a usage rate (see Kind* constants).
+	PromQL string `yaml:"promQL"`
+	// CapacityPromQL is the query for the resource's total capacity. Its
+	// meaning depends on Kind; empty means "derive from PromQL" (KindRate
+	// only).
+	CapacityPromQL string `yaml:"capacityPromQL"`
+	// AggregationLabel is the label PromQL/CapacityPromQL results are
+	// grouped by (e.g. "instance" or "node"); defaults to "instance" to
+	// match the rest of the plugin's Prometheus queries.
+	AggregationLabel string `yaml:"aggregationLabel"`
+	// Unit is informational, surfaced in logs/metrics; it has no effect on
+	// scheduling.
+	Unit string `yaml:"unit"`
+	// Kind selects how PromQL/CapacityPromQL combine; one of the Kind*
+	// constants. Defaults to KindRate.
+	Kind string `yaml:"kind"`
+	// Smoothed, when true, averages PromQL over diskNetSmoothWindow via a
+	// range query instead of trusting one instant sample; used by the
+	// rate-kind disk/network built-ins.
+	Smoothed bool `yaml:"smoothed"`
+	// Default is the fallback total capacity used when both queries fail
+	// or return no sample for a node, matching the hard-coded per-resource
+	// defaults the plugin always fell back to.
+	Default float64 `yaml:"default"`
+}
+
+// metricQuerySpecsFile is the top-level shape of the YAML specs config.
+type metricQuerySpecsFile struct {
+	Resources []MetricQuerySpec `yaml:"resources"`
+}
+
+// defaultMetricQuerySpecs is the built-in resource set a cluster that never
+// sets MULTIRESOURCE_METRIC_SPECS_CONFIG uses: the plugin's original 6
+// resources as specs, plus "gpu".
+func defaultMetricQuerySpecs() []MetricQuerySpec {
+	return []MetricQuerySpec{
+		{
+			Name:             "cpu",
+			Kind:             KindRatio,
+			PromQL:           `1 - avg by (instance) (rate(node_cpu_seconds_total{mode="idle"}[5m]))`,
+			CapacityPromQL:   `count(node_cpu_seconds_total{mode="idle"}) by (instance)`,
+			AggregationLabel: "instance",
+			Unit:             "cores",
+			Default:          1.0,
+		},
+		{
+			Name:             "mem",
+			Kind:             KindDirect,
+			PromQL:           `node_memory_MemAvailable_bytes`,
+			CapacityPromQL:   `node_memory_MemTotal_bytes`,
+			AggregationLabel: "instance",
+			Unit:             "bytes",
+			Default:          4 * 1024 * 1024 * 1024,
+		},
+		{
+			Name:             "disk-read",
+			Kind:             KindRate,
+			PromQL:           `sum by (instance) (rate(node_disk_read_bytes_total[1m]))`,
+			AggregationLabel: "instance",
+			Smoothed:         true,
+			Unit:             "bytes/sec",
+			Default:          100 * 1024 * 1024,
+		},
+		{
+			Name:             "disk-write",
+			Kind:             KindRate,
+			PromQL:           `sum by (instance) (rate(node_disk_written_bytes_total[1m]))`,
+			AggregationLabel: "instance",
+			Smoothed:         true,
+			Unit:             "bytes/sec",
+			Default:          50 * 1024 * 1024,
+		},
+		{
+			Name:             "net-up",
+			Kind:             KindRate,
+			PromQL:           `sum by (instance) (rate(node_network_transmit_bytes_total[1m]))`,
+			AggregationLabel: "instance",
+			Smoothed:         true,
+			Unit:             "bytes/sec",
+			Default:          125 * 1024 * 1024,
+		},
+		{
+			Name:             "net-down",
+			Kind:             KindRate,
+			PromQL:           `sum by (instance) (rate(node_network_receive_bytes_total[1m]))`,
+			AggregationLabel: "instance",
+			Smoothed:         true,
+			Unit:             "bytes/sec",
+			Default:          125 * 1024 * 1024,
+		},
+		{
+			// gpu's Total is a device count, not a capacity amount, same as
+			// every other resource built from a DCGM_FI_DEV_GPU_UTIL sample
+			// per device: CapacityPromQL counts how many device series
+			// instance has. A node with no GPUs reports no samples at all,
+			// so it falls back to Default (no GPUs) rather than the 0.8
+			// "missing data" heuristic every other ratio resource uses.
+			Name:             "gpu",
+			Kind:             KindRatio,
+			PromQL:           `avg by (instance) (DCGM_FI_DEV_GPU_UTIL/100)`,
+			CapacityPromQL:   `count by (instance) (DCGM_FI_DEV_GPU_UTIL)`,
+			AggregationLabel: "instance",
+			Unit:             "devices",
+			Default:          0,
+		},
+	}
+}
+
+// LoadMetricQuerySpecs reads a list of MetricQuerySpecs from a YAML file,
+// falling back to an empty Kind/AggregationLabel of "rate"/"instance"
+// respectively so a minimal config only needs to set PromQL per resource.
+func LoadMetricQuerySpecs(path string) ([]MetricQuerySpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading metric query specs file %s: %v", path, err)
+	}
+	var parsed metricQuerySpecsFile
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing metric query specs file %s: %v", path, err)
+	}
+	for i := range parsed.Resources {
+		if parsed.Resources[i].Kind == "" {
+			parsed.Resources[i].Kind = KindRate
+		}
+		if parsed.Resources[i].AggregationLabel == "" {
+			parsed.Resources[i].AggregationLabel = "instance"
+		}
+	}
+	return parsed.Resources, nil
+}
+
+// metricQuerySpecsFromEnv loads specs from MULTIRESOURCE_METRIC_SPECS_CONFIG
+// if set, otherwise returns defaultMetricQuerySpecs (the 7 built-in
+// resources), so existing deployments need no config change.
+func metricQuerySpecsFromEnv() []MetricQuerySpec {
+	path := os.Getenv("MULTIRESOURCE_METRIC_SPECS_CONFIG")
+	if path == "" {
+		return defaultMetricQuerySpecs()
+	}
+	specs, err := LoadMetricQuerySpecs(path)
+	if err != nil {
+		klog.Errorf("Error loading metric query specs from %s, falling back to built-in resources: %v", path, err)
+		return defaultMetricQuerySpecs()
+	}
+	return specs
+}
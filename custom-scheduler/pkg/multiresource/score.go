@@ -4,39 +4,168 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"strconv"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
+// Strategy* name the scoring functions Score can dispatch to, selected via
+// Plugin.scoreStrategy (see scoreStrategyFromEnv). StrategyBalanced is the
+// default and preserves the original alpha-based packing/spreading behavior.
+const (
+	StrategyBalanced    = "balanced"
+	StrategyDominant    = "dominant"
+	StrategyWeightedSum = "weighted-sum"
+	StrategyLeastLoaded = "least-loaded"
+	StrategyBestFit     = "best-fit"
+)
+
+// ResourceWeights holds the per-resource weights used by StrategyWeightedSum,
+// keyed by MetricQuerySpec.Name. They need not sum to 1; scoreWeightedSum
+// normalizes by their total.
+type ResourceWeights map[string]float64
+
+// alphaAnnotation lets a pod override Plugin.alpha (the bin-packing vs
+// spreading knob) for itself, e.g. a cache that always wants to spread
+// regardless of the cluster-wide default.
+const alphaAnnotation = "scheduler.extender/alpha"
+
+// topologyKeyAnnotation names the node label (e.g.
+// "topology.kubernetes.io/zone", "kubernetes.io/hostname") that Score
+// spreads a pod's owner across when set. See topologyConcentration.
+const topologyKeyAnnotation = "scheduler.extender/topology-key"
+
+// topologyPenaltyWeight scales how much of maxScore a node can lose to
+// topologyConcentration. A full-weight, full-alpha, fully-concentrated
+// domain forfeits this fraction of maxScore, so a single outlier domain
+// can't eclipse the resource-based portion of the score entirely.
+const topologyPenaltyWeight = 0.25
+
+// podAlpha returns the effective alpha for pod: its alphaAnnotation value
+// if set and parseable, clamped to [0, 1], otherwise Plugin.alpha.
+func (p *Plugin) podAlpha(pod *v1.Pod) float64 {
+	raw, ok := pod.Annotations[alphaAnnotation]
+	if !ok {
+		return p.alpha
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		klog.Warningf("Pod %s/%s has non-numeric %s annotation %q, using plugin default alpha", pod.Namespace, pod.Name, alphaAnnotation, raw)
+		return p.alpha
+	}
+	return math.Max(0.0, math.Min(1.0, v))
+}
+
+// podOwnerKey identifies the controller pod belongs to (so every pod it
+// re-creates resolves to the same key), scoped to the pod's namespace.
+// Returns false for an unowned pod, which has no siblings to spread from.
+func podOwnerKey(pod *v1.Pod) (string, bool) {
+	for _, owner := range pod.OwnerReferences {
+		return pod.Namespace + "/" + owner.Kind + "/" + owner.Name, true
+	}
+	return "", false
+}
+
+// topologyConcentration returns the fraction of pod's already-scheduled
+// siblings (same owner, per podOwnerKey) that share nodeName's value for
+// topologyKey, using the scheduler's node/pod snapshot. 0 if pod has no
+// owner, nodeName's topology value is unknown, or it has no siblings yet,
+// so a lone first replica is never penalized.
+func (p *Plugin) topologyConcentration(pod *v1.Pod, nodeName, topologyKey string) float64 {
+	ownerKey, ok := podOwnerKey(pod)
+	if !ok || p.handle == nil {
+		return 0
+	}
+	lister := p.handle.SnapshotSharedLister()
+	if lister == nil {
+		return 0
+	}
+	nodeInfos, err := lister.NodeInfos().List()
+	if err != nil {
+		return 0
+	}
+
+	domain, domainKnown := "", false
+	for _, nodeInfo := range nodeInfos {
+		if node := nodeInfo.Node(); node != nil && node.Name == nodeName {
+			domain, domainKnown = node.Labels[topologyKey]
+			break
+		}
+	}
+	if !domainKnown {
+		return 0
+	}
+
+	var total, inDomain int
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		nodeDomain, hasDomain := node.Labels[topologyKey]
+		for _, podInfo := range nodeInfo.Pods {
+			if key, ok := podOwnerKey(podInfo.Pod); ok && key == ownerKey {
+				total++
+				if hasDomain && nodeDomain == domain {
+					inDomain++
+				}
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(inDomain) / float64(total)
+}
+
 // Score scores nodes based on resource availability
 func (p *Plugin) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
 	// Extract pod requirements
 	podReq := p.extractPodRequirements(pod)
 
-	// Get node stats from cache
-	nodeStats, err := p.getNodeStatsFromCache(nodeName)
+	// Get node stats from cache, refreshing on a miss (see
+	// getOrRefreshNodeStats).
+	nodeStats, err := p.getOrRefreshNodeStats(ctx, nodeName)
 	if err != nil {
 		klog.Warningf("Error getting node stats for %s: %v", nodeName, err)
+		return 0, framework.NewStatus(framework.Error, fmt.Sprintf("Failed to get node %s stats", nodeName))
+	}
 
-		// Attempt to refresh the stats for this node
-		klog.Infof("Refreshing node stats for %s", nodeName)
-		nodeStats, err = p.getNodeStats(nodeName)
-		if err != nil {
-			klog.Warningf("Still error getting node stats for %s: %v", nodeName, err)
-			return 0, framework.NewStatus(framework.Error, fmt.Sprintf("Failed to get node %s stats", nodeName))
-		}
+	alpha := p.podAlpha(pod)
 
-		// Cache the updated stats
-		p.mu.Lock()
-		p.nodeStats[nodeName] = nodeStats
-		p.mu.Unlock()
+	// Calculate score using whichever strategy the plugin was configured with
+	var score int
+	switch p.scoreStrategy {
+	case StrategyDominant:
+		score = p.scoreDominantResourceFairness(podReq, nodeStats)
+	case StrategyWeightedSum:
+		score = p.scoreWeightedSum(podReq, nodeStats)
+	case StrategyLeastLoaded:
+		score = p.scoreLeastLoaded(podReq, nodeStats)
+	case StrategyBestFit:
+		score = p.scoreBestFit(podReq, nodeStats)
+	default:
+		score = p.scoreMultiResource(podReq, nodeStats, alpha)
 	}
 
-	// Calculate score
-	score := p.scoreMultiResource(podReq, nodeStats)
-	klog.V(4).Infof("Score for node %s: %d", nodeName, score)
+	// Topology-aware spreading: a pod that opts in via topologyKeyAnnotation
+	// loses score on nodes whose topology domain already holds a
+	// disproportionate share of its siblings, independent of the strategy
+	// above, so a live-resource-driven scorer also gets anti-affinity-like
+	// behavior without a hard constraint.
+	if topologyKey := pod.Annotations[topologyKeyAnnotation]; topologyKey != "" {
+		concentration := p.topologyConcentration(pod, nodeName, topologyKey)
+		penalty := int(concentration * alpha * topologyPenaltyWeight * float64(p.maxScore))
+		score -= penalty
+		if score < 0 {
+			score = 0
+		}
+		klog.V(5).Infof("Topology concentration for node %s (key=%s): %.2f, penalty=%d", nodeName, topologyKey, concentration, penalty)
+	}
+
+	klog.V(4).Infof("Score for node %s (strategy=%s): %d", nodeName, p.scoreStrategy, score)
 
 	return int64(score), nil
 }
@@ -78,81 +207,170 @@ func (p *Plugin) NormalizeScore(ctx context.Context, state *framework.CycleState
 	return nil
 }
 
-// scoreMultiResource calculates a score for a node based on its resource availability and the pod's requirements
-// It considers CPU, memory, disk I/O, and network bandwidth
-// Higher scores are better
-func (p *Plugin) scoreMultiResource(podReq PodRequest, nodeStats NodeStats) int {
-	// Temporary variables to calculate resource usage after the pod is placed
-	cpuUsage := (nodeStats.CPUTotal - nodeStats.CPUFree + podReq.CPU) / nodeStats.CPUTotal
-	memUsage := (nodeStats.MemTotal - nodeStats.MemFree + podReq.Mem) / nodeStats.MemTotal
-	diskReadUsage := 0.0
-	if nodeStats.DiskReadTotal > 0 {
-		diskReadUsage = (nodeStats.DiskReadTotal - nodeStats.DiskReadFree + podReq.DiskRead) / nodeStats.DiskReadTotal
-	}
-	diskWriteUsage := 0.0
-	if nodeStats.DiskWriteTotal > 0 {
-		diskWriteUsage = (nodeStats.DiskWriteTotal - nodeStats.DiskWriteFree + podReq.DiskWrite) / nodeStats.DiskWriteTotal
-	}
-	netUpUsage := 0.0
-	if nodeStats.NetUpTotal > 0 {
-		netUpUsage = (nodeStats.NetUpTotal - nodeStats.NetUpFree + podReq.NetUp) / nodeStats.NetUpTotal
-	}
-	netDownUsage := 0.0
-	if nodeStats.NetDownTotal > 0 {
-		netDownUsage = (nodeStats.NetDownTotal - nodeStats.NetDownFree + podReq.NetDown) / nodeStats.NetDownTotal
-	}
-
-	// Ensure all usage values are within [0, 1]
-	cpuUsage = math.Max(0.0, math.Min(1.0, cpuUsage))
-	memUsage = math.Max(0.0, math.Min(1.0, memUsage))
-	diskReadUsage = math.Max(0.0, math.Min(1.0, diskReadUsage))
-	diskWriteUsage = math.Max(0.0, math.Min(1.0, diskWriteUsage))
-	netUpUsage = math.Max(0.0, math.Min(1.0, netUpUsage))
-	netDownUsage = math.Max(0.0, math.Min(1.0, netDownUsage))
-
-	// Calculate balanced resource usage score
-	// - For alpha=0: prefer spreading (lower usage is better)
-	// - For alpha=1: prefer packing (higher usage is better)
-	// We weight CPU and memory higher than I/O and network
-	cpuScore := p.resourceToScore(cpuUsage, 0.4)
-	memScore := p.resourceToScore(memUsage, 0.3)
-	diskReadScore := p.resourceToScore(diskReadUsage, 0.075)
-	diskWriteScore := p.resourceToScore(diskWriteUsage, 0.075)
-	netUpScore := p.resourceToScore(netUpUsage, 0.075)
-	netDownScore := p.resourceToScore(netDownUsage, 0.075)
-
-	// Combine scores with weights
-	totalScore := cpuScore + memScore + diskReadScore + diskWriteScore + netUpScore + netDownScore
-
-	// Scale to maxScore
-	finalScore := int(totalScore * float64(p.maxScore))
+// computeResourceUsage projects podReq onto nodeStats and clamps each
+// resource's resulting usage fraction to [0, 1], keyed by resource name.
+// Shared by every scoring strategy so they agree on what "usage" means.
+func computeResourceUsage(podReq PodRequest, nodeStats NodeStats) map[string]float64 {
+	usage := make(map[string]float64, len(nodeStats.Resources))
+	for name, avail := range nodeStats.Resources {
+		u := 0.0
+		if avail.Total > 0 {
+			u = (avail.Total - avail.Free + podReq.Resources[name]) / avail.Total
+		}
+		usage[name] = math.Max(0.0, math.Min(1.0, u))
+	}
+	return usage
+}
+
+// cpuLimitUtilWeight/memLimitUtilWeight weight NodeStats.CPULimitUtilization/
+// MemLimitUtilization in scoreMultiResource, on top of the per-resource
+// weights in defaultResourceWeights, so a node whose steady-state limit
+// utilization is already high scores lower even if its momentary Free
+// headroom looks fine.
+const (
+	cpuLimitUtilWeight = 0.05
+	memLimitUtilWeight = 0.05
+)
 
+// scoreMultiResource calculates a score for a node based on its resource
+// availability and the pod's requirements, weighting every resource by
+// defaultResourceWeights (falling back to an even split for any resource
+// not in that map, e.g. one added via a custom resourceSpecs config), plus
+// the node's CPU/mem limit utilization ratios. alpha is the bin-packing vs
+// spreading knob resourceToScore uses (Plugin.alpha, or a pod's override;
+// see podAlpha). Higher scores are better.
+func (p *Plugin) scoreMultiResource(podReq PodRequest, nodeStats NodeStats, alpha float64) int {
+	u := computeResourceUsage(podReq, nodeStats)
+
+	var totalScore float64
+	logged := make(map[string]float64, len(u)+2)
+	for name, usage := range u {
+		weight, ok := defaultResourceWeights[name]
+		if !ok {
+			weight = 1.0 / float64(len(u))
+		}
+		resourceScore := resourceToScore(usage, weight, alpha)
+		totalScore += resourceScore
+		logged[name] = resourceScore
+	}
+
+	cpuLimitScore := resourceToScore(nodeStats.CPULimitUtilization, cpuLimitUtilWeight, alpha)
+	memLimitScore := resourceToScore(nodeStats.MemLimitUtilization, memLimitUtilWeight, alpha)
+	totalScore += cpuLimitScore + memLimitScore
+	logged["cpuLimitUtil"] = cpuLimitScore
+	logged["memLimitUtil"] = memLimitScore
+
+	finalScore := int(totalScore * float64(p.maxScore))
 	if finalScore > p.maxScore {
 		finalScore = p.maxScore
 	}
 
-	klog.V(5).Infof("Scores for node: CPU=%.2f, Mem=%.2f, DiskRead=%.2f, DiskWrite=%.2f, NetUp=%.2f, NetDown=%.2f, Total=%d",
-		cpuScore, memScore, diskReadScore, diskWriteScore, netUpScore, netDownScore, finalScore)
+	klog.V(5).Infof("Scores for node: %+v, Total=%d", logged, finalScore)
 
 	return finalScore
 }
 
 // resourceToScore converts a resource usage value to a score based on alpha
-func (p *Plugin) resourceToScore(usage float64, weight float64) float64 {
+func resourceToScore(usage float64, weight float64, alpha float64) float64 {
 	// For alpha=0, lower usage is better (spreading)
 	// For alpha=1, higher usage is better (packing)
 	var score float64
-	if p.alpha < 0.5 {
+	if alpha < 0.5 {
 		// Spreading: score = 1 - usage (adjusted by alpha)
 		spreadingScore := 1.0 - usage
 		packingScore := usage
-		score = spreadingScore*(1-p.alpha*2) + packingScore*(p.alpha*2)
+		score = spreadingScore*(1-alpha*2) + packingScore*(alpha*2)
 	} else {
 		// Packing: score = usage (adjusted by alpha)
 		spreadingScore := 1.0 - usage
 		packingScore := usage
-		score = spreadingScore*(2.0-p.alpha*2) + packingScore*((p.alpha-0.5)*2)
+		score = spreadingScore*(2.0-alpha*2) + packingScore*((alpha-0.5)*2)
 	}
 
 	return score * weight
 }
+
+// scoreDominantResourceFairness scores a node by its dominant share: the
+// largest usage fraction across every tracked resource once podReq is
+// placed. Lower dominant share is better, so nodes are ranked by how much
+// headroom remains in whichever resource podReq stresses most, matching
+// Dominant Resource Fairness's notion of a pod's "dominant resource".
+func (p *Plugin) scoreDominantResourceFairness(podReq PodRequest, nodeStats NodeStats) int {
+	u := computeResourceUsage(podReq, nodeStats)
+	var dominant float64
+	for _, usage := range u {
+		dominant = math.Max(dominant, usage)
+	}
+
+	finalScore := int((1.0 - dominant) * float64(p.maxScore))
+	klog.V(5).Infof("DRF score for node: dominant share=%.2f, Total=%d", dominant, finalScore)
+	return finalScore
+}
+
+// scoreWeightedSum scores a node using the operator-configured per-resource
+// weights in Plugin.resourceWeights instead of the fixed
+// defaultResourceWeights split scoreMultiResource uses. Usage is combined directly (no alpha
+// spreading/packing term), so lower weighted usage always scores higher.
+func (p *Plugin) scoreWeightedSum(podReq PodRequest, nodeStats NodeStats) int {
+	u := computeResourceUsage(podReq, nodeStats)
+	w := p.resourceWeights
+
+	var totalWeight, weightedSum float64
+	for name, usage := range u {
+		weight := w[name]
+		totalWeight += weight
+		weightedSum += usage * weight
+	}
+	if totalWeight <= 0 {
+		return p.scoreMultiResource(podReq, nodeStats, p.alpha)
+	}
+
+	weightedUsage := weightedSum / totalWeight
+
+	finalScore := int((1.0 - weightedUsage) * float64(p.maxScore))
+	klog.V(5).Infof("Weighted-sum score for node: weighted usage=%.2f, Total=%d", weightedUsage, finalScore)
+	return finalScore
+}
+
+// scoreLeastLoaded scores a node by its average usage across every tracked
+// resource, treating every resource equally. Lower average usage scores
+// higher, spreading load evenly regardless of which resource a pod stresses.
+func (p *Plugin) scoreLeastLoaded(podReq PodRequest, nodeStats NodeStats) int {
+	u := computeResourceUsage(podReq, nodeStats)
+	if len(u) == 0 {
+		return p.maxScore
+	}
+	var sum float64
+	for _, usage := range u {
+		sum += usage
+	}
+	avg := sum / float64(len(u))
+
+	finalScore := int((1.0 - avg) * float64(p.maxScore))
+	klog.V(5).Infof("Least-loaded score for node: average usage=%.2f, Total=%d", avg, finalScore)
+	return finalScore
+}
+
+// scoreBestFit scores a node by the remaining headroom in whichever
+// resource would be left most constrained after placing podReq: the
+// minimum post-placement free fraction across every tracked resource.
+// Maximizing that minimum spreads remaining capacity evenly across
+// resources instead of stranding one of them, reducing fragmentation
+// compared to scoreMultiResource's fixed-weight packing.
+func (p *Plugin) scoreBestFit(podReq PodRequest, nodeStats NodeStats) int {
+	u := computeResourceUsage(podReq, nodeStats)
+	if len(u) == 0 {
+		return p.maxScore
+	}
+
+	minRemaining := 1.0
+	for _, usage := range u {
+		if remaining := 1.0 - usage; remaining < minRemaining {
+			minRemaining = remaining
+		}
+	}
+
+	finalScore := int(minRemaining * float64(p.maxScore))
+	klog.V(5).Infof("Best-fit score for node: min remaining=%.2f, Total=%d", minRemaining, finalScore)
+	return finalScore
+}
@@ -0,0 +1,190 @@
+package multiresource
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// statsOf builds a NodeStats with the given cpu/mem availability, leaving
+// every other tracked resource absent so tests only exercise the resources
+// they set up.
+func statsOf(cpuFree, cpuTotal, memFree, memTotal float64) NodeStats {
+	return NodeStats{
+		Resources: map[string]ResourceAvailability{
+			"cpu": {Total: cpuTotal, Free: cpuFree},
+			"mem": {Total: memTotal, Free: memFree},
+		},
+	}
+}
+
+func TestScoreDominantResourceFairness_Ties(t *testing.T) {
+	p := &Plugin{maxScore: 100}
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 1, "mem": 1}}
+	// Both resources have the same post-placement usage fraction, so
+	// neither should be treated as uniquely "dominant".
+	stats := statsOf(2, 4, 2, 4)
+
+	got := p.scoreDominantResourceFairness(podReq, stats)
+	want := 25 // dominant share = (4-2+1)/4 = 0.75 for both resources
+	if got != want {
+		t.Errorf("scoreDominantResourceFairness() with tied shares = %d, want %d", got, want)
+	}
+}
+
+func TestScoreDominantResourceFairness_ZeroRequest(t *testing.T) {
+	p := &Plugin{maxScore: 100}
+	// Requesting nothing should still score the node purely on its
+	// existing usage (podReq contributes 0 to every resource).
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 0, "mem": 0}}
+	stats := statsOf(4, 4, 4, 4)
+
+	got := p.scoreDominantResourceFairness(podReq, stats)
+	if got != p.maxScore {
+		t.Errorf("scoreDominantResourceFairness() with zero-request on an empty node = %d, want %d", got, p.maxScore)
+	}
+}
+
+func TestScoreDominantResourceFairness_SaturatedNode(t *testing.T) {
+	p := &Plugin{maxScore: 100}
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 1, "mem": 0}}
+	// cpu is already fully saturated (Free: 0), so any demand maxes out
+	// the dominant share and the node should score 0.
+	stats := statsOf(0, 4, 4, 4)
+
+	got := p.scoreDominantResourceFairness(podReq, stats)
+	if got != 0 {
+		t.Errorf("scoreDominantResourceFairness() on a saturated node = %d, want 0", got)
+	}
+}
+
+func TestScoreBestFit_Ties(t *testing.T) {
+	p := &Plugin{maxScore: 100}
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 1, "mem": 1}}
+	stats := statsOf(2, 4, 2, 4)
+
+	got := p.scoreBestFit(podReq, stats)
+	want := 25 // remaining fraction = 1 - 0.75 = 0.25 for both resources
+	if got != want {
+		t.Errorf("scoreBestFit() with tied remaining fractions = %d, want %d", got, want)
+	}
+}
+
+func TestScoreBestFit_ZeroRequest(t *testing.T) {
+	p := &Plugin{maxScore: 100}
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 0, "mem": 0}}
+	stats := statsOf(4, 4, 4, 4)
+
+	got := p.scoreBestFit(podReq, stats)
+	if got != p.maxScore {
+		t.Errorf("scoreBestFit() with zero-request on an empty node = %d, want %d", got, p.maxScore)
+	}
+}
+
+func TestScoreBestFit_SaturatedNode(t *testing.T) {
+	p := &Plugin{maxScore: 100}
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 1, "mem": 0}}
+	stats := statsOf(0, 4, 4, 4)
+
+	got := p.scoreBestFit(podReq, stats)
+	if got != 0 {
+		t.Errorf("scoreBestFit() on a saturated node = %d, want 0", got)
+	}
+}
+
+func TestScoreMultiResource_Ties(t *testing.T) {
+	p := &Plugin{maxScore: 100, alpha: 0.5}
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 1, "mem": 1}}
+	stats := statsOf(2, 4, 2, 4)
+
+	a := p.scoreMultiResource(podReq, stats, p.alpha)
+	b := p.scoreMultiResource(podReq, stats, p.alpha)
+	if a != b {
+		t.Errorf("scoreMultiResource() is not deterministic across identical calls: %d vs %d", a, b)
+	}
+}
+
+func TestScoreMultiResource_ZeroRequest(t *testing.T) {
+	p := &Plugin{maxScore: 100, alpha: 0.5}
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 0, "mem": 0}}
+	// At alpha=0.5, resourceToScore reduces to pure spreading (1 - usage),
+	// so a zero-request pod on an empty node must still score in range.
+	stats := statsOf(4, 4, 4, 4)
+
+	got := p.scoreMultiResource(podReq, stats, p.alpha)
+	if got < 0 || got > p.maxScore {
+		t.Errorf("scoreMultiResource() = %d out of bounds [0, %d]", got, p.maxScore)
+	}
+}
+
+func TestScoreMultiResource_SaturatedNode(t *testing.T) {
+	p := &Plugin{maxScore: 100, alpha: 0.0} // pure spreading: higher usage scores lower
+	podReq := PodRequest{Resources: map[string]float64{"cpu": 1, "mem": 0}}
+	stats := statsOf(0, 4, 4, 4)
+
+	unsaturated := statsOf(4, 4, 4, 4)
+	saturatedScore := p.scoreMultiResource(podReq, stats, p.alpha)
+	unsaturatedScore := p.scoreMultiResource(podReq, unsaturated, p.alpha)
+	if saturatedScore >= unsaturatedScore {
+		t.Errorf("scoreMultiResource() on a saturated node = %d, want less than unsaturated score %d", saturatedScore, unsaturatedScore)
+	}
+}
+
+func TestPodAlpha_AnnotationOverride(t *testing.T) {
+	p := &Plugin{alpha: 0.5}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{alphaAnnotation: "0.1"},
+	}}
+
+	if got := p.podAlpha(pod); got != 0.1 {
+		t.Errorf("podAlpha() with annotation = %v, want 0.1", got)
+	}
+}
+
+func TestPodAlpha_ClampedToUnitRange(t *testing.T) {
+	p := &Plugin{alpha: 0.5}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{alphaAnnotation: "5"},
+	}}
+
+	if got := p.podAlpha(pod); got != 1.0 {
+		t.Errorf("podAlpha() with out-of-range annotation = %v, want 1.0", got)
+	}
+}
+
+func TestPodAlpha_FallsBackToPluginDefault(t *testing.T) {
+	p := &Plugin{alpha: 0.7}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{alphaAnnotation: "not-a-number"},
+	}}
+
+	if got := p.podAlpha(pod); got != 0.7 {
+		t.Errorf("podAlpha() with unparseable annotation = %v, want plugin default 0.7", got)
+	}
+}
+
+func TestTopologyConcentration_NoHandleIsZero(t *testing.T) {
+	p := &Plugin{}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: "web"},
+			},
+		},
+	}
+
+	if got := p.topologyConcentration(pod, "node-1", "topology.kubernetes.io/zone"); got != 0 {
+		t.Errorf("topologyConcentration() with no scheduler handle = %v, want 0", got)
+	}
+}
+
+func TestTopologyConcentration_NoOwnerIsZero(t *testing.T) {
+	p := &Plugin{}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}}
+
+	if got := p.topologyConcentration(pod, "node-1", "topology.kubernetes.io/zone"); got != 0 {
+		t.Errorf("topologyConcentration() for an unowned pod = %v, want 0", got)
+	}
+}
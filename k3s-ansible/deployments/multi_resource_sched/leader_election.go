@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// This file lets multiple replicas of the scheduler run for HA, with only
+// the elected leader running the pod controller, via the same Lease-based
+// leaderelection package kube-scheduler itself uses.
+
+// runWithLeaderElection blocks running a leader election loop against a
+// Lease named lockName in namespace; onStartedLeading is called (in its own
+// goroutine, per LeaderElectionConfig's contract) when this process becomes
+// leader, and is expected to run until ctx is cancelled. If this process
+// loses leadership, onStoppedLeading fires and the process exits via
+// log.Fatalf, so a replica that's no longer sure it's the sole scheduler
+// never keeps binding pods.
+func runWithLeaderElection(client kubernetes.Interface, namespace, lockName string, leaseDuration, renewDeadline, retryPeriod time.Duration, onStartedLeading func(ctx context.Context)) {
+	identity, err := os.Hostname()
+	if err != nil {
+		identity = "unknown"
+	}
+	identity = identity + "_" + string(uuid.NewUUID())
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      lockName,
+			Namespace: namespace,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	leaderelection.RunOrDie(context.Background(), leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: renewDeadline,
+		RetryPeriod:   retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Printf("%s: became leader, starting pod controller", identity)
+				onStartedLeading(ctx)
+			},
+			OnStoppedLeading: func() {
+				log.Fatalf("%s: lost leadership, exiting", identity)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					log.Printf("Observed new leader: %s", leader)
+				}
+			},
+		},
+	})
+}
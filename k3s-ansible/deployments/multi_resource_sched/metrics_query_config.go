@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// This file lets operators override the PromQL queries and capacity
+// defaults getNodeStats uses for CPU capacity, disk, and network. node_exporter
+// has no "max throughput" metric for disk, so unlike CPU/memory (sourced
+// from machine_cpu_cores / the node's own Status.Capacity) disk capacity
+// always comes from here, never from Prometheus itself. Network capacity
+// comes from NetSpeedQuery when Prometheus returns a sample for the node,
+// and from here otherwise.
+
+// MetricsQueryConfig holds the PromQL queries and capacity defaults
+// getNodeStats uses, loaded from the JSON file named by
+// --metrics-query-config.
+type MetricsQueryConfig struct {
+	// CPUCoresQuery is tried first for a node's CPU capacity; if it returns
+	// no sample, getNodeStats falls back to the node's own
+	// Status.Capacity[cpu] via the Kubernetes API.
+	CPUCoresQuery string `json:"cpuCoresQuery"`
+
+	DiskReadUsageQuery  string `json:"diskReadUsageQuery"`
+	DiskWriteUsageQuery string `json:"diskWriteUsageQuery"`
+	// DiskIOTimeQuery, when it returns a sample, scales capacity down by
+	// observed I/O saturation (node_disk_io_time_seconds_total's rate is
+	// the fraction of a second the device was busy) instead of relying
+	// purely on a bytes-used-vs-capacity subtraction.
+	DiskIOTimeQuery   string `json:"diskIOTimeQuery"`
+	NetUpUsageQuery   string `json:"netUpUsageQuery"`
+	NetDownUsageQuery string `json:"netDownUsageQuery"`
+	// NetSpeedQuery is tried first for network capacity; many node_exporter
+	// deployments don't expose it (virtual NICs in particular), so it falls
+	// back to DefaultNetBytesPerSec/NodeNetBytesPerSecOverride below.
+	NetSpeedQuery string `json:"netSpeedQuery"`
+
+	DefaultDiskReadBytesPerSec  float64 `json:"defaultDiskReadBytesPerSec"`
+	DefaultDiskWriteBytesPerSec float64 `json:"defaultDiskWriteBytesPerSec"`
+	DefaultNetBytesPerSec       float64 `json:"defaultNetBytesPerSec"`
+
+	// NodeDiskReadBytesPerSecOverride/NodeDiskWriteBytesPerSecOverride/
+	// NodeNetBytesPerSecOverride key by node name, for fleets with
+	// heterogeneous disks or NICs where a single default isn't accurate.
+	NodeDiskReadBytesPerSecOverride  map[string]float64 `json:"nodeDiskReadBytesPerSecOverride"`
+	NodeDiskWriteBytesPerSecOverride map[string]float64 `json:"nodeDiskWriteBytesPerSecOverride"`
+	NodeNetBytesPerSecOverride       map[string]float64 `json:"nodeNetBytesPerSecOverride"`
+}
+
+// defaultMetricsQueryConfig mirrors the queries/constants getNodeStats used
+// before this config existed, so an empty --metrics-query-config changes
+// nothing.
+func defaultMetricsQueryConfig() *MetricsQueryConfig {
+	return &MetricsQueryConfig{
+		CPUCoresQuery:               "machine_cpu_cores",
+		DiskReadUsageQuery:          "rate(node_disk_read_bytes_total[5m])",
+		DiskWriteUsageQuery:         "rate(node_disk_written_bytes_total[5m])",
+		DiskIOTimeQuery:             "rate(node_disk_io_time_seconds_total[5m])",
+		NetUpUsageQuery:             `rate(node_network_transmit_bytes_total{device!~"lo|veth.*"}[5m])`,
+		NetDownUsageQuery:           `rate(node_network_receive_bytes_total{device!~"lo|veth.*"}[5m])`,
+		NetSpeedQuery:               "node_network_speed_bytes",
+		DefaultDiskReadBytesPerSec:  100 * 1024 * 1024,
+		DefaultDiskWriteBytesPerSec: 50 * 1024 * 1024,
+		DefaultNetBytesPerSec:       1000 * 1024 * 1024,
+	}
+}
+
+// LoadMetricsQueryConfig reads a MetricsQueryConfig from a JSON file. An
+// empty path returns defaultMetricsQueryConfig() so --metrics-query-config
+// is optional; fields the file omits keep their default value.
+func LoadMetricsQueryConfig(path string) (*MetricsQueryConfig, error) {
+	cfg := defaultMetricsQueryConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading metrics query config %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing metrics query config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// metricsQueryConfig is populated from --metrics-query-config during flag
+// parsing in main. getNodeStats falls back to defaultMetricsQueryConfig()
+// when it's nil, so callers (including existing tests) that never set it
+// see the original queries and constants.
+var metricsQueryConfig *MetricsQueryConfig
+
+func effectiveMetricsQueryConfig() *MetricsQueryConfig {
+	if metricsQueryConfig != nil {
+		return metricsQueryConfig
+	}
+	return defaultMetricsQueryConfig()
+}
+
+// queryPrometheusForNode runs query and picks the sample for nodeName out of
+// the result, using the same fuzzy instance-label matching (exact
+// "<node>:9100", then substring, then "any single series") queryNodeLabel's
+// callers already rely on, since node_exporter's instance label isn't
+// guaranteed to equal the Kubernetes node name.
+func queryPrometheusForNode(query, nodeName string) (float64, bool) {
+	metrics, err := queryPrometheus(query)
+	if err != nil {
+		log.Printf("Warning: Prometheus query %q failed: %v", query, err)
+		return 0, false
+	}
+	if value, exists := metrics[nodeName+":9100"]; exists {
+		return value, true
+	}
+	for instance, value := range metrics {
+		if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
+			return value, true
+		}
+	}
+	for _, value := range metrics {
+		return value, true
+	}
+	return 0, false
+}
+
+// cpuCoresFor returns nodeName's CPU capacity: cfg.CPUCoresQuery if
+// Prometheus exposes it (machine_cpu_cores, as cAdvisor reports), otherwise
+// the node's own Status.Capacity[cpu] via client, otherwise 6.0 as a last
+// resort when neither signal is available.
+func cpuCoresFor(client kubernetes.Interface, nodeName string, cfg *MetricsQueryConfig) float64 {
+	if cores, ok := queryPrometheusForNode(cfg.CPUCoresQuery, nodeName); ok && cores > 0 {
+		return cores
+	}
+	if client != nil {
+		if node, err := client.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{}); err == nil {
+			if cpuQty, ok := node.Status.Capacity[v1.ResourceCPU]; ok {
+				return float64(cpuQty.MilliValue()) / 1000.0
+			}
+		} else {
+			log.Printf("Warning: Failed to fetch node %s for CPU capacity: %v", nodeName, err)
+		}
+	}
+	return 6.0
+}
+
+// diskCapacityFor returns the configured per-node override for nodeName if
+// one exists, otherwise the fallback default.
+func diskCapacityFor(nodeName string, override map[string]float64, fallback float64) float64 {
+	if v, ok := override[nodeName]; ok {
+		return v
+	}
+	return fallback
+}
+
+// diskThroughputStats derives (total, free) disk throughput for nodeName.
+// capacity always comes from cfg (node_exporter exposes no max-throughput
+// metric), but free is scaled down by observed I/O saturation when
+// ioTimeQuery returns a sample, not just a plain capacity-minus-usage
+// subtraction.
+func diskThroughputStats(nodeName, usageQuery, ioTimeQuery string, capacity float64) (total, free float64) {
+	usage, ok := queryPrometheusForNode(usageQuery, nodeName)
+	if !ok {
+		usage = 0
+	}
+	free = capacity - usage
+	if saturation, ok := queryPrometheusForNode(ioTimeQuery, nodeName); ok {
+		if saturation < 0 {
+			saturation = 0
+		} else if saturation > 1 {
+			saturation = 1
+		}
+		if scaled := capacity * (1 - saturation); scaled < free {
+			free = scaled
+		}
+	}
+	if free < 0 {
+		free = 0
+	}
+	return capacity, free
+}
+
+// networkCapacityFor returns the network throughput capacity to use for
+// nodeName: a per-node override if configured, else node_network_speed_bytes
+// if Prometheus actually exposes it, else the configured default.
+func networkCapacityFor(nodeName string, cfg *MetricsQueryConfig) float64 {
+	if override, ok := cfg.NodeNetBytesPerSecOverride[nodeName]; ok {
+		return override
+	}
+	if speed, ok := queryPrometheusForNode(cfg.NetSpeedQuery, nodeName); ok && speed > 0 {
+		return speed
+	}
+	return cfg.DefaultNetBytesPerSec
+}
+
+// networkThroughputStats derives (total, free) network throughput for
+// nodeName against capacity.
+func networkThroughputStats(nodeName, usageQuery string, capacity float64) (total, free float64) {
+	usage, ok := queryPrometheusForNode(usageQuery, nodeName)
+	if !ok {
+		usage = 0
+	}
+	free = capacity - usage
+	if free < 0 {
+		free = 0
+	}
+	return capacity, free
+}
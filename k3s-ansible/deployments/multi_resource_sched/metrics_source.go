@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MetricsSource gathers NodeStats for a node from some backing metrics
+// system (Prometheus or the kubelet Summary API). buildMetricsSource chains
+// the primary source with the others as fallbacks, so Prometheus being
+// unreachable or returning nothing for a node doesn't leave the scheduler
+// with no signal to score it on.
+type MetricsSource interface {
+	// Name identifies the source in logs.
+	Name() string
+	NodeStats(client kubernetes.Interface, nodeName string) (NodeStats, error)
+}
+
+// buildMetricsSource selects a MetricsSource chain based on --metrics-source.
+// The named source is tried first; the remaining built-in sources are
+// appended as fallbacks.
+func buildMetricsSource(primary string) MetricsSource {
+	all := map[string]MetricsSource{
+		"prometheus": prometheusMetricsSource{},
+		"kubelet":    newKubeletSummaryMetricsSource(),
+	}
+
+	p, ok := all[primary]
+	if !ok {
+		log.Printf("Warning: unknown metrics source %q, defaulting to prometheus", primary)
+		primary = "prometheus"
+		p = all["prometheus"]
+	}
+
+	chain := []MetricsSource{p}
+	for _, name := range []string{"prometheus", "kubelet"} {
+		if name != primary {
+			chain = append(chain, all[name])
+		}
+	}
+	return metricsSourceChain{sources: chain}
+}
+
+// metricsSourceChain tries each source in order, falling back to the next
+// one on error instead of propagating the failure.
+type metricsSourceChain struct {
+	sources []MetricsSource
+}
+
+func (c metricsSourceChain) Name() string {
+	names := make([]string, len(c.sources))
+	for i, s := range c.sources {
+		names[i] = s.Name()
+	}
+	return strings.Join(names, "->")
+}
+
+func (c metricsSourceChain) NodeStats(client kubernetes.Interface, nodeName string) (NodeStats, error) {
+	var lastErr error
+	for _, s := range c.sources {
+		stats, err := s.NodeStats(client, nodeName)
+		if err == nil {
+			return stats, nil
+		}
+		log.Printf("Warning: metrics source %s failed for node %s: %v", s.Name(), nodeName, err)
+		lastErr = err
+	}
+	return NodeStats{}, fmt.Errorf("all metrics sources failed, last error: %v", lastErr)
+}
+
+// prometheusMetricsSource is the original behavior: stats come from
+// getNodeStats's Prometheus queries.
+type prometheusMetricsSource struct{}
+
+func (prometheusMetricsSource) Name() string { return "prometheus" }
+
+func (prometheusMetricsSource) NodeStats(client kubernetes.Interface, nodeName string) (NodeStats, error) {
+	return getNodeStats(client, nodeName)
+}
+
+// summaryResponse mirrors the subset of stats/v1alpha1.Summary this source
+// needs from the kubelet Summary API.
+type summaryResponse struct {
+	Node struct {
+		CPU struct {
+			UsageNanoCores *uint64 `json:"usageNanoCores"`
+		} `json:"cpu"`
+		Memory struct {
+			AvailableBytes *uint64 `json:"availableBytes"`
+		} `json:"memory"`
+		Fs struct {
+			CapacityBytes *uint64 `json:"capacityBytes"`
+			UsedBytes     *uint64 `json:"usedBytes"`
+		} `json:"fs"`
+		Network struct {
+			Interfaces []struct {
+				RxBytes *uint64 `json:"rxBytes"`
+				TxBytes *uint64 `json:"txBytes"`
+			} `json:"interfaces"`
+		} `json:"network"`
+	} `json:"node"`
+}
+
+// netCounterSample records the cumulative rx/tx byte counters read from a
+// node's kubelet summary at a point in time, so the next read can derive a
+// bytes/sec rate from the delta instead of treating the raw counter as if it
+// were already a rate.
+type netCounterSample struct {
+	rxBytes uint64
+	txBytes uint64
+	at      time.Time
+}
+
+// kubeletSummaryMetricsSource reads nodeName's authoritative /stats/summary
+// directly from its kubelet (proxied through the apiserver), so this
+// scheduler can still derive CPU/memory/disk/network for clusters that
+// don't run Prometheus/node_exporter. RxBytes/TxBytes in the Summary API are
+// cumulative counters, not a rate, so netSamples tracks the previous sample
+// per node to derive one.
+type kubeletSummaryMetricsSource struct {
+	mu         sync.Mutex
+	netSamples map[string]netCounterSample
+}
+
+func newKubeletSummaryMetricsSource() *kubeletSummaryMetricsSource {
+	return &kubeletSummaryMetricsSource{netSamples: make(map[string]netCounterSample)}
+}
+
+func (*kubeletSummaryMetricsSource) Name() string { return "kubelet" }
+
+func (s *kubeletSummaryMetricsSource) NodeStats(client kubernetes.Interface, nodeName string) (NodeStats, error) {
+	if client == nil {
+		return NodeStats{}, fmt.Errorf("kubelet summary source requires a Kubernetes client")
+	}
+
+	node, err := client.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return NodeStats{}, fmt.Errorf("error fetching node %s: %w", nodeName, err)
+	}
+	cpuTotal, memTotal := allocatableStats(node)
+
+	raw, err := client.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", nodeName, "proxy/stats/summary").
+		DoRaw(context.Background())
+	if err != nil {
+		return NodeStats{}, fmt.Errorf("error fetching kubelet summary for %s: %w", nodeName, err)
+	}
+
+	var summary summaryResponse
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return NodeStats{}, fmt.Errorf("error parsing kubelet summary for %s: %w", nodeName, err)
+	}
+
+	var stats NodeStats
+	stats.CPUTotal = cpuTotal
+	stats.MemTotal = memTotal
+
+	if summary.Node.CPU.UsageNanoCores != nil {
+		stats.CPUFree = cpuTotal - float64(*summary.Node.CPU.UsageNanoCores)/1e9
+	} else {
+		stats.CPUFree = cpuTotal
+	}
+	if stats.CPUFree < 0 {
+		stats.CPUFree = 0
+	}
+
+	if summary.Node.Memory.AvailableBytes != nil {
+		stats.MemFree = float64(*summary.Node.Memory.AvailableBytes)
+	} else {
+		stats.MemFree = memTotal
+	}
+
+	if summary.Node.Fs.CapacityBytes != nil && summary.Node.Fs.UsedBytes != nil {
+		stats.DiskReadTotal = float64(*summary.Node.Fs.CapacityBytes)
+		stats.DiskReadFree = stats.DiskReadTotal - float64(*summary.Node.Fs.UsedBytes)
+		stats.DiskWriteTotal = stats.DiskReadTotal
+		stats.DiskWriteFree = stats.DiskReadFree
+	}
+
+	var rxTotal, txTotal uint64
+	for _, iface := range summary.Node.Network.Interfaces {
+		if iface.RxBytes != nil {
+			rxTotal += *iface.RxBytes
+		}
+		if iface.TxBytes != nil {
+			txTotal += *iface.TxBytes
+		}
+	}
+
+	// The Summary API reports cumulative bytes, not a capacity or a rate;
+	// without a capacity signal we report demand against an assumed 1Gbps
+	// link (125 MiB/s). This is separate from the Prometheus path's
+	// MetricsQueryConfig.DefaultNetBytesPerSec.
+	// txTotal/rxTotal only become a rate by diffing against the previous
+	// sample for this node, so until a previous sample exists (the first
+	// call for a node) assume no usage rather than subtracting the raw
+	// counter from the capacity.
+	const assumedNetCapacity = 125 * 1024 * 1024
+	stats.NetUpTotal = assumedNetCapacity
+	stats.NetDownTotal = assumedNetCapacity
+	stats.NetUpFree = assumedNetCapacity
+	stats.NetDownFree = assumedNetCapacity
+
+	now := time.Now()
+	s.mu.Lock()
+	prev, ok := s.netSamples[nodeName]
+	s.netSamples[nodeName] = netCounterSample{rxBytes: rxTotal, txBytes: txTotal, at: now}
+	s.mu.Unlock()
+
+	if ok {
+		if elapsed := now.Sub(prev.at).Seconds(); elapsed > 0 {
+			// A counter can go backwards across a kubelet/node restart; treat
+			// that as zero usage for this sample rather than underflowing the
+			// unsigned subtraction into a huge rate.
+			var txRate, rxRate float64
+			if txTotal >= prev.txBytes {
+				txRate = float64(txTotal-prev.txBytes) / elapsed
+			}
+			if rxTotal >= prev.rxBytes {
+				rxRate = float64(rxTotal-prev.rxBytes) / elapsed
+			}
+			stats.NetUpFree = assumedNetCapacity - txRate
+			stats.NetDownFree = assumedNetCapacity - rxRate
+		}
+	}
+
+	return stats, nil
+}
+
+// allocatableStats derives CPU/memory totals from node's own
+// Status.Allocatable instead of the hardcoded 6.0-core/32GB defaults
+// getNodeStats falls back to when Prometheus has no data for it.
+func allocatableStats(node *v1.Node) (cpuTotal, memTotal float64) {
+	cpuQty, ok := node.Status.Allocatable[v1.ResourceCPU]
+	if !ok {
+		cpuQty = resource.MustParse("1")
+	}
+	memQty, ok := node.Status.Allocatable[v1.ResourceMemory]
+	if !ok {
+		memQty = resource.MustParse("1Gi")
+	}
+	return float64(cpuQty.MilliValue()) / 1000.0, float64(memQty.Value())
+}
@@ -12,10 +12,14 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -46,69 +50,107 @@ type PodRequest struct {
 	NetUp     float64 // Network upload demand (bytes/sec).
 	NetDown   float64 // Network download demand (bytes/sec).
 	Priority  int     // Higher value means higher priority.
+	// User identifies the pod's owner for dominantResourceFairnessScorer's
+	// userShare tracking; extractPodRequirements sets it to the pod's
+	// namespace.
+	User string
 }
 
 // RunningPod represents a running Pod's resource usage and priority.
 type RunningPod struct {
-	Name       string
-	Namespace  string
-	CPURequest float64
-	MemRequest float64
-	// (Additional resource usage fields could be added here.)
-	Priority int
+	Name             string
+	Namespace        string
+	CPURequest       float64
+	MemRequest       float64
+	DiskReadRequest  float64
+	DiskWriteRequest float64
+	NetUpRequest     float64
+	NetDownRequest   float64
+	Priority         int
+	// Labels is carried along so attemptPreemption can match the candidate
+	// against any PodDisruptionBudget covering it.
+	Labels map[string]string
+}
+
+// cpuAverageWindow/cpuAverageStep control the range query getNodeStats uses
+// to average CPU usage over a window instead of trusting the single
+// instantaneous rate() sample the old query returned.
+const (
+	cpuAverageWindow = 5 * time.Minute
+	cpuAverageStep   = 30 * time.Second
+)
+
+// lookupNodeValue returns nodeName's entry from a map keyed by node name
+// (see queryNodeLabel), falling back to an arbitrary entry if nodeName isn't
+// present so a single-node test cluster with a differently-named instance
+// still gets usable defaults.
+func lookupNodeValue(byNode map[string]float64, nodeName string) (float64, bool) {
+	if v, ok := byNode[nodeName]; ok {
+		return v, true
+	}
+	for _, v := range byNode {
+		return v, true
+	}
+	return 0, false
+}
+
+// lookupNodeSamples is lookupNodeValue's range-query counterpart, returning
+// nodeName's sample series instead of a single value.
+func lookupNodeSamples(byNode map[string][]float64, nodeName string) ([]float64, bool) {
+	if v, ok := byNode[nodeName]; ok {
+		return v, true
+	}
+	for _, v := range byNode {
+		return v, true
+	}
+	return nil, false
 }
 
-// getNodeStats gathers metrics from Prometheus for a given node.
+// mean returns the arithmetic mean of samples, or 0 for an empty slice.
+func mean(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	return sum / float64(len(samples))
+}
+
+// getNodeStats gathers metrics from Prometheus for a given node; it backs
+// prometheusMetricsSource (see metrics_source.go), which chains it with
+// kubeletSummaryMetricsSource as a fallback when Prometheus is unreachable.
 // It queries for CPU and memory usage as before, and now also for disk and network.
 // For disk and network, it uses two queries each: one for current usage (via rate())
-// and one for the hardware capacity (assumed exposed by metrics).
-func getNodeStats(nodeName string) (NodeStats, error) {
+// and one more: disk I/O time, whose saturation scales free disk capacity down,
+// and link speed, which sets network capacity. Disk capacity, and network
+// capacity when link speed has no sample, come from MetricsQueryConfig. client is used to
+// read a node's own Status.Capacity when machine_cpu_cores isn't exposed; it may be
+// nil, in which case that fallback is skipped.
+func getNodeStats(client kubernetes.Interface, nodeName string) (NodeStats, error) {
 	var stats NodeStats
-	var err error
+	cfg := effectiveMetricsQueryConfig()
 
 	log.Printf("Getting stats for node: %s", nodeName)
 
 	// ---------- CPU Metrics ----------
 	cpuQuery := "sum(rate(node_cpu_seconds_total{mode!=\"idle\"}[5m])) by (instance)"
-	cpuMetrics, err := queryPrometheus(cpuQuery)
+	end := time.Now()
+	start := end.Add(-cpuAverageWindow)
+	stats.CPUTotal = cpuCoresFor(client, nodeName, cfg)
+	cpuSeries, err := queryPrometheusRange(cpuQuery, start, end, cpuAverageStep)
 	if err != nil {
 		log.Printf("Warning: Failed to fetch CPU metrics: %v", err)
-		stats.CPUTotal = 6.0
-		stats.CPUFree = 2.0
-	} else {
-		var cpuUsage float64
-		var nodeFound bool
-		if usage, exists := cpuMetrics[nodeName+":9100"]; exists {
-			cpuUsage = usage
-			nodeFound = true
-		} else {
-			for instance, usage := range cpuMetrics {
-				if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-					cpuUsage = usage
-					nodeFound = true
-					break
-				}
-			}
-			if !nodeFound && len(cpuMetrics) > 0 {
-				for _, usage := range cpuMetrics {
-					cpuUsage = usage
-					nodeFound = true
-					break
-				}
-			}
-		}
-		if !nodeFound {
-			log.Printf("Warning: Node %s not found in CPU metrics, using default values", nodeName)
-			stats.CPUTotal = 6.0
-			stats.CPUFree = 2.0
-		} else {
-			// Assuming 8 cores total for the node (adjust as needed).
-			stats.CPUTotal = 6.0
-			stats.CPUFree = stats.CPUTotal - cpuUsage
-			if stats.CPUFree < 0 {
-				stats.CPUFree = 0
-			}
+		stats.CPUFree = stats.CPUTotal / 3
+	} else if samples, ok := lookupNodeSamples(cpuSeries, nodeName); ok {
+		stats.CPUFree = stats.CPUTotal - mean(samples)
+		if stats.CPUFree < 0 {
+			stats.CPUFree = 0
 		}
+	} else {
+		log.Printf("Warning: Node %s not found in CPU metrics, using default values", nodeName)
+		stats.CPUFree = stats.CPUTotal / 3
 	}
 
 	// ---------- Memory Metrics ----------
@@ -118,81 +160,34 @@ func getNodeStats(nodeName string) (NodeStats, error) {
 	if err != nil {
 		log.Printf("Warning: Failed to fetch memory total metrics: %v", err)
 		stats.MemTotal = 32 * 1024 * 1024 * 1024 // 32 GB default.
+	} else if memTotal, ok := lookupNodeValue(memTotalMetrics, nodeName); ok {
+		stats.MemTotal = memTotal
 	} else {
-		var memTotal float64
-		var nodeFound bool
-		if total, exists := memTotalMetrics[nodeName+":9100"]; exists {
-			memTotal = total
-			nodeFound = true
-		} else {
-			for instance, total := range memTotalMetrics {
-				if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-					memTotal = total
-					nodeFound = true
-					break
-				}
-			}
-			if !nodeFound && len(memTotalMetrics) > 0 {
-				for _, total := range memTotalMetrics {
-					memTotal = total
-					nodeFound = true
-					break
-				}
-			}
-		}
-		if !nodeFound {
-			log.Printf("Warning: Node %s not found in memory total metrics, using default values", nodeName)
-			stats.MemTotal = 32 * 1024 * 1024 * 1024 // 32 GB default.
-		} else {
-			stats.MemTotal = memTotal
-		}
+		log.Printf("Warning: Node %s not found in memory total metrics, using default values", nodeName)
+		stats.MemTotal = 32 * 1024 * 1024 * 1024 // 32 GB default.
 	}
 
 	memFreeMetrics, err := queryPrometheus(memFreeQuery)
 	if err != nil {
 		log.Printf("Warning: Failed to fetch memory free metrics: %v", err)
 		stats.MemFree = 16 * 1024 * 1024 * 1024 // 16 GB default.
+	} else if memFree, ok := lookupNodeValue(memFreeMetrics, nodeName); ok {
+		stats.MemFree = memFree
 	} else {
-		var memFree float64
-		var nodeFound bool
-		if free, exists := memFreeMetrics[nodeName+":9100"]; exists {
-			memFree = free
-			nodeFound = true
-		} else {
-			for instance, free := range memFreeMetrics {
-				if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-					memFree = free
-					nodeFound = true
-					break
-				}
-			}
-			if !nodeFound && len(memFreeMetrics) > 0 {
-				for _, free := range memFreeMetrics {
-					memFree = free
-					nodeFound = true
-					break
-				}
-			}
-		}
-		if !nodeFound {
-			log.Printf("Warning: Node %s not found in memory free metrics, using default values", nodeName)
-			stats.MemFree = 16 * 1024 * 1024 * 1024 // 16 GB default.
-		} else {
-			stats.MemFree = memFree
-		}
+		log.Printf("Warning: Node %s not found in memory free metrics, using default values", nodeName)
+		stats.MemFree = 16 * 1024 * 1024 * 1024 // 16 GB default.
 	}
 
 	// ---------- Disk Metrics ----------
-	// For simplicity, we'll use default values for disk and network.
-	// In a real implementation, you'd query Prometheus for these metrics.
-	stats.DiskReadTotal = 100 * 1024 * 1024 // 100 MB/s read capacity.
-	stats.DiskReadFree = 80 * 1024 * 1024   // 80 MB/s available read.
-	stats.DiskWriteTotal = 50 * 1024 * 1024 // 50 MB/s write capacity.
-	stats.DiskWriteFree = 40 * 1024 * 1024  // 40 MB/s available write.
-	stats.NetUpTotal = 1000 * 1024 * 1024   // 1000 MB/s upload capacity.
-	stats.NetUpFree = 800 * 1024 * 1024     // 800 MB/s available upload.
-	stats.NetDownTotal = 1000 * 1024 * 1024 // 1000 MB/s download capacity.
-	stats.NetDownFree = 800 * 1024 * 1024   // 800 MB/s available download.
+	diskReadCap := diskCapacityFor(nodeName, cfg.NodeDiskReadBytesPerSecOverride, cfg.DefaultDiskReadBytesPerSec)
+	stats.DiskReadTotal, stats.DiskReadFree = diskThroughputStats(nodeName, cfg.DiskReadUsageQuery, cfg.DiskIOTimeQuery, diskReadCap)
+	diskWriteCap := diskCapacityFor(nodeName, cfg.NodeDiskWriteBytesPerSecOverride, cfg.DefaultDiskWriteBytesPerSec)
+	stats.DiskWriteTotal, stats.DiskWriteFree = diskThroughputStats(nodeName, cfg.DiskWriteUsageQuery, cfg.DiskIOTimeQuery, diskWriteCap)
+
+	// ---------- Network Metrics ----------
+	netCap := networkCapacityFor(nodeName, cfg)
+	stats.NetUpTotal, stats.NetUpFree = networkThroughputStats(nodeName, cfg.NetUpUsageQuery, netCap)
+	stats.NetDownTotal, stats.NetDownFree = networkThroughputStats(nodeName, cfg.NetDownUsageQuery, netCap)
 
 	// Log the stats for debugging.
 	log.Printf("Node stats for %s: CPU Total: %.2f, CPU Free: %.2f, Mem Total: %.2f GB, Mem Free: %.2f GB",
@@ -201,26 +196,173 @@ func getNodeStats(nodeName string) (NodeStats, error) {
 	return stats, nil
 }
 
-// queryPrometheus sends a query to Prometheus and returns the results.
-func queryPrometheus(query string) (map[string]float64, error) {
-	baseUrl := getPrometheusURL()
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", baseUrl, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+// PrometheusConfig holds the connection and federation settings
+// queryPrometheus/queryPrometheusRange use, configurable via PROMETHEUS_*
+// environment variables so this binary can point at a federated/Thanos
+// endpoint that aggregates several clusters without a code change.
+type PrometheusConfig struct {
+	// BaseURL has no /api/v1/... suffix; doPrometheusRequest appends the
+	// path for whichever endpoint it's calling.
+	BaseURL       string
+	BearerToken   string
+	BasicAuthUser string
+	BasicAuthPass string
+	// ClusterLabelName/ClusterLabelValue scope every query to one cluster
+	// (e.g. `cluster="prod"`) when BaseURL points at a federated endpoint
+	// that aggregates samples from several.
+	ClusterLabelName  string
+	ClusterLabelValue string
+	// ThanosPartialResponse/ThanosDedup are appended as query parameters
+	// Thanos query-frontends understand; a plain Prometheus server ignores
+	// them.
+	ThanosPartialResponse bool
+	ThanosDedup           bool
+}
+
+// prometheusConfigFromEnv builds a PrometheusConfig from PROMETHEUS_* env
+// vars, read once at startup.
+func prometheusConfigFromEnv() PrometheusConfig {
+	cfg := PrometheusConfig{
+		BaseURL:           strings.TrimSuffix(getPrometheusURL(), "/api/v1/query"),
+		BearerToken:       os.Getenv("PROMETHEUS_BEARER_TOKEN"),
+		BasicAuthUser:     os.Getenv("PROMETHEUS_BASIC_AUTH_USER"),
+		BasicAuthPass:     os.Getenv("PROMETHEUS_BASIC_AUTH_PASS"),
+		ClusterLabelName:  os.Getenv("PROMETHEUS_CLUSTER_LABEL_NAME"),
+		ClusterLabelValue: os.Getenv("PROMETHEUS_CLUSTER_LABEL_VALUE"),
 	}
-	q := url.Values{}
-	q.Add("query", query)
-	req.URL.RawQuery = q.Encode()
-	req.Header.Set("Accept", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making GET request: %v", err)
+	if cfg.ClusterLabelValue != "" && cfg.ClusterLabelName == "" {
+		cfg.ClusterLabelName = "cluster"
+	}
+	cfg.ThanosPartialResponse, _ = strconv.ParseBool(os.Getenv("PROMETHEUS_THANOS_PARTIAL"))
+	cfg.ThanosDedup, _ = strconv.ParseBool(os.Getenv("PROMETHEUS_THANOS_DEDUP"))
+	return cfg
+}
+
+// promConfig is read once at process startup; queryPrometheus/
+// queryPrometheusRange share it so every call in this binary talks to the
+// same endpoint.
+var promConfig = prometheusConfigFromEnv()
+
+// clusterSelector returns promConfig's cluster label matcher (e.g.
+// `cluster="prod"`), or "" if unset.
+func clusterSelector() string {
+	if promConfig.ClusterLabelName == "" || promConfig.ClusterLabelValue == "" {
+		return ""
+	}
+	return fmt.Sprintf(`%s="%s"`, promConfig.ClusterLabelName, promConfig.ClusterLabelValue)
+}
+
+// injectClusterSelector merges clusterSelector() into query's first `{...}`
+// selector block, so a federated/Thanos endpoint aggregating several
+// clusters only matches the one promConfig names.
+func injectClusterSelector(query string) string {
+	sel := clusterSelector()
+	if sel == "" {
+		return query
+	}
+	idx := strings.Index(query, "{")
+	if idx == -1 {
+		return query
+	}
+	return query[:idx+1] + sel + "," + query[idx+1:]
+}
+
+// queryNodeLabel returns the node name a Prometheus sample's metric labels
+// identify, preferring the "node"/"kubernetes_node" labels a federated
+// setup typically attaches over node_exporter's raw "instance" (host:port),
+// which is ambiguous once several clusters are aggregated behind one query
+// endpoint.
+func queryNodeLabel(metric map[string]string) string {
+	if node := metric["node"]; node != "" {
+		return node
+	}
+	if node := metric["kubernetes_node"]; node != "" {
+		return node
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	return strings.Split(metric["instance"], ":")[0]
+}
+
+// prometheusQueryRetries/prometheusRetryBackoff control queryPrometheus's
+// retry-with-backoff, configurable via PROMETHEUS_QUERY_RETRIES/
+// PROMETHEUS_RETRY_BACKOFF (defaults: 3 retries, 500ms doubling backoff).
+func prometheusQueryRetries() int {
+	if val := os.Getenv("PROMETHEUS_QUERY_RETRIES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return 3
+}
+
+func prometheusRetryBackoff() time.Duration {
+	if val := os.Getenv("PROMETHEUS_RETRY_BACKOFF"); val != "" {
+		if ms, err := strconv.Atoi(val); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return 500 * time.Millisecond
+}
+
+// doPrometheusRequest issues a GET against path (e.g. "/api/v1/query") with
+// the given query parameters, attaching auth headers and Thanos params from
+// promConfig, and retrying with exponential backoff on transport errors or
+// a 5xx response.
+func doPrometheusRequest(path string, query url.Values) ([]byte, error) {
+	if promConfig.ThanosPartialResponse {
+		query.Set("partial_response", "true")
+	}
+	if promConfig.ThanosDedup {
+		query.Set("dedup", "true")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	backoff := prometheusRetryBackoff()
+	var lastErr error
+	for attempt := 0; attempt <= prometheusQueryRetries(); attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		req, err := http.NewRequest("GET", promConfig.BaseURL+path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %v", err)
+		}
+		req.URL.RawQuery = query.Encode()
+		req.Header.Set("Accept", "application/json")
+		if promConfig.BearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+promConfig.BearerToken)
+		} else if promConfig.BasicAuthUser != "" {
+			req.SetBasicAuth(promConfig.BasicAuthUser, promConfig.BasicAuthPass)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("error making GET request: %v", err)
+			continue
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("error reading response body: %v", err)
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("prometheus returned status %d: %s", resp.StatusCode, body)
+			continue
+		}
+		return body, nil
+	}
+	return nil, lastErr
+}
+
+// queryPrometheus runs an instant query against Prometheus, scoped to
+// promConfig's cluster label if set, and returns the results keyed by node
+// name (see queryNodeLabel).
+func queryPrometheus(query string) (map[string]float64, error) {
+	body, err := doPrometheusRequest("/api/v1/query", url.Values{"query": {injectClusterSelector(query)}})
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
+		return nil, err
 	}
 	var result struct {
 		Status string `json:"status"`
@@ -239,7 +381,7 @@ func queryPrometheus(query string) (map[string]float64, error) {
 	}
 	metrics := make(map[string]float64)
 	for _, r := range result.Data.Result {
-		key := r.Metric["instance"]
+		key := queryNodeLabel(r.Metric)
 		if key == "" {
 			key = fmt.Sprintf("metric_%d", len(metrics))
 		}
@@ -255,6 +397,54 @@ func queryPrometheus(query string) (map[string]float64, error) {
 	return metrics, nil
 }
 
+// queryPrometheusRange runs a /api/v1/query_range query over [start, end] at
+// the given step, scoped the same way queryPrometheus is, and returns each
+// node's sample series in chronological order so callers can average a
+// bursty metric over a window instead of trusting one instant sample.
+func queryPrometheusRange(query string, start, end time.Time, step time.Duration) (map[string][]float64, error) {
+	body, err := doPrometheusRequest("/api/v1/query_range", url.Values{
+		"query": {injectClusterSelector(query)},
+		"start": {strconv.FormatInt(start.Unix(), 10)},
+		"end":   {strconv.FormatInt(end.Unix(), 10)},
+		"step":  {step.String()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][]interface{}   `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("error unmarshaling JSON: %v", err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("query_range returned non-success status: %v", result.Status)
+	}
+	series := make(map[string][]float64, len(result.Data.Result))
+	for _, r := range result.Data.Result {
+		key := queryNodeLabel(r.Metric)
+		samples := make([]float64, 0, len(r.Values))
+		for _, v := range r.Values {
+			if len(v) < 2 {
+				continue
+			}
+			valueStr, ok := v[1].(string)
+			if !ok {
+				continue
+			}
+			samples = append(samples, parseFloat(valueStr))
+		}
+		series[key] = samples
+	}
+	return series, nil
+}
+
 // parseFloat converts a string to a float64.
 func parseFloat(s string) float64 {
 	v, err := strconv.ParseFloat(s, 64)
@@ -300,24 +490,35 @@ func getRunningPods(client kubernetes.Interface, nodeName string) ([]RunningPod,
 			prio = int(*pod.Spec.Priority)
 		}
 		runningPods = append(runningPods, RunningPod{
-			Name:       pod.Name,
-			Namespace:  pod.Namespace,
-			CPURequest: cpuReq,
-			MemRequest: memReq,
-			Priority:   prio,
+			Name:             pod.Name,
+			Namespace:        pod.Namespace,
+			CPURequest:       cpuReq,
+			MemRequest:       memReq,
+			DiskReadRequest:  throughputRequirement(&pod, "disk-read-bps"),
+			DiskWriteRequest: throughputRequirement(&pod, "disk-write-bps"),
+			NetUpRequest:     throughputRequirement(&pod, "net-up-bps"),
+			NetDownRequest:   throughputRequirement(&pod, "net-down-bps"),
+			Priority:         prio,
+			Labels:           pod.Labels,
 		})
 	}
 	return runningPods, nil
 }
 
-// canScheduleMulti checks if a node has sufficient capacity across all resources.
-func canScheduleMulti(pod PodRequest, stats NodeStats, alpha float64) bool {
-	resources := []struct {
-		free  float64
-		total float64
-		req   float64
-		name  string
-	}{
+// resourceUsage pairs one tracked resource's free/total capacity with a
+// pod's demand for it.
+type resourceUsage struct {
+	free  float64
+	total float64
+	req   float64
+	name  string
+}
+
+// podResourceUsages returns pod's demand against stats for every resource
+// this scheduler tracks, shared by canScheduleMulti and every Scorer so they
+// agree on which resources exist and how to read them off NodeStats/PodRequest.
+func podResourceUsages(pod PodRequest, stats NodeStats) []resourceUsage {
+	return []resourceUsage{
 		{stats.CPUFree, stats.CPUTotal, pod.CPU, "cpu"},
 		{stats.MemFree, stats.MemTotal, pod.Mem, "mem"},
 		{stats.DiskReadFree, stats.DiskReadTotal, pod.DiskRead, "diskRead"},
@@ -325,8 +526,11 @@ func canScheduleMulti(pod PodRequest, stats NodeStats, alpha float64) bool {
 		{stats.NetUpFree, stats.NetUpTotal, pod.NetUp, "netUp"},
 		{stats.NetDownFree, stats.NetDownTotal, pod.NetDown, "netDown"},
 	}
+}
 
-	for _, r := range resources {
+// canScheduleMulti checks if a node has sufficient capacity across all resources.
+func canScheduleMulti(pod PodRequest, stats NodeStats, alpha float64) bool {
+	for _, r := range podResourceUsages(pod, stats) {
 		if r.req == 0 {
 			continue
 		}
@@ -343,38 +547,231 @@ func canScheduleMulti(pod PodRequest, stats NodeStats, alpha float64) bool {
 	return true
 }
 
-// scoreMultiResource computes a score based on the dominant resource share.
-func scoreMultiResource(pod PodRequest, stats NodeStats, maxScore int) int {
-	dominantShare := 0.0
-	resources := []struct {
-		free float64
-		req  float64
-		name string
-	}{
-		{stats.CPUFree, pod.CPU, "cpu"},
-		{stats.MemFree, pod.Mem, "mem"},
-		{stats.DiskReadFree, pod.DiskRead, "diskRead"},
-		{stats.DiskWriteFree, pod.DiskWrite, "diskWrite"},
-		{stats.NetUpFree, pod.NetUp, "netUp"},
-		{stats.NetDownFree, pod.NetDown, "netDown"},
+// dominantShare returns the largest req/free ratio across pod's resource
+// demands against stats -- the resource pod stresses most on this node.
+func dominantShare(pod PodRequest, stats NodeStats) float64 {
+	var share float64
+	for _, r := range podResourceUsages(pod, stats) {
+		if r.req == 0 || r.free == 0 {
+			continue
+		}
+		if s := r.req / r.free; s > share {
+			share = s
+		}
 	}
+	return share
+}
 
-	for _, r := range resources {
-		if r.req == 0 {
+// maxExpectedUtilization returns the largest post-placement utilization
+// fraction across pod's resource demands against stats, the same quantity
+// canScheduleMulti compares against alpha.
+func maxExpectedUtilization(pod PodRequest, stats NodeStats) float64 {
+	var max float64
+	for _, r := range podResourceUsages(pod, stats) {
+		if r.req == 0 || r.total == 0 {
 			continue
 		}
-		share := r.req / r.free
-		if share > dominantShare {
-			dominantShare = share
+		if util := 1 - ((r.free - r.req) / r.total); util > max {
+			max = util
 		}
 	}
-	score := float64(maxScore) - (float64(maxScore) * dominantShare)
+	return max
+}
+
+// Scorer ranks how well a pod fits a node once it's already known to fit
+// (canScheduleMulti passed); findNodeForPod scores every feasible node with
+// the configured Scorer and picks the highest instead of the first fit.
+type Scorer interface {
+	Score(pod PodRequest, stats NodeStats) int
+}
+
+// ShareCommitter is implemented by Scorers that track cumulative state
+// across scheduling decisions (e.g. dominantResourceFairnessScorer's
+// per-user share). findNodeForPod calls CommitShare once, after a node has
+// actually been chosen for the pod, instead of accumulating state inside
+// Score itself — Score runs once per candidate node, so mutating there
+// inflates the accumulated state by however many nodes were scored for a
+// single scheduling decision.
+type ShareCommitter interface {
+	CommitShare(pod PodRequest, stats NodeStats)
+}
+
+// ScoringPolicy* name the Scorer implementations newScorer can build,
+// selected via the --scoring-policy flag.
+const (
+	ScoringPolicyDominant    = "dominant"
+	ScoringPolicyBestFit     = "best-fit"
+	ScoringPolicyWorstFit    = "worst-fit"
+	ScoringPolicyWeightedSum = "weighted-sum"
+)
+
+// newScorer builds the Scorer named by policy, defaulting to
+// ScoringPolicyDominant (the original scoreMultiResource behavior) for an
+// empty or unrecognized value.
+func newScorer(policy string, maxScore int, alpha float64, weights map[string]float64) Scorer {
+	switch policy {
+	case ScoringPolicyBestFit:
+		return &bestFitScorer{maxScore: maxScore, alpha: alpha}
+	case ScoringPolicyWorstFit:
+		return &worstFitScorer{maxScore: maxScore}
+	case ScoringPolicyWeightedSum:
+		return &weightedSumScorer{maxScore: maxScore, weights: weights}
+	case "", ScoringPolicyDominant:
+		return newDominantResourceFairnessScorer(maxScore)
+	default:
+		log.Printf("Unknown scoring policy %q, falling back to %s", policy, ScoringPolicyDominant)
+		return newDominantResourceFairnessScorer(maxScore)
+	}
+}
+
+// dominantResourceFairnessScorer scores a node by how much headroom remains
+// in whichever resource the pod stresses most (lower dominant share scores
+// higher), the same notion Dominant Resource Fairness uses for a pod's
+// "dominant resource". userShare accumulates each pod owner's cumulative
+// dominant share across scheduling decisions so a user who has already
+// claimed a large share is nudged toward less favorable placements next
+// time, instead of every decision being scored in isolation.
+type dominantResourceFairnessScorer struct {
+	maxScore int
+
+	mu        sync.Mutex
+	userShare map[string]float64
+}
+
+func newDominantResourceFairnessScorer(maxScore int) *dominantResourceFairnessScorer {
+	return &dominantResourceFairnessScorer{maxScore: maxScore, userShare: make(map[string]float64)}
+}
+
+// Score is read-only: it must not mutate userShare, since findNodeForPod
+// calls Score once per candidate node for a single scheduling decision.
+// CommitShare records the actual accumulated share, once, after a node has
+// been chosen.
+func (s *dominantResourceFairnessScorer) Score(pod PodRequest, stats NodeStats) int {
+	share := dominantShare(pod, stats)
+
+	s.mu.Lock()
+	historicalShare := s.userShare[pod.User]
+	s.mu.Unlock()
+
+	// A user who has already accumulated a larger share is penalized with a
+	// lower score for the same placement, spreading their pods out rather
+	// than scoring every decision as if it were the user's first.
+	score := float64(s.maxScore) - (float64(s.maxScore) * share) - (float64(s.maxScore) * historicalShare * 0.1)
+	if score < 0 {
+		score = 0
+	}
+	return int(score)
+}
+
+// CommitShare adds pod's dominant share against stats to its owner's
+// cumulative userShare. Called once per scheduling decision, after the node
+// pod will actually run on is known (see ShareCommitter).
+func (s *dominantResourceFairnessScorer) CommitShare(pod PodRequest, stats NodeStats) {
+	share := dominantShare(pod, stats)
+
+	s.mu.Lock()
+	s.userShare[pod.User] += share
+	s.mu.Unlock()
+}
+
+// bestFitScorer maximizes expected post-placement utilization while staying
+// under alpha, packing pods onto already-busy nodes and leaving emptier
+// nodes free for larger pods.
+type bestFitScorer struct {
+	maxScore int
+	alpha    float64
+}
+
+func (s *bestFitScorer) Score(pod PodRequest, stats NodeStats) int {
+	util := maxExpectedUtilization(pod, stats)
+	if util > s.alpha {
+		return 0
+	}
+	return int(util * float64(s.maxScore))
+}
+
+// worstFitScorer minimizes the max post-placement utilization, spreading
+// load across nodes instead of packing them.
+type worstFitScorer struct {
+	maxScore int
+}
+
+func (s *worstFitScorer) Score(pod PodRequest, stats NodeStats) int {
+	util := maxExpectedUtilization(pod, stats)
+	score := (1 - util) * float64(s.maxScore)
 	if score < 0 {
 		score = 0
 	}
 	return int(score)
 }
 
+// weightedSumScorer scores a node by its weighted-average post-placement
+// utilization, using operator-configured per-resource weights instead of
+// always ranking by whichever resource happens to be dominant.
+type weightedSumScorer struct {
+	maxScore int
+	weights  map[string]float64
+}
+
+func (s *weightedSumScorer) Score(pod PodRequest, stats NodeStats) int {
+	var totalWeight, weightedUtil float64
+	for _, r := range podResourceUsages(pod, stats) {
+		if r.total == 0 {
+			continue
+		}
+		weight := s.weights[r.name]
+		util := 1 - ((r.free - r.req) / r.total)
+		totalWeight += weight
+		weightedUtil += util * weight
+	}
+	if totalWeight <= 0 {
+		return newDominantResourceFairnessScorer(s.maxScore).Score(pod, stats)
+	}
+	score := (1 - weightedUtil/totalWeight) * float64(s.maxScore)
+	if score < 0 {
+		score = 0
+	}
+	return int(score)
+}
+
+// defaultScoringWeights is the per-resource split ScoringPolicyWeightedSum
+// uses when no ConfigMap is named or loading it fails.
+var defaultScoringWeights = map[string]float64{
+	"cpu":       0.4,
+	"mem":       0.3,
+	"diskRead":  0.075,
+	"diskWrite": 0.075,
+	"netUp":     0.075,
+	"netDown":   0.075,
+}
+
+// scoringWeightsFromConfigMap reads per-resource weights for
+// ScoringPolicyWeightedSum from a ConfigMap's Data, one key per
+// podResourceUsages name (e.g. "cpu", "diskRead"), falling back to
+// defaultScoringWeights for any key the ConfigMap doesn't set.
+func scoringWeightsFromConfigMap(client kubernetes.Interface, namespace, name string) (map[string]float64, error) {
+	weights := make(map[string]float64, len(defaultScoringWeights))
+	for k, v := range defaultScoringWeights {
+		weights[k] = v
+	}
+	if name == "" {
+		return weights, nil
+	}
+
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading scoring weights ConfigMap %s/%s: %v", namespace, name, err)
+	}
+	for k, v := range cm.Data {
+		if weight, err := strconv.ParseFloat(v, 64); err == nil && weight >= 0 {
+			weights[k] = weight
+		} else {
+			log.Printf("Ignoring non-numeric scoring weight %s=%q in ConfigMap %s/%s", k, v, namespace, name)
+		}
+	}
+	return weights, nil
+}
+
 func main() {
 	// Command-line flags.
 	nodeName := flag.String("node", "", "Name of the node to check (if empty, will use the first available node)")
@@ -388,8 +785,35 @@ func main() {
 	alpha := flag.Float64("alpha", 0.8, "Maximum resource utilization threshold (0.0-1.0)")
 	interval := flag.Int("interval", 60, "Interval in seconds between checks")
 	watchMode := flag.Bool("watch", true, "Enable watching for unscheduled pods")
+	scoringPolicy := flag.String("scoring-policy", ScoringPolicyDominant,
+		"Scoring policy for findNodeForPod: dominant, best-fit, worst-fit, or weighted-sum")
+	scoringWeightsConfigMap := flag.String("scoring-weights-configmap", "",
+		"Name of a ConfigMap (in the pod's own namespace) holding per-resource weights for the weighted-sum scoring policy")
+	maxScore := flag.Int("max-score", 100, "Maximum score a Scorer can assign a node")
+	metricsSourceName := flag.String("metrics-source", "prometheus",
+		"Primary node metrics source: prometheus or kubelet (falls back to the other on error)")
+	metricsQueryConfigPath := flag.String("metrics-query-config", "",
+		"Path to a JSON file overriding getNodeStats' CPU/disk/network PromQL queries and capacity defaults")
+	enablePreemption := flag.Bool("enable-preemption", false,
+		"Preempt lower-priority pods on a node that lacks sufficient resources instead of giving up")
+	preemptionDryRun := flag.Bool("preemption-dry-run", false,
+		"Log preemption plans (victims, node, force mode) instead of actually evicting")
+	workerCount := flag.Int("worker-count", 4, "Number of parallel workers draining the pod scheduling workqueue in watch mode")
+	metricsAddr := flag.String("metrics-addr", ":8080", "Address to serve Prometheus metrics on (/metrics)")
+	leaderElect := flag.Bool("leader-elect", false, "Run multiple replicas for HA, with only the elected leader running the pod controller (watch mode only)")
+	leaderElectionNamespace := flag.String("leader-election-namespace", "kube-system", "Namespace holding the leader election Lease")
+	leaderElectionLockName := flag.String("leader-election-lock-name", "multi-resource-scheduler", "Name of the leader election Lease")
+	leaseDurationSeconds := flag.Int("leader-elect-lease-duration", 15, "Seconds a non-leader waits without a renewed Lease before attempting to acquire it")
+	renewDeadlineSeconds := flag.Int("leader-elect-renew-deadline", 10, "Seconds the leader retries renewing its Lease before giving up")
+	retryPeriodSeconds := flag.Int("leader-elect-retry-period", 2, "Seconds between leader election actions (acquire/renew attempts)")
 	flag.Parse()
 
+	cfg, err := LoadMetricsQueryConfig(*metricsQueryConfigPath)
+	if err != nil {
+		log.Fatalf("Error loading metrics query config: %v", err)
+	}
+	metricsQueryConfig = cfg
+
 	// Create Kubernetes client.
 	config, err := rest.InClusterConfig()
 	if err != nil {
@@ -407,10 +831,46 @@ func main() {
 		log.Fatalf("Error creating Kubernetes client: %v", err)
 	}
 
-	// Start a goroutine to watch for unscheduled pods if watch mode is enabled
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		namespace = "default"
+	}
+	weights, err := scoringWeightsFromConfigMap(client, namespace, *scoringWeightsConfigMap)
+	if err != nil {
+		log.Printf("Error loading scoring weights, falling back to defaults: %v", err)
+		weights = defaultScoringWeights
+	}
+	scorer := newScorer(*scoringPolicy, *maxScore, *alpha, weights)
+	metricsSource := buildMetricsSource(*metricsSourceName)
+
+	// Serve Prometheus metrics (scheduling attempt latency, queue depth,
+	// pods-scheduled counters) alongside whatever watch mode is doing.
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+
+	// Start the informer-driven pod controller if watch mode is enabled,
+	// replacing the old list-poll loop with a shared informer feeding a
+	// workqueue drained by --worker-count workers. With --leader-elect,
+	// only the replica holding the Lease runs it, so several replicas can
+	// run for HA without double-scheduling pods.
 	if *watchMode {
-		log.Println("Starting to watch for unscheduled pods...")
-		go watchForUnscheduledPods(client, *alpha)
+		startController := func(ctx context.Context) {
+			log.Println("Starting informer-driven pod controller...")
+			controller := newPodController(client, *alpha, scorer, metricsSource, *enablePreemption, *preemptionDryRun, *workerCount)
+			go controller.Run(wait.NeverStop)
+		}
+		if *leaderElect {
+			go runWithLeaderElection(client, *leaderElectionNamespace, *leaderElectionLockName,
+				time.Duration(*leaseDurationSeconds)*time.Second, time.Duration(*renewDeadlineSeconds)*time.Second, time.Duration(*retryPeriodSeconds)*time.Second,
+				startController)
+		} else {
+			startController(context.Background())
+		}
 	}
 
 	for {
@@ -452,7 +912,7 @@ func main() {
 			log.Printf("Prometheus connection test successful. Metrics: %+v", testMetrics)
 		}
 
-		stats, err := getNodeStats(targetNode)
+		stats, err := metricsSource.NodeStats(client, targetNode)
 		if err != nil {
 			log.Printf("Error getting node stats: %v", err)
 			time.Sleep(time.Duration(*interval) * time.Second)
@@ -474,7 +934,17 @@ func main() {
 			log.Printf("Node %s can schedule the Pod.", targetNode)
 		} else {
 			log.Printf("Node %s lacks sufficient resources.", targetNode)
-			log.Printf("Multi-resource scheduler does not support preemption; cannot schedule Pod on node %s.", targetNode)
+			if !*enablePreemption {
+				log.Printf("Preemption disabled (--enable-preemption=false); cannot schedule Pod on node %s.", targetNode)
+			} else if victims, ok, err := attemptPreemption(client, targetNode, podReq, stats, *alpha, *preemptionDryRun); err != nil {
+				log.Printf("Error attempting preemption on node %s: %v", targetNode, err)
+			} else if !ok {
+				log.Printf("No feasible preemption found on node %s; cannot schedule Pod.", targetNode)
+			} else if *preemptionDryRun {
+				log.Printf("Dry run: preempting %d pod(s) on node %s would let the Pod schedule.", len(victims), targetNode)
+			} else {
+				log.Printf("Preempted %d pod(s) on node %s to schedule the Pod.", len(victims), targetNode)
+			}
 		}
 
 		log.Printf("Sleeping for %d seconds before next check...", *interval)
@@ -482,59 +952,6 @@ func main() {
 	}
 }
 
-// watchForUnscheduledPods watches for pods that have no node assigned and attempts to schedule them
-func watchForUnscheduledPods(client kubernetes.Interface, alpha float64) {
-	for {
-		// Get all pods in the cluster
-		pods, err := client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
-			FieldSelector: "spec.schedulerName=multi-resource-scheduler,spec.nodeName=",
-		})
-		if err != nil {
-			log.Printf("Error listing pods: %v", err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-
-		// Process each unscheduled pod
-		for _, pod := range pods.Items {
-			log.Printf("Found unscheduled pod: %s/%s", pod.Namespace, pod.Name)
-
-			// Skip pods that are being deleted
-			if pod.DeletionTimestamp != nil {
-				log.Printf("Pod %s/%s is being deleted, skipping", pod.Namespace, pod.Name)
-				continue
-			}
-
-			// Get pod resource requirements
-			podReq := extractPodRequirements(&pod)
-
-			// Find a suitable node for the pod
-			nodeName, err := findNodeForPod(client, podReq, alpha)
-			if err != nil {
-				log.Printf("Error finding node for pod %s/%s: %v", pod.Namespace, pod.Name, err)
-				continue
-			}
-
-			if nodeName == "" {
-				log.Printf("No suitable node found for pod %s/%s", pod.Namespace, pod.Name)
-				continue
-			}
-
-			// Bind the pod to the node
-			err = bindPodToNode(client, &pod, nodeName)
-			if err != nil {
-				log.Printf("Error binding pod %s/%s to node %s: %v", pod.Namespace, pod.Name, nodeName, err)
-				continue
-			}
-
-			log.Printf("Successfully scheduled pod %s/%s on node %s", pod.Namespace, pod.Name, nodeName)
-		}
-
-		// Sleep before checking again
-		time.Sleep(1 * time.Second)
-	}
-}
-
 // extractPodRequirements extracts resource requirements from a pod
 func extractPodRequirements(pod *v1.Pod) PodRequest {
 	var cpuReq, memReq float64
@@ -558,25 +975,68 @@ func extractPodRequirements(pod *v1.Pod) PodRequest {
 	}
 
 	return PodRequest{
-		CPU:      cpuReq,
-		Mem:      memReq,
-		Priority: priority,
-		// Set other fields to 0 as they're not typically specified in pod specs
-		DiskRead:  0,
-		DiskWrite: 0,
-		NetUp:     0,
-		NetDown:   0,
+		CPU:       cpuReq,
+		Mem:       memReq,
+		Priority:  priority,
+		User:      pod.Namespace,
+		DiskRead:  throughputRequirement(pod, "disk-read-bps"),
+		DiskWrite: throughputRequirement(pod, "disk-write-bps"),
+		NetUp:     throughputRequirement(pod, "net-up-bps"),
+		NetDown:   throughputRequirement(pod, "net-down-bps"),
 	}
 }
 
-// findNodeForPod finds a suitable node for the pod
-func findNodeForPod(client kubernetes.Interface, podReq PodRequest, alpha float64) (string, error) {
+// throughputAnnotationPrefix/throughputResourcePrefix are how pods declare
+// disk/network throughput requests, since ResourceRequirements has no
+// built-in field for them: a scheduler.dissertation.io/<name> annotation, or
+// (if that's absent) a dissertation.io/<name> extended resource request
+// summed across containers.
+const (
+	throughputAnnotationPrefix = "scheduler.dissertation.io/"
+	throughputResourcePrefix   = "dissertation.io/"
+)
+
+// throughputRequirement returns pod's requested bytes/sec for the named
+// dimension (e.g. "disk-read-bps"): the scheduler.dissertation.io/ annotation
+// if present and parseable, otherwise the sum of the dissertation.io/
+// extended resource request across containers.
+func throughputRequirement(pod *v1.Pod, name string) float64 {
+	if raw, ok := pod.Annotations[throughputAnnotationPrefix+name]; ok {
+		if value, err := strconv.ParseFloat(raw, 64); err == nil {
+			return value
+		} else {
+			log.Printf("Warning: pod %s/%s annotation %s%s=%q is not a number: %v", pod.Namespace, pod.Name, throughputAnnotationPrefix, name, raw, err)
+		}
+	}
+
+	var total float64
+	resourceName := v1.ResourceName(throughputResourcePrefix + name)
+	for _, container := range pod.Spec.Containers {
+		if qty, ok := container.Resources.Requests[resourceName]; ok {
+			total += float64(qty.Value())
+		}
+	}
+	return total
+}
+
+// findNodeForPod scores every feasible node with scorer and returns the
+// highest-scoring one, instead of the first node that fits. If no node
+// fits outright and enablePreemption is set, it falls back to the first
+// constraint-satisfying node where attemptPreemption finds a feasible
+// victim set, evicting those victims before returning it. In
+// preemptionDryRun it only logs each feasible plan and returns no node.
+func findNodeForPod(client kubernetes.Interface, pod *v1.Pod, podReq PodRequest, alpha float64, scorer Scorer, metricsSource MetricsSource, enablePreemption bool, preemptionDryRun bool) (string, error) {
 	// Get all nodes
 	nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return "", fmt.Errorf("error listing nodes: %v", err)
 	}
 
+	bestNode := ""
+	bestScore := -1
+	var bestStats NodeStats
+	var preemptionCandidates []string
+
 	// Check each node
 	for _, node := range nodes.Items {
 		nodeName := node.Name
@@ -587,20 +1047,72 @@ func findNodeForPod(client kubernetes.Interface, podReq PodRequest, alpha float6
 			continue
 		}
 
+		// Skip nodes that fail the pod's NodeSelector/node affinity,
+		// taints/tolerations, or pod (anti-)affinity.
+		if ok, reason := nodeSatisfiesConstraints(client, pod, &node); !ok {
+			log.Printf("Node %s rejected for pod %s/%s: %s", nodeName, pod.Namespace, pod.Name, reason)
+			continue
+		}
+
 		// Get node stats
-		stats, err := getNodeStats(nodeName)
+		stats, err := metricsSource.NodeStats(client, nodeName)
 		if err != nil {
 			log.Printf("Error getting stats for node %s: %v", nodeName, err)
 			continue
 		}
 
-		// Check if the node can schedule the pod
-		if canScheduleMulti(podReq, stats, alpha) {
-			return nodeName, nil
+		// Skip nodes that can't schedule the pod, but remember them as
+		// preemption candidates in case no node fits outright.
+		if !canScheduleMulti(podReq, stats, alpha) {
+			preemptionCandidates = append(preemptionCandidates, nodeName)
+			continue
+		}
+
+		score := scorer.Score(podReq, stats)
+		log.Printf("Node %s scored %d for pod", nodeName, score)
+		if score > bestScore {
+			bestScore = score
+			bestNode = nodeName
+			bestStats = stats
+		}
+	}
+
+	if bestNode != "" {
+		if committer, ok := scorer.(ShareCommitter); ok {
+			committer.CommitShare(podReq, bestStats)
+		}
+		return bestNode, nil
+	}
+
+	if !enablePreemption {
+		return "", nil
+	}
+
+	for _, nodeName := range preemptionCandidates {
+		stats, err := metricsSource.NodeStats(client, nodeName)
+		if err != nil {
+			log.Printf("Error getting stats for node %s: %v", nodeName, err)
+			continue
+		}
+		victims, ok, err := attemptPreemption(client, nodeName, podReq, stats, alpha, preemptionDryRun)
+		if err != nil {
+			log.Printf("Error attempting preemption on node %s for pod %s/%s: %v", nodeName, pod.Namespace, pod.Name, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if preemptionDryRun {
+			log.Printf("Dry run: preempting %d pod(s) on node %s would let pod %s/%s schedule", len(victims), nodeName, pod.Namespace, pod.Name)
+			continue
+		}
+		log.Printf("Preempted %d pod(s) on node %s to schedule pod %s/%s", len(victims), nodeName, pod.Namespace, pod.Name)
+		if committer, ok := scorer.(ShareCommitter); ok {
+			committer.CommitShare(podReq, simulateStatsWithVictimsFreed(stats, victims))
 		}
+		return nodeName, nil
 	}
 
-	// No suitable node found
 	return "", nil
 }
 
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// syntheticNodes builds n simulated NodeStats with varied headroom, standing
+// in for a real cluster so the Benchmark* functions below can compare
+// Scorer implementations without hitting Prometheus or the Kubernetes API.
+func syntheticNodes(n int) []NodeStats {
+	nodes := make([]NodeStats, n)
+	for i := range nodes {
+		load := float64(i%10) / 10.0 // 0.0, 0.1, ..., 0.9, repeating
+		nodes[i] = NodeStats{
+			CPUTotal:       8,
+			CPUFree:        8 * (1 - load),
+			MemTotal:       32 * 1024 * 1024 * 1024,
+			MemFree:        32 * 1024 * 1024 * 1024 * (1 - load),
+			DiskReadTotal:  100 * 1024 * 1024,
+			DiskReadFree:   100 * 1024 * 1024 * (1 - load),
+			DiskWriteTotal: 50 * 1024 * 1024,
+			DiskWriteFree:  50 * 1024 * 1024 * (1 - load),
+			NetUpTotal:     125 * 1024 * 1024,
+			NetUpFree:      125 * 1024 * 1024 * (1 - load),
+			NetDownTotal:   125 * 1024 * 1024,
+			NetDownFree:    125 * 1024 * 1024 * (1 - load),
+		}
+	}
+	return nodes
+}
+
+// syntheticPodTrace builds n PodRequests spread across a handful of users,
+// sized like a typical mixed CPU/memory workload.
+func syntheticPodTrace(n int) []PodRequest {
+	pods := make([]PodRequest, n)
+	for i := range pods {
+		pods[i] = PodRequest{
+			CPU:  0.5 + float64(i%4)*0.25,
+			Mem:  float64(512+i%2048) * 1024 * 1024,
+			User: fmt.Sprintf("user-%d", i%5),
+		}
+	}
+	return pods
+}
+
+// benchmarkScoringPolicy replays a synthetic pod trace across simulated
+// nodes the way findNodeForPod does -- scoring every feasible node with
+// policy and picking the max -- so `go test -bench` can compare Scorer
+// implementations the way Yunikorn's scheduler_perf_test.go compares its
+// own scheduling policies.
+func benchmarkScoringPolicy(b *testing.B, policy string) {
+	nodes := syntheticNodes(200)
+	trace := syntheticPodTrace(1000)
+	scorer := newScorer(policy, 100, 0.8, defaultScoringWeights)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, pod := range trace {
+			bestScore := -1
+			for _, node := range nodes {
+				if !canScheduleMulti(pod, node, 0.8) {
+					continue
+				}
+				if score := scorer.Score(pod, node); score > bestScore {
+					bestScore = score
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkScoringPolicyDominant(b *testing.B)    { benchmarkScoringPolicy(b, ScoringPolicyDominant) }
+func BenchmarkScoringPolicyBestFit(b *testing.B)     { benchmarkScoringPolicy(b, ScoringPolicyBestFit) }
+func BenchmarkScoringPolicyWorstFit(b *testing.B)    { benchmarkScoringPolicy(b, ScoringPolicyWorstFit) }
+func BenchmarkScoringPolicyWeightedSum(b *testing.B) { benchmarkScoringPolicy(b, ScoringPolicyWeightedSum) }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExtractPodRequirements_ThroughputAnnotation(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Annotations: map[string]string{
+				"scheduler.dissertation.io/disk-read-bps": "1048576",
+				"scheduler.dissertation.io/net-up-bps":    "2048",
+				"scheduler.dissertation.io/net-down-bps":  "not-a-number",
+			},
+		},
+	}
+
+	req := extractPodRequirements(pod)
+	if req.DiskRead != 1048576 {
+		t.Errorf("DiskRead = %v, want 1048576", req.DiskRead)
+	}
+	if req.NetUp != 2048 {
+		t.Errorf("NetUp = %v, want 2048", req.NetUp)
+	}
+	if req.NetDown != 0 {
+		t.Errorf("NetDown = %v, want 0 for an unparseable annotation", req.NetDown)
+	}
+	if req.DiskWrite != 0 {
+		t.Errorf("DiskWrite = %v, want 0 when neither annotation nor extended resource is set", req.DiskWrite)
+	}
+}
+
+func TestExtractPodRequirements_ThroughputExtendedResourceFallback(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							"dissertation.io/disk-write-bps": resource.MustParse("500000"),
+						},
+					},
+				},
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							"dissertation.io/disk-write-bps": resource.MustParse("250000"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	req := extractPodRequirements(pod)
+	if req.DiskWrite != 750000 {
+		t.Errorf("DiskWrite = %v, want 750000 (summed across containers)", req.DiskWrite)
+	}
+}
+
+func TestTaintsTolerated_RejectsUntoleratedNoScheduleTaint(t *testing.T) {
+	pod := &v1.Pod{}
+	node := &v1.Node{
+		Spec: v1.NodeSpec{
+			Taints: []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}},
+		},
+	}
+
+	ok, reason := taintsTolerated(pod, node)
+	if ok {
+		t.Errorf("taintsTolerated() = true, want false for an untolerated taint")
+	}
+	if reason == "" {
+		t.Errorf("taintsTolerated() reason is empty, want an explanation")
+	}
+}
+
+func TestTaintsTolerated_AcceptsToleratedTaint(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Tolerations: []v1.Toleration{
+				{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu", Effect: v1.TaintEffectNoSchedule},
+			},
+		},
+	}
+	node := &v1.Node{
+		Spec: v1.NodeSpec{
+			Taints: []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}},
+		},
+	}
+
+	ok, _ := taintsTolerated(pod, node)
+	if !ok {
+		t.Errorf("taintsTolerated() = false, want true when pod tolerates the node's only taint")
+	}
+}
+
+func TestTaintsTolerated_IgnoresPreferNoScheduleTaint(t *testing.T) {
+	pod := &v1.Pod{}
+	node := &v1.Node{
+		Spec: v1.NodeSpec{
+			Taints: []v1.Taint{{Key: "spot", Value: "true", Effect: v1.TaintEffectPreferNoSchedule}},
+		},
+	}
+
+	ok, _ := taintsTolerated(pod, node)
+	if !ok {
+		t.Errorf("taintsTolerated() = false, want true: PreferNoSchedule isn't a hard constraint")
+	}
+}
+
+func TestNodeSelectorAndAffinityMatch_RejectsMismatchedNodeSelector(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{NodeSelector: map[string]string{"disktype": "ssd"}},
+	}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"disktype": "hdd"}},
+	}
+
+	ok, _ := nodeSelectorAndAffinityMatch(pod, node)
+	if ok {
+		t.Errorf("nodeSelectorAndAffinityMatch() = true, want false for a mismatched nodeSelector")
+	}
+}
+
+func TestNodeSelectorAndAffinityMatch_RejectsUnmetRequiredNodeAffinity(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Affinity: &v1.Affinity{
+				NodeAffinity: &v1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+						NodeSelectorTerms: []v1.NodeSelectorTerm{
+							{MatchExpressions: []v1.NodeSelectorRequirement{
+								{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"us-east-1a"}},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"zone": "us-west-2a"}}}
+
+	ok, _ := nodeSelectorAndAffinityMatch(pod, node)
+	if ok {
+		t.Errorf("nodeSelectorAndAffinityMatch() = true, want false: node's zone label doesn't satisfy required affinity")
+	}
+}
+
+func TestNodeSelectorAndAffinityMatch_AcceptsMetRequiredNodeAffinity(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Affinity: &v1.Affinity{
+				NodeAffinity: &v1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+						NodeSelectorTerms: []v1.NodeSelectorTerm{
+							{MatchExpressions: []v1.NodeSelectorRequirement{
+								{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"us-east-1a"}},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"zone": "us-east-1a"}}}
+
+	ok, _ := nodeSelectorAndAffinityMatch(pod, node)
+	if !ok {
+		t.Errorf("nodeSelectorAndAffinityMatch() = false, want true: node's zone label satisfies required affinity")
+	}
+}
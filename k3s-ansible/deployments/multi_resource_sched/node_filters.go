@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodeSatisfiesConstraints reports whether node is a legal placement for pod
+// under its NodeSelector/node affinity, taints/tolerations, and pod
+// (anti-)affinity, the checks findNodeForPod previously skipped entirely
+// (only Ready/Unschedulable were filtered). client is used to list the pods
+// already bound to node for the pod-affinity check.
+func nodeSatisfiesConstraints(client kubernetes.Interface, pod *v1.Pod, node *v1.Node) (ok bool, reason string) {
+	if ok, reason := nodeSelectorAndAffinityMatch(pod, node); !ok {
+		return false, reason
+	}
+	if ok, reason := taintsTolerated(pod, node); !ok {
+		return false, reason
+	}
+	return podAffinitySatisfied(client, pod, node)
+}
+
+// nodeSelectorAndAffinityMatch checks pod's legacy spec.NodeSelector and
+// required node affinity against node's labels.
+func nodeSelectorAndAffinityMatch(pod *v1.Pod, node *v1.Node) (bool, string) {
+	for key, value := range pod.Spec.NodeSelector {
+		if node.Labels[key] != value {
+			return false, fmt.Sprintf("does not match nodeSelector %s=%s", key, value)
+		}
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true, ""
+	}
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if matchNodeSelectorTerms(terms, node.Labels) {
+		return true, ""
+	}
+	return false, "does not match required node affinity"
+}
+
+// matchNodeSelectorTerms reports whether nodeLabels satisfies at least one of
+// terms (terms are OR'd; each term's expressions are AND'd), per the
+// NodeSelectorTerm contract.
+func matchNodeSelectorTerms(terms []v1.NodeSelectorTerm, nodeLabels map[string]string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	for _, term := range terms {
+		if matchNodeSelectorTerm(term, nodeLabels) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchNodeSelectorTerm(term v1.NodeSelectorTerm, nodeLabels map[string]string) bool {
+	for _, expr := range term.MatchExpressions {
+		value, present := nodeLabels[expr.Key]
+		switch expr.Operator {
+		case v1.NodeSelectorOpIn:
+			if !present || !containsString(expr.Values, value) {
+				return false
+			}
+		case v1.NodeSelectorOpNotIn:
+			if present && containsString(expr.Values, value) {
+				return false
+			}
+		case v1.NodeSelectorOpExists:
+			if !present {
+				return false
+			}
+		case v1.NodeSelectorOpDoesNotExist:
+			if present {
+				return false
+			}
+		default:
+			// Gt/Lt and field selectors aren't supported; treat as
+			// non-matching rather than silently ignoring an operator the pod
+			// author relied on.
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// taintsTolerated rejects node if any of its NoSchedule or NoExecute taints
+// isn't tolerated by pod, mirroring the kubelet's own TaintToleration
+// admission rule.
+func taintsTolerated(pod *v1.Pod, node *v1.Node) (bool, string) {
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect != v1.TaintEffectNoSchedule && taint.Effect != v1.TaintEffectNoExecute {
+			continue
+		}
+		if !tolerated(pod.Spec.Tolerations, taint) {
+			return false, fmt.Sprintf("untolerated taint %s=%s:%s", taint.Key, taint.Value, taint.Effect)
+		}
+	}
+	return true, ""
+}
+
+// tolerated reports whether any toleration in tolerations covers taint.
+func tolerated(tolerations []v1.Toleration, taint v1.Taint) bool {
+	for _, t := range tolerations {
+		if t.Effect != "" && t.Effect != taint.Effect {
+			continue
+		}
+		switch t.Operator {
+		case v1.TolerationOpExists:
+			if t.Key == "" || t.Key == taint.Key {
+				return true
+			}
+		case "", v1.TolerationOpEqual:
+			if t.Key == taint.Key && t.Value == taint.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// podAffinitySatisfied approximates pod (anti-)affinity by treating every
+// topology key as "same node" (this scheduler doesn't track a node's
+// region/zone topology), checking required terms against pods already bound
+// to node.
+func podAffinitySatisfied(client kubernetes.Interface, pod *v1.Pod, node *v1.Node) (bool, string) {
+	affinity := pod.Spec.Affinity
+	if affinity == nil || (affinity.PodAffinity == nil && affinity.PodAntiAffinity == nil) {
+		return true, ""
+	}
+
+	nodePods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + node.Name,
+	})
+	if err != nil {
+		log.Printf("Warning: podAffinity filter could not list pods on node %s, assuming it matches: %v", node.Name, err)
+		return true, ""
+	}
+
+	if affinity.PodAffinity != nil {
+		for _, term := range affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			if !anyPodMatchesAffinityTerm(term, pod.Namespace, nodePods.Items) {
+				return false, "no existing pod on the node satisfies required pod affinity"
+			}
+		}
+	}
+	if affinity.PodAntiAffinity != nil {
+		for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			if anyPodMatchesAffinityTerm(term, pod.Namespace, nodePods.Items) {
+				return false, "an existing pod on the node violates required pod anti-affinity"
+			}
+		}
+	}
+	return true, ""
+}
+
+func anyPodMatchesAffinityTerm(term v1.PodAffinityTerm, podNamespace string, candidates []v1.Pod) bool {
+	selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+	if err != nil {
+		log.Printf("Warning: invalid pod affinity label selector: %v", err)
+		return false
+	}
+	namespaces := map[string]bool{podNamespace: true}
+	for _, ns := range term.Namespaces {
+		namespaces[ns] = true
+	}
+	for _, candidate := range candidates {
+		if !namespaces[candidate.Namespace] {
+			continue
+		}
+		if selector.Matches(labels.Set(candidate.Labels)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Metrics exposed on /metrics for the pod controller.
+var (
+	schedulingAttemptDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "multi_resource_sched_attempt_duration_seconds",
+		Help: "Time spent attempting to schedule a single pod, from workqueue pop to bind/requeue.",
+	}, []string{"result"})
+	schedulingQueueLength = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "multi_resource_sched_queue_length",
+		Help: "Number of pods currently waiting in the scheduling workqueue.",
+	})
+	podsScheduledTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "multi_resource_sched_pods_scheduled_total",
+		Help: "Number of pods successfully bound to a node, by namespace.",
+	}, []string{"namespace"})
+)
+
+func init() {
+	prometheus.MustRegister(schedulingAttemptDuration, schedulingQueueLength, podsScheduledTotal)
+}
+
+// multiResourceSchedulerName is the spec.schedulerName this controller
+// claims pods for, replacing the old watchForUnscheduledPods poll loop's
+// FieldSelector literal.
+const multiResourceSchedulerName = "multi-resource-scheduler"
+
+// podController replaces the old watchForUnscheduledPods polling loop (a
+// 1-second Pods("").List against the whole cluster) with a shared-informer/
+// workqueue pipeline: the informer watches pods claiming this scheduler and
+// feeds their keys into a rate-limiting workqueue, which workerCount worker
+// goroutines drain in parallel.
+type podController struct {
+	client           kubernetes.Interface
+	informer         cache.SharedIndexInformer
+	queue            workqueue.RateLimitingInterface
+	alpha            float64
+	scorer           Scorer
+	metricsSource    MetricsSource
+	enablePreemption bool
+	preemptionDryRun bool
+	workerCount      int
+}
+
+// newPodController builds a podController watching Pods with
+// spec.schedulerName=multi-resource-scheduler and no spec.nodeName, across
+// all namespaces.
+func newPodController(client kubernetes.Interface, alpha float64, scorer Scorer, metricsSource MetricsSource, enablePreemption bool, preemptionDryRun bool, workerCount int) *podController {
+	pc := &podController{
+		client:           client,
+		queue:            workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		alpha:            alpha,
+		scorer:           scorer,
+		metricsSource:    metricsSource,
+		enablePreemption: enablePreemption,
+		preemptionDryRun: preemptionDryRun,
+		workerCount:      workerCount,
+	}
+
+	selector := fields.AndSelectors(
+		fields.OneTermEqualSelector("spec.schedulerName", multiResourceSchedulerName),
+		fields.OneTermEqualSelector("spec.nodeName", ""),
+	).String()
+
+	pc.informer = cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = selector
+				return client.CoreV1().Pods(metav1.NamespaceAll).List(context.Background(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = selector
+				return client.CoreV1().Pods(metav1.NamespaceAll).Watch(context.Background(), options)
+			},
+		},
+		&v1.Pod{},
+		30*time.Second,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+
+	pc.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    pc.enqueue,
+		UpdateFunc: func(_, newObj interface{}) { pc.enqueue(newObj) },
+	})
+
+	return pc
+}
+
+// enqueue adds obj's namespace/name key to the workqueue if it's a pod that
+// still needs a node and isn't being deleted.
+func (pc *podController) enqueue(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Printf("Error computing key for pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		return
+	}
+	pc.queue.Add(key)
+	schedulingQueueLength.Set(float64(pc.queue.Len()))
+}
+
+// Run starts the pod informer and workerCount worker goroutines, blocking
+// until stopCh is closed.
+func (pc *podController) Run(stopCh <-chan struct{}) {
+	defer pc.queue.ShutDown()
+
+	go pc.informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, pc.informer.HasSynced) {
+		log.Println("Error: timed out waiting for pod informer cache to sync")
+		return
+	}
+
+	for i := 0; i < pc.workerCount; i++ {
+		go wait.Until(pc.runWorker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+// runWorker pops one key at a time from the workqueue and attempts to
+// schedule it until the queue shuts down.
+func (pc *podController) runWorker() {
+	for pc.processNextItem() {
+	}
+}
+
+// processNextItem pops a single key, processes it, and reports the
+// namespace/name's scheduling outcome to the workqueue so it's retried with
+// backoff on failure or forgotten on success. It returns false once the
+// queue has shut down.
+func (pc *podController) processNextItem() bool {
+	key, shutdown := pc.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer pc.queue.Done(key)
+	schedulingQueueLength.Set(float64(pc.queue.Len()))
+
+	if err := pc.schedulePod(key.(string)); err != nil {
+		log.Printf("Error scheduling pod %s, requeuing: %v", key, err)
+		pc.queue.AddRateLimited(key)
+		return true
+	}
+	pc.queue.Forget(key)
+	return true
+}
+
+// schedulePod fetches key (namespace/name), finds it a node, and binds it,
+// mirroring the per-pod body of the old watchForUnscheduledPods loop.
+func (pc *podController) schedulePod(key string) error {
+	start := time.Now()
+	result := "error"
+	defer func() {
+		schedulingAttemptDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	}()
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	pod, err := pc.client.CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		result = "fetch-error"
+		return err
+	}
+	if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil {
+		result = "skipped"
+		return nil
+	}
+
+	podReq := extractPodRequirements(pod)
+
+	nodeName, err := findNodeForPod(pc.client, pod, podReq, pc.alpha, pc.scorer, pc.metricsSource, pc.enablePreemption, pc.preemptionDryRun)
+	if err != nil {
+		result = "find-node-error"
+		return err
+	}
+	if nodeName == "" {
+		result = "no-node"
+		return fmt.Errorf("no suitable node found for pod %s/%s", namespace, name)
+	}
+
+	if err := bindPodToNode(pc.client, pod, nodeName); err != nil {
+		result = "bind-error"
+		return err
+	}
+
+	log.Printf("Successfully scheduled pod %s/%s on node %s", namespace, name, nodeName)
+	podsScheduledTotal.WithLabelValues(namespace).Inc()
+	result = "scheduled"
+	return nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// This file gives multi_resource_sched the priority/PDB-aware preemption
+// canScheduleMulti's failure path previously just gave up on: when a node
+// lacks the resources for a Pod, attemptPreemption looks for lower-priority
+// pods running on it whose eviction would free enough of every resource the
+// Pod requests (CPU, mem, disk read/write, network up/down), preferring a
+// victim set that doesn't push any covering PodDisruptionBudget below its
+// desired healthy count.
+
+// systemCriticalThreshold returns the priority value at or above which
+// selectVictims switches to force mode (DELETE instead of the eviction
+// subresource, bypassing PodDisruptionBudgets), configurable via
+// PREEMPTION_SYSTEM_CRITICAL_THRESHOLD. The default matches the
+// system-cluster-critical PriorityClass value Kubernetes ships with.
+func systemCriticalThreshold() int {
+	if val := os.Getenv("PREEMPTION_SYSTEM_CRITICAL_THRESHOLD"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil {
+			return parsed
+		}
+	}
+	return 2000000000
+}
+
+// pdbBudgetTracker simulates the effect of evicting a growing victim set
+// against every PodDisruptionBudget those victims are covered by, so a
+// multi-pod preemption pass doesn't collectively violate a single PDB even
+// though each individual eviction looks safe in isolation.
+type pdbBudgetTracker struct {
+	client kubernetes.Interface
+	// remaining[namespace/name] is how many more evictions that PDB can
+	// currently absorb before currentHealthy would drop below
+	// desiredHealthy. Populated lazily per namespace.
+	remaining map[string]int32
+	pdbs      map[string][]policyv1.PodDisruptionBudget // cached by namespace
+}
+
+func newPDBBudgetTracker(client kubernetes.Interface) *pdbBudgetTracker {
+	return &pdbBudgetTracker{
+		client:    client,
+		remaining: make(map[string]int32),
+		pdbs:      make(map[string][]policyv1.PodDisruptionBudget),
+	}
+}
+
+// namespacePDBs returns (and caches) the PDBs in namespace, seeding
+// t.remaining with each one's current headroom the first time it's seen.
+func (t *pdbBudgetTracker) namespacePDBs(namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	if cached, ok := t.pdbs[namespace]; ok {
+		return cached, nil
+	}
+
+	pdbList, err := t.client.PolicyV1().PodDisruptionBudgets(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	t.pdbs[namespace] = pdbList.Items
+	for _, pdb := range pdbList.Items {
+		t.remaining[namespace+"/"+pdb.Name] = pdb.Status.CurrentHealthy - pdb.Status.DesiredHealthy
+	}
+	return pdbList.Items, nil
+}
+
+// coveringPDBs returns the namespace-scoped PDB keys whose selector matches
+// candidate's labels.
+func (t *pdbBudgetTracker) coveringPDBs(candidate RunningPod) ([]string, error) {
+	pdbs, err := t.namespacePDBs(candidate.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			log.Printf("Warning: invalid selector on PodDisruptionBudget %s/%s: %v", pdb.Namespace, pdb.Name, err)
+			continue
+		}
+		if selector.Matches(labels.Set(candidate.Labels)) {
+			keys = append(keys, candidate.Namespace+"/"+pdb.Name)
+		}
+	}
+	return keys, nil
+}
+
+// canEvict reports whether evicting candidate would keep every PDB covering
+// it at or above its desired healthy count, given evictions already
+// reserved by earlier calls to reserve in this preemption pass.
+func (t *pdbBudgetTracker) canEvict(candidate RunningPod) (ok bool, blockingPDB string) {
+	keys, err := t.coveringPDBs(candidate)
+	if err != nil {
+		log.Printf("Warning: failed to list PodDisruptionBudgets for %s/%s, assuming no PDB applies: %v", candidate.Namespace, candidate.Name, err)
+		return true, ""
+	}
+	for _, key := range keys {
+		if t.remaining[key] <= 0 {
+			return false, key
+		}
+	}
+	return true, ""
+}
+
+// reserve records candidate as committed-to-evict, decrementing the
+// simulated remaining budget of every PDB covering it.
+func (t *pdbBudgetTracker) reserve(candidate RunningPod) {
+	keys, err := t.coveringPDBs(candidate)
+	if err != nil {
+		return
+	}
+	for _, key := range keys {
+		t.remaining[key]--
+	}
+}
+
+// fitsWithVictims reports whether evicting every pod in set would free
+// enough of each resource pod requests -- CPU, mem, disk read/write, and
+// network up/down -- to fit in stats. A dimension pod doesn't request
+// (req == 0) is ignored, matching canScheduleMulti's own "not requested"
+// guard.
+func fitsWithVictims(set []RunningPod, stats NodeStats, pod PodRequest) bool {
+	freed := simulateStatsWithVictimsFreed(stats, set)
+	return canScheduleMultiIgnoringAlpha(pod, freed)
+}
+
+// simulateStatsWithVictimsFreed returns a copy of stats with every tracked
+// resource's Free field increased by what evicting victims would release,
+// so canScheduleMulti can be re-run against the post-eviction picture
+// instead of just the raw capacity victims free.
+func simulateStatsWithVictimsFreed(stats NodeStats, victims []RunningPod) NodeStats {
+	freed := stats
+	for _, v := range victims {
+		freed.CPUFree += v.CPURequest
+		freed.MemFree += v.MemRequest
+		freed.DiskReadFree += v.DiskReadRequest
+		freed.DiskWriteFree += v.DiskWriteRequest
+		freed.NetUpFree += v.NetUpRequest
+		freed.NetDownFree += v.NetDownRequest
+	}
+	return freed
+}
+
+// canScheduleMultiIgnoringAlpha reports whether pod fits in stats on raw
+// free capacity alone, across every resource it requests, without
+// canScheduleMulti's additional alpha utilization-ceiling check. Victim
+// selection uses this so adding one more victim is judged purely on
+// whether it frees enough room; attemptPreemption separately re-validates
+// the chosen set against canScheduleMulti's full alpha-aware check before
+// committing to it.
+func canScheduleMultiIgnoringAlpha(pod PodRequest, stats NodeStats) bool {
+	for _, r := range podResourceUsages(pod, stats) {
+		if r.req == 0 {
+			continue
+		}
+		if r.free < r.req {
+			return false
+		}
+	}
+	return true
+}
+
+// buildVictimSet greedily selects candidates (already sorted
+// ascending-priority) in order, skipping any that would violate a covering
+// PodDisruptionBudget unless allowViolating is set, in which case it's
+// selected anyway and recorded in violatingKeys. Selection stops as soon as
+// the running total would let pod fit.
+func buildVictimSet(candidates []RunningPod, stats NodeStats, pod PodRequest, budget *pdbBudgetTracker, allowViolating bool) (selected []RunningPod, violatingKeys map[string]bool, fits bool) {
+	violatingKeys = make(map[string]bool)
+	for _, candidate := range candidates {
+		safe, pdbName := budget.canEvict(candidate)
+		if !safe {
+			if !allowViolating {
+				log.Printf("Skipping candidate %s/%s: evicting it would violate PodDisruptionBudget %s", candidate.Namespace, candidate.Name, pdbName)
+				continue
+			}
+			violatingKeys[candidate.Namespace+"/"+candidate.Name] = true
+		}
+		budget.reserve(candidate)
+		selected = append(selected, candidate)
+		if fitsWithVictims(selected, stats, pod) {
+			return selected, violatingKeys, true
+		}
+	}
+	return selected, violatingKeys, fitsWithVictims(selected, stats, pod)
+}
+
+// selectVictims implements the generic scheduler's preemption algorithm: it
+// builds the set of runningPods whose priority is strictly lower than pod's,
+// then greedily removes them in ascending-priority order until pod would
+// fit, preferring a victim set that doesn't push any covering
+// PodDisruptionBudget below its desired healthy count and only falling back
+// to PDB-violating victims if no PDB-safe set is feasible (pod's priority
+// being system-critical skips the PDB check entirely, as before). It then
+// runs a reverse pass, re-adding victims in descending-priority order as
+// long as pod still fits without them, so the returned set is the minimal
+// one actually needed. ok is false if no feasible victim set exists;
+// violatesPDB reports whether the returned set contains a PDB-violating
+// victim.
+func selectVictims(client kubernetes.Interface, pod PodRequest, stats NodeStats, runningPods []RunningPod) (victims []RunningPod, violatesPDB bool, ok bool) {
+	var candidates []RunningPod
+	for _, rp := range runningPods {
+		if rp.Priority < pod.Priority {
+			candidates = append(candidates, rp)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, false, false
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Priority < candidates[j].Priority
+	})
+
+	forceMode := pod.Priority >= systemCriticalThreshold()
+
+	selected, violatingKeys, fits := buildVictimSet(candidates, stats, pod, newPDBBudgetTracker(client), forceMode)
+	if !fits && !forceMode {
+		// No PDB-safe-only set fits; retry allowing PDB-violating victims
+		// too, with a fresh budget simulation.
+		selected, violatingKeys, fits = buildVictimSet(candidates, stats, pod, newPDBBudgetTracker(client), true)
+	}
+	if !fits {
+		return nil, false, false
+	}
+
+	// Reverse pass: try excluding victims starting with the highest
+	// priority (the end of the ascending-sorted slice); keep the exclusion
+	// only if the pod still fits without it, so the final set is minimal.
+	kept := append([]RunningPod{}, selected...)
+	for i := len(selected) - 1; i >= 0; i-- {
+		trial := removeVictim(kept, selected[i])
+		if fitsWithVictims(trial, stats, pod) {
+			kept = trial
+		}
+	}
+
+	for _, v := range kept {
+		if violatingKeys[v.Namespace+"/"+v.Name] {
+			violatesPDB = true
+			break
+		}
+	}
+	return kept, violatesPDB, true
+}
+
+// removeVictim returns a copy of set with the first RunningPod matching
+// target's namespace/name removed.
+func removeVictim(set []RunningPod, target RunningPod) []RunningPod {
+	out := make([]RunningPod, 0, len(set))
+	removed := false
+	for _, v := range set {
+		if !removed && v.Namespace == target.Namespace && v.Name == target.Name {
+			removed = true
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// evictPod evicts a pod using the Kubernetes eviction API.
+func evictPod(client kubernetes.Interface, pod RunningPod) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+	return client.PolicyV1().Evictions(eviction.Namespace).Evict(context.Background(), eviction)
+}
+
+// evictPodForced deletes pod directly via the core API, bypassing the
+// eviction subresource (and therefore any PodDisruptionBudget check).
+func evictPodForced(client kubernetes.Interface, pod RunningPod) error {
+	return client.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+}
+
+// evictWithBackoff evicts pod via the eviction subresource (or, in force
+// mode, deletes it directly), retrying with exponential backoff if the API
+// server responds 429 Too Many Requests (e.g. because a PDB is already at
+// its limit from evictions outside this scheduler's view).
+func evictWithBackoff(client kubernetes.Interface, pod RunningPod, force bool) error {
+	const maxAttempts = 5
+	backoff := 200 * time.Millisecond
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var err error
+		if force {
+			err = evictPodForced(client, pod)
+		} else {
+			err = evictPod(client, pod)
+		}
+		if err == nil {
+			return nil
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return err
+		}
+		lastErr = err
+		log.Printf("Eviction of %s/%s throttled (429), retrying in %s", pod.Namespace, pod.Name, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return fmt.Errorf("giving up evicting %s/%s after %d attempts: %w", pod.Namespace, pod.Name, maxAttempts, lastErr)
+}
+
+// attemptPreemption looks for lower-priority pods on nodeName whose eviction
+// would free enough of every resource pod requests for it to fit in stats
+// (and pass canScheduleMulti's alpha check). When dryRun is set, it
+// only logs the plan and returns the victim set without calling the API.
+// ok is false if no feasible victim set exists (including when pod itself
+// has no running pods of lower priority to preempt).
+func attemptPreemption(client kubernetes.Interface, nodeName string, pod PodRequest, stats NodeStats, alpha float64, dryRun bool) (victims []RunningPod, ok bool, err error) {
+	runningPods, err := getRunningPods(client, nodeName)
+	if err != nil {
+		return nil, false, fmt.Errorf("listing running pods on node %s: %w", nodeName, err)
+	}
+
+	victims, violatesPDB, ok := selectVictims(client, pod, stats, runningPods)
+	if !ok {
+		return nil, false, nil
+	}
+
+	// selectVictims only checked raw freed capacity per dimension; re-run
+	// the real admission check (which also enforces alpha's utilization
+	// ceiling) against the post-eviction picture before committing to this
+	// victim set, so a node that still fails canScheduleMulti after
+	// eviction is rejected instead of evicted into anyway.
+	if !canScheduleMulti(pod, simulateStatsWithVictimsFreed(stats, victims), alpha) {
+		log.Printf("Preempting on node %s would free enough raw capacity but still fail the utilization ceiling; skipping", nodeName)
+		return nil, false, nil
+	}
+
+	forceMode := pod.Priority >= systemCriticalThreshold()
+	if dryRun {
+		for _, victim := range victims {
+			log.Printf("Dry run: would evict pod %s/%s from node %s (force=%v, violatesPDB=%v) to schedule Pod", victim.Namespace, victim.Name, nodeName, forceMode, violatesPDB)
+		}
+		return victims, true, nil
+	}
+
+	for _, victim := range victims {
+		if err := evictWithBackoff(client, victim, forceMode); err != nil {
+			return nil, false, fmt.Errorf("evicting %s/%s: %w", victim.Namespace, victim.Name, err)
+		}
+		log.Printf("Evicted pod %s/%s on node %s (force=%v)", victim.Namespace, victim.Name, nodeName, forceMode)
+	}
+	return victims, true, nil
+}
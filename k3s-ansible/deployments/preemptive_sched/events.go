@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+)
+
+// This file gives the scheduler a voice beyond log.Printf: Scheduled,
+// FailedScheduling, and Preempted Events on the Pod objects involved, plus
+// a PodScheduled=False condition on pods that can't be placed, matching
+// what kube-scheduler itself surfaces via `kubectl describe pod`.
+
+// newEventRecorder wires up a client-go EventRecorder that posts Events
+// attributed to the schedulerName component.
+func newEventRecorder(client kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedEventSink{client})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: schedulerName})
+}
+
+// typedEventSink adapts client.CoreV1().Events("") to record.EventSink
+// without importing the (larger) typed event client package just for this.
+type typedEventSink struct {
+	client kubernetes.Interface
+}
+
+func (s *typedEventSink) Create(event *v1.Event) (*v1.Event, error) {
+	return s.client.CoreV1().Events(event.Namespace).Create(context.Background(), event, metav1.CreateOptions{})
+}
+
+func (s *typedEventSink) Update(event *v1.Event) (*v1.Event, error) {
+	return s.client.CoreV1().Events(event.Namespace).Update(context.Background(), event, metav1.UpdateOptions{})
+}
+
+func (s *typedEventSink) Patch(event *v1.Event, data []byte) (*v1.Event, error) {
+	return s.client.CoreV1().Events(event.Namespace).Patch(context.Background(), event.Name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+}
+
+// formatFailureSummary renders a kube-scheduler-style FailedScheduling
+// message from the per-reason rejection counts classifyNodes accumulated,
+// e.g. "0/5 nodes are available: 3 Insufficient cpu, 2 untolerated taint."
+func formatFailureSummary(totalNodes int, reasons map[string]int) string {
+	if len(reasons) == 0 {
+		return fmt.Sprintf("0/%d nodes are available: no nodes matched.", totalNodes)
+	}
+	parts := make([]string, 0, len(reasons))
+	for reason, count := range reasons {
+		parts = append(parts, fmt.Sprintf("%d %s", count, reason))
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("0/%d nodes are available: %s.", totalNodes, strings.Join(parts, ", "))
+}
+
+// recordScheduled emits a Scheduled Event once pod has been bound to
+// nodeName.
+func recordScheduled(recorder record.EventRecorder, pod *v1.Pod, nodeName string) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(pod, v1.EventTypeNormal, "Scheduled", "Successfully assigned %s/%s to %s", pod.Namespace, pod.Name, nodeName)
+}
+
+// recordFailedScheduling emits a FailedScheduling Event on pod and sets its
+// PodScheduled condition to False, so kubectl describe pod surfaces why
+// even if no one is watching the scheduler's own logs. A nil recorder means
+// pod isn't a real API object worth reporting against (e.g. the CLI
+// diagnostic path's synthetic pod), so both steps are skipped.
+func recordFailedScheduling(client kubernetes.Interface, recorder record.EventRecorder, pod *v1.Pod, message string) {
+	if recorder == nil {
+		return
+	}
+	recorder.Event(pod, v1.EventTypeWarning, "FailedScheduling", message)
+	if err := setPodScheduledCondition(client, pod, v1.ConditionFalse, "Unschedulable", message); err != nil {
+		log.Printf("Warning: failed to set PodScheduled=False on pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+}
+
+// recordPreempted emits a Preempted Event on each victim, referencing the
+// preemptor pod and the node it's being evicted from.
+func recordPreempted(recorder record.EventRecorder, preemptor *v1.Pod, nodeName string, victims []RunningPod) {
+	if recorder == nil {
+		return
+	}
+	for _, victim := range victims {
+		ref := &v1.ObjectReference{Kind: "Pod", Namespace: victim.Namespace, Name: victim.Name, UID: victim.UID}
+		recorder.Eventf(ref, v1.EventTypeNormal, "Preempted", "Preempted by %s/%s to make room on node %s", preemptor.Namespace, preemptor.Name, nodeName)
+	}
+}
+
+// setPodScheduledCondition sets or updates pod's PodScheduled condition via
+// the status subresource, matching the patch pattern patchNominatedNodeName
+// uses for NominatedNodeName.
+func setPodScheduledCondition(client kubernetes.Interface, pod *v1.Pod, status v1.ConditionStatus, reason, message string) error {
+	updated := pod.DeepCopy()
+	now := metav1.Now()
+	condition := v1.PodCondition{
+		Type:               v1.PodScheduled,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: now,
+	}
+
+	found := false
+	for i, c := range updated.Status.Conditions {
+		if c.Type != v1.PodScheduled {
+			continue
+		}
+		if c.Status == status && c.Reason == reason {
+			return nil
+		}
+		updated.Status.Conditions[i] = condition
+		found = true
+		break
+	}
+	if !found {
+		updated.Status.Conditions = append(updated.Status.Conditions, condition)
+	}
+
+	_, err := client.CoreV1().Pods(updated.Namespace).UpdateStatus(context.Background(), updated, metav1.UpdateOptions{})
+	return err
+}
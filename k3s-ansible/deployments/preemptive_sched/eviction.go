@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// envOrDefault returns the value of the named environment variable, or def
+// if it is unset.
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// lookupNodeMetric finds nodeName's value in a Prometheus instance->value
+// map, matching the same "instance:9100, substring, or first available"
+// fallback order getNodeStats uses for its other queries.
+func lookupNodeMetric(metrics map[string]float64, nodeName string) (float64, bool) {
+	if v, ok := metrics[nodeName+":9100"]; ok {
+		return v, true
+	}
+	for instance, v := range metrics {
+		if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
+			return v, true
+		}
+	}
+	for _, v := range metrics {
+		return v, true
+	}
+	return 0, false
+}
+
+// populateEvictionStats fills in stats' eviction-signal fields via the same
+// node_exporter filesystem/process metrics kubelet's own eviction manager is
+// modeled on. Imagefs is assumed to share the root filesystem unless
+// EVICTION_IMAGEFS_MOUNTPOINT says otherwise, which is the common case for
+// clusters that don't split out a separate image filesystem.
+func populateEvictionStats(nodeName string, stats *NodeStats) {
+	rootMount := evictionMountpoint()
+	imageMount := evictionImageMountpoint()
+
+	if avail, total, ok := queryFSMetrics(nodeName, rootMount); ok {
+		stats.NodeFSAvailableBytes = avail
+		stats.NodeFSCapacityBytes = total
+	}
+	if avail, total, ok := queryFSMetrics(nodeName, imageMount); ok {
+		stats.ImageFSAvailableBytes = avail
+		stats.ImageFSCapacityBytes = total
+	}
+
+	if free, total, ok := queryINodeMetrics(nodeName, rootMount); ok {
+		stats.INodesFree = free
+		stats.INodesCapacity = total
+	}
+
+	if avail, total, ok := queryPIDMetrics(nodeName); ok {
+		stats.PIDsAvailable = avail
+		stats.PIDsCapacity = total
+	}
+}
+
+func queryFSMetrics(nodeName, mountpoint string) (avail, total float64, ok bool) {
+	availMetrics, err := queryPrometheus(fmt.Sprintf(`node_filesystem_avail_bytes{mountpoint="%s"}`, mountpoint))
+	if err != nil {
+		log.Printf("Warning: Failed to fetch filesystem available bytes for %s: %v", mountpoint, err)
+		return 0, 0, false
+	}
+	totalMetrics, err := queryPrometheus(fmt.Sprintf(`node_filesystem_size_bytes{mountpoint="%s"}`, mountpoint))
+	if err != nil {
+		log.Printf("Warning: Failed to fetch filesystem size bytes for %s: %v", mountpoint, err)
+		return 0, 0, false
+	}
+	a, aok := lookupNodeMetric(availMetrics, nodeName)
+	t, tok := lookupNodeMetric(totalMetrics, nodeName)
+	if !aok || !tok {
+		return 0, 0, false
+	}
+	return a, t, true
+}
+
+func queryINodeMetrics(nodeName, mountpoint string) (free, total float64, ok bool) {
+	freeMetrics, err := queryPrometheus(fmt.Sprintf(`node_filesystem_files_free{mountpoint="%s"}`, mountpoint))
+	if err != nil {
+		log.Printf("Warning: Failed to fetch free inodes for %s: %v", mountpoint, err)
+		return 0, 0, false
+	}
+	totalMetrics, err := queryPrometheus(fmt.Sprintf(`node_filesystem_files{mountpoint="%s"}`, mountpoint))
+	if err != nil {
+		log.Printf("Warning: Failed to fetch total inodes for %s: %v", mountpoint, err)
+		return 0, 0, false
+	}
+	f, fok := lookupNodeMetric(freeMetrics, nodeName)
+	t, tok := lookupNodeMetric(totalMetrics, nodeName)
+	if !fok || !tok {
+		return 0, 0, false
+	}
+	return f, t, true
+}
+
+func queryPIDMetrics(nodeName string) (available, capacity float64, ok bool) {
+	usedMetrics, err := queryPrometheus("node_processes_pids")
+	if err != nil {
+		log.Printf("Warning: Failed to fetch process count: %v", err)
+		return 0, 0, false
+	}
+	used, uok := lookupNodeMetric(usedMetrics, nodeName)
+	if !uok {
+		return 0, 0, false
+	}
+	// pid_max is rarely exported by node_exporter; fall back to the common
+	// Linux default rather than failing the whole signal.
+	capacity = pidMaxDefault()
+	return capacity - used, capacity, true
+}
+
+func evictionMountpoint() string {
+	return envOrDefault("EVICTION_NODEFS_MOUNTPOINT", "/")
+}
+
+func evictionImageMountpoint() string {
+	return envOrDefault("EVICTION_IMAGEFS_MOUNTPOINT", evictionMountpoint())
+}
+
+func pidMaxDefault() float64 {
+	return 4194304
+}
+
+// evictionSignal is a single kubelet-style eviction signal threshold, e.g.
+// the "500Mi" in "memory.available<500Mi" or the "10%" in
+// "nodefs.available<10%".
+type evictionSignal struct {
+	bytes   float64 // absolute threshold in bytes/count, used when isPct is false
+	percent float64 // threshold as a fraction (0-1) of capacity, used when isPct is true
+	isPct   bool
+}
+
+// satisfiedBy reports whether available (and, for percentage thresholds,
+// capacity) stays above this signal's threshold.
+func (s evictionSignal) satisfiedBy(available, capacity float64) bool {
+	if s.isPct {
+		if capacity <= 0 {
+			return true
+		}
+		return available/capacity >= s.percent
+	}
+	return available >= s.bytes
+}
+
+// EvictionThresholds mirrors kubelet's --eviction-hard/--eviction-soft
+// signal set: memory.available, nodefs.available, imagefs.available,
+// pid.available, and nodefs.inodesFree. A nil field means that signal isn't
+// configured and is never checked.
+type EvictionThresholds struct {
+	MemoryAvailable  *evictionSignal
+	NodeFSAvailable  *evictionSignal
+	ImageFSAvailable *evictionSignal
+	PIDAvailable     *evictionSignal
+	INodesFree       *evictionSignal
+}
+
+// parseEvictionThresholds parses a kubelet-style eviction signal list such as
+// "memory.available<500Mi,nodefs.available<10%,pid.available<1000". An empty
+// string returns a zero-value EvictionThresholds (nothing configured).
+func parseEvictionThresholds(spec string) (EvictionThresholds, error) {
+	var t EvictionThresholds
+	if strings.TrimSpace(spec) == "" {
+		return t, nil
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "<", 2)
+		if len(parts) != 2 {
+			return t, fmt.Errorf("invalid eviction signal %q, expected SIGNAL<VALUE", entry)
+		}
+		name := strings.TrimSpace(parts[0])
+		sig, err := parseEvictionSignalValue(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return t, fmt.Errorf("invalid value for signal %q: %w", name, err)
+		}
+
+		switch name {
+		case "memory.available":
+			t.MemoryAvailable = &sig
+		case "nodefs.available":
+			t.NodeFSAvailable = &sig
+		case "imagefs.available":
+			t.ImageFSAvailable = &sig
+		case "pid.available":
+			t.PIDAvailable = &sig
+		case "nodefs.inodesFree":
+			t.INodesFree = &sig
+		default:
+			return t, fmt.Errorf("unknown eviction signal %q", name)
+		}
+	}
+	return t, nil
+}
+
+// parseEvictionSignalValue parses a value like "500Mi", "10%", or "1000" into
+// an evictionSignal, following kubelet's eviction-threshold syntax.
+func parseEvictionSignalValue(val string) (evictionSignal, error) {
+	if strings.HasSuffix(val, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(val, "%"), 64)
+		if err != nil {
+			return evictionSignal{}, err
+		}
+		return evictionSignal{percent: pct / 100.0, isPct: true}, nil
+	}
+
+	unit := 1.0
+	numPart := val
+	switch {
+	case strings.HasSuffix(val, "Ki"):
+		unit, numPart = 1024, strings.TrimSuffix(val, "Ki")
+	case strings.HasSuffix(val, "Mi"):
+		unit, numPart = 1024*1024, strings.TrimSuffix(val, "Mi")
+	case strings.HasSuffix(val, "Gi"):
+		unit, numPart = 1024*1024*1024, strings.TrimSuffix(val, "Gi")
+	}
+	num, err := strconv.ParseFloat(numPart, 64)
+	if err != nil {
+		return evictionSignal{}, err
+	}
+	return evictionSignal{bytes: num * unit}, nil
+}
+
+// checkEvictionThresholds reports whether stats (after projecting podReq
+// onto it) stays clear of every hard threshold in t. A nil signal is
+// skipped. Crossing nodefs/imagefs/inodes triggers DiskPressure, memory
+// triggers MemoryPressure, and pid triggers PIDPressure, matching the
+// condition kubelet itself would report.
+func checkEvictionThresholds(t EvictionThresholds, podReq PodRequest, stats NodeStats) (ok bool, reason string) {
+	if t.MemoryAvailable != nil {
+		projected := stats.MemFree - podReq.Mem
+		if !t.MemoryAvailable.satisfiedBy(projected, stats.MemTotal) {
+			return false, "would cross memory.available eviction threshold (MemoryPressure)"
+		}
+	}
+	if t.NodeFSAvailable != nil && !t.NodeFSAvailable.satisfiedBy(stats.NodeFSAvailableBytes, stats.NodeFSCapacityBytes) {
+		return false, "node is at or below nodefs.available eviction threshold (DiskPressure)"
+	}
+	if t.ImageFSAvailable != nil && !t.ImageFSAvailable.satisfiedBy(stats.ImageFSAvailableBytes, stats.ImageFSCapacityBytes) {
+		return false, "node is at or below imagefs.available eviction threshold (DiskPressure)"
+	}
+	if t.PIDAvailable != nil && !t.PIDAvailable.satisfiedBy(stats.PIDsAvailable, stats.PIDsCapacity) {
+		return false, "node is at or below pid.available eviction threshold (PIDPressure)"
+	}
+	if t.INodesFree != nil && !t.INodesFree.satisfiedBy(stats.INodesFree, stats.INodesCapacity) {
+		return false, "node is at or below nodefs.inodesFree eviction threshold (DiskPressure)"
+	}
+	return true, ""
+}
+
+// nodeConditionBlocksScheduling reports whether node already carries a
+// DiskPressure, MemoryPressure, PIDPressure, or NetworkUnavailable
+// condition, in which case it should be rejected immediately regardless of
+// projected usage.
+func nodeConditionBlocksScheduling(node *v1.Node) (blocked bool, reason string) {
+	if node == nil {
+		return false, ""
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Status != v1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case v1.NodeDiskPressure, v1.NodeMemoryPressure, v1.NodePIDPressure, v1.NodeNetworkUnavailable:
+			return true, fmt.Sprintf("node condition %s is True", cond.Type)
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// This file lets operators delegate part of a scheduling decision to one or
+// more external HTTP services, mirroring upstream kube-scheduler's
+// Extender mechanism: after this scheduler's own Filter phase narrows the
+// node list, each configured extender's /filter endpoint gets a chance to
+// narrow it further, and each /prioritize endpoint contributes a weighted
+// score alongside this scheduler's own Score plugins. A /preempt endpoint
+// similarly lets an extender veto or trim the victim set before eviction.
+
+// ManagedResource names a resource an extender cares about. A pod that
+// doesn't request any non-IgnoredByScheduler ManagedResource can skip the
+// extender entirely, mirroring upstream's ExtenderManagedResource gate.
+type ManagedResource struct {
+	Name               string `json:"name"`
+	IgnoredByScheduler bool   `json:"ignoredByScheduler"`
+}
+
+// ExtenderConfig describes one HTTP scheduler extender, loaded from the
+// JSON file named by --extender-config.
+type ExtenderConfig struct {
+	URLPrefix          string            `json:"urlPrefix"`
+	FilterVerb         string            `json:"filterVerb"`
+	PrioritizeVerb     string            `json:"prioritizeVerb"`
+	PreemptVerb        string            `json:"preemptVerb"`
+	Weight             int               `json:"weight"`
+	NodeCacheCapable   bool              `json:"nodeCacheCapable"`
+	ManagedResources   []ManagedResource `json:"managedResources"`
+	Ignorable          bool              `json:"ignorable"`
+	HTTPTimeoutSeconds int               `json:"httpTimeoutSeconds"`
+}
+
+// LoadExtenderConfigs reads a list of ExtenderConfig from a JSON file. An
+// empty path returns (nil, nil) so --extender-config is optional.
+func LoadExtenderConfigs(path string) ([]ExtenderConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading extender config %s: %w", path, err)
+	}
+	var configs []ExtenderConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("parsing extender config %s: %w", path, err)
+	}
+	for i := range configs {
+		if configs[i].FilterVerb == "" {
+			configs[i].FilterVerb = "filter"
+		}
+		if configs[i].PrioritizeVerb == "" {
+			configs[i].PrioritizeVerb = "prioritize"
+		}
+		if configs[i].PreemptVerb == "" {
+			configs[i].PreemptVerb = "preempt"
+		}
+		if configs[i].Weight <= 0 {
+			configs[i].Weight = 1
+		}
+	}
+	return configs, nil
+}
+
+// handlesPod reports whether e should be consulted for pod at all.
+func (e ExtenderConfig) handlesPod(podReq PodRequest) bool {
+	if len(e.ManagedResources) == 0 {
+		return true
+	}
+	for _, res := range e.ManagedResources {
+		if res.IgnoredByScheduler {
+			continue
+		}
+		switch res.Name {
+		case "cpu":
+			if podReq.CPU > 0 {
+				return true
+			}
+		case "memory":
+			if podReq.Mem > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// httpClient returns an http.Client bounded by e.HTTPTimeoutSeconds (10s if
+// unset), so a hung extender can't stall scheduling indefinitely.
+func (e ExtenderConfig) httpClient() *http.Client {
+	timeout := time.Duration(e.HTTPTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// post JSON-encodes body, POSTs it to e.URLPrefix/verb, and JSON-decodes
+// the response into out (skipped if out is nil).
+func (e ExtenderConfig) post(verb string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshaling request for extender %s: %w", e.URLPrefix, err)
+	}
+	url := strings.TrimRight(e.URLPrefix, "/") + "/" + verb
+	resp, err := e.httpClient().Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("calling extender %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response from extender %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("extender %s returned HTTP %d: %s", url, resp.StatusCode, respBody)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("parsing response from extender %s: %w", url, err)
+	}
+	return nil
+}
+
+// ExtenderFilterArgs is POSTed to an extender's /filter endpoint.
+type ExtenderFilterArgs struct {
+	Pod            *v1.Pod              `json:"pod"`
+	Nodes          []v1.Node            `json:"nodes"`
+	NodeNameToInfo map[string]NodeStats `json:"nodeNameToInfo"`
+}
+
+// ExtenderFilterResult is returned by an extender's /filter endpoint: the
+// subset of node names it still considers feasible, plus why it rejected
+// the rest. Error, if non-empty, means the extender itself failed (as
+// opposed to feasibly rejecting every node).
+type ExtenderFilterResult struct {
+	NodeNames   []string          `json:"nodeNames"`
+	FailedNodes map[string]string `json:"failedNodes"`
+	Error       string            `json:"error"`
+}
+
+// ExtenderPrioritizeArgs is POSTed to an extender's /prioritize endpoint.
+type ExtenderPrioritizeArgs struct {
+	Pod   *v1.Pod   `json:"pod"`
+	Nodes []v1.Node `json:"nodes"`
+}
+
+// HostPriority is one node's extender-assigned score, 0-10 like upstream
+// kube-scheduler extenders (scaled up to a SchedulingConfig's MaxScore
+// before being weighted and added into the node's total).
+type HostPriority struct {
+	Host  string `json:"host"`
+	Score int64  `json:"score"`
+}
+
+// ExtenderPreemptionArgs is POSTed to an extender's /preempt endpoint,
+// giving it a chance to veto or trim the victim set the in-process
+// preemption algorithm chose for each candidate node.
+type ExtenderPreemptionArgs struct {
+	Pod               *v1.Pod                 `json:"pod"`
+	NodeNameToVictims map[string][]RunningPod `json:"nodeNameToVictims"`
+}
+
+// ExtenderPreemptionResult is returned by an extender's /preempt endpoint:
+// the (possibly trimmed) victim set per node it's willing to allow.
+// Omitting a node drops it from preemption consideration entirely.
+type ExtenderPreemptionResult struct {
+	NodeNameToVictims map[string][]RunningPod `json:"nodeNameToVictims"`
+}
+
+// runExtenderFilters calls /filter on every extender that handles pod, in
+// order, intersecting each one's feasible node list into the running set,
+// matching upstream's "extenders narrow, never widen" semantics. An
+// extender marked Ignorable is skipped (with a log) on HTTP failure
+// instead of failing the whole scheduling attempt.
+func runExtenderFilters(extenders []ExtenderConfig, pod *v1.Pod, podReq PodRequest, candidates []schedulableCandidate) ([]schedulableCandidate, error) {
+	for _, ext := range extenders {
+		if len(candidates) == 0 || !ext.handlesPod(podReq) {
+			continue
+		}
+
+		nodes := make([]v1.Node, len(candidates))
+		nodeNameToInfo := make(map[string]NodeStats, len(candidates))
+		for i, c := range candidates {
+			nodes[i] = *c.node
+			nodeNameToInfo[c.node.Name] = c.stats
+		}
+
+		var result ExtenderFilterResult
+		if err := ext.post(ext.FilterVerb, ExtenderFilterArgs{Pod: pod, Nodes: nodes, NodeNameToInfo: nodeNameToInfo}, &result); err != nil {
+			if ext.Ignorable {
+				log.Printf("Warning: ignorable extender %s /filter failed, skipping: %v", ext.URLPrefix, err)
+				continue
+			}
+			return nil, fmt.Errorf("extender %s /filter failed: %w", ext.URLPrefix, err)
+		}
+		if result.Error != "" {
+			if ext.Ignorable {
+				log.Printf("Warning: ignorable extender %s /filter returned an error, skipping: %s", ext.URLPrefix, result.Error)
+				continue
+			}
+			return nil, fmt.Errorf("extender %s /filter returned an error: %s", ext.URLPrefix, result.Error)
+		}
+
+		feasible := make(map[string]bool, len(result.NodeNames))
+		for _, name := range result.NodeNames {
+			feasible[name] = true
+		}
+		kept := candidates[:0:0]
+		for _, c := range candidates {
+			if feasible[c.node.Name] {
+				kept = append(kept, c)
+				continue
+			}
+			if reason, ok := result.FailedNodes[c.node.Name]; ok {
+				log.Printf("Node %s rejected by extender %s: %s", c.node.Name, ext.URLPrefix, reason)
+			}
+		}
+		candidates = kept
+	}
+	return candidates, nil
+}
+
+// runExtenderPrioritize calls /prioritize on every extender that handles
+// pod, combining each one's HostPriority scores (scaled to maxScore and
+// weighted by its Weight) into a single per-node addend for
+// pickBestCandidate to add on top of its own Score-plugin total.
+func runExtenderPrioritize(extenders []ExtenderConfig, pod *v1.Pod, podReq PodRequest, candidates []schedulableCandidate, maxScore int) (map[string]float64, error) {
+	totals := make(map[string]float64, len(candidates))
+	if len(candidates) == 0 {
+		return totals, nil
+	}
+
+	nodes := make([]v1.Node, len(candidates))
+	for i, c := range candidates {
+		nodes[i] = *c.node
+	}
+
+	for _, ext := range extenders {
+		if !ext.handlesPod(podReq) {
+			continue
+		}
+
+		var priorities []HostPriority
+		if err := ext.post(ext.PrioritizeVerb, ExtenderPrioritizeArgs{Pod: pod, Nodes: nodes}, &priorities); err != nil {
+			if ext.Ignorable {
+				log.Printf("Warning: ignorable extender %s /prioritize failed, skipping: %v", ext.URLPrefix, err)
+				continue
+			}
+			return nil, fmt.Errorf("extender %s /prioritize failed: %w", ext.URLPrefix, err)
+		}
+
+		for _, hp := range priorities {
+			scaled := float64(hp.Score) / 10.0 * float64(maxScore)
+			totals[hp.Host] += scaled * float64(ext.Weight)
+		}
+	}
+	return totals, nil
+}
+
+// callExtenderPreempt calls /preempt on every extender that handles pod, in
+// order, letting each one veto or trim nodeNameToVictims (e.g. an extender
+// managing a shared GPU might refuse to let a particular pod be evicted).
+// An extender marked Ignorable is skipped on HTTP failure instead of
+// failing preemption altogether.
+func callExtenderPreempt(extenders []ExtenderConfig, pod *v1.Pod, podReq PodRequest, nodeNameToVictims map[string][]RunningPod) (map[string][]RunningPod, error) {
+	for _, ext := range extenders {
+		if len(nodeNameToVictims) == 0 || !ext.handlesPod(podReq) {
+			continue
+		}
+
+		var result ExtenderPreemptionResult
+		if err := ext.post(ext.PreemptVerb, ExtenderPreemptionArgs{Pod: pod, NodeNameToVictims: nodeNameToVictims}, &result); err != nil {
+			if ext.Ignorable {
+				log.Printf("Warning: ignorable extender %s /preempt failed, skipping: %v", ext.URLPrefix, err)
+				continue
+			}
+			return nil, fmt.Errorf("extender %s /preempt failed: %w", ext.URLPrefix, err)
+		}
+		nodeNameToVictims = result.NodeNameToVictims
+	}
+	return nodeNameToVictims, nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// MetricsProvider gathers NodeStats for a node from some backing source
+// (Prometheus, the kubelet Summary API, or local gopsutil sampling).
+// Providers are composed into a chain by buildMetricsProvider so that a
+// source outage degrades to the next-best source instead of falling back to
+// hardcoded constants.
+type MetricsProvider interface {
+	// Name identifies the provider in logs.
+	Name() string
+	// NodeStats returns resource stats for node. client may be nil for
+	// providers that don't need the API server (e.g. gopsutil).
+	NodeStats(client kubernetes.Interface, node *v1.Node) (NodeStats, error)
+}
+
+// buildMetricsProvider selects a MetricsProvider chain based on --metrics-source.
+// The named source is tried first; the remaining built-in sources are appended
+// as fallbacks so a single source outage doesn't take scheduling down with it.
+func buildMetricsProvider(source string) MetricsProvider {
+	all := map[string]MetricsProvider{
+		"prometheus": prometheusMetricsProvider{},
+		"kubelet":    kubeletSummaryProvider{},
+		"gopsutil":   gopsutilMetricsProvider{},
+	}
+
+	primary, ok := all[source]
+	if !ok {
+		log.Printf("Warning: unknown metrics source %q, defaulting to prometheus", source)
+		source = "prometheus"
+		primary = all["prometheus"]
+	}
+
+	chain := []MetricsProvider{primary}
+	for _, name := range []string{"prometheus", "kubelet", "gopsutil"} {
+		if name != source {
+			chain = append(chain, all[name])
+		}
+	}
+	return metricsProviderChain{providers: chain}
+}
+
+// metricsProviderChain tries each provider in order, falling back to the
+// next one on error instead of propagating the failure.
+type metricsProviderChain struct {
+	providers []MetricsProvider
+}
+
+func (c metricsProviderChain) Name() string {
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, "->")
+}
+
+func (c metricsProviderChain) NodeStats(client kubernetes.Interface, node *v1.Node) (NodeStats, error) {
+	var lastErr error
+	for _, p := range c.providers {
+		stats, err := p.NodeStats(client, node)
+		if err == nil {
+			return stats, nil
+		}
+		log.Printf("Warning: metrics provider %s failed for node %s: %v", p.Name(), node.Name, err)
+		lastErr = err
+	}
+	return NodeStats{}, fmt.Errorf("all metrics providers failed, last error: %w", lastErr)
+}
+
+// allocatableStats derives CPU/memory totals from the node's own
+// Status.Allocatable instead of the hardcoded 6.0/8GB constants the
+// Prometheus-only getNodeStats used to fall back to.
+func allocatableStats(node *v1.Node) (cpuTotal, memTotal float64) {
+	cpuQty, ok := node.Status.Allocatable[v1.ResourceCPU]
+	if !ok {
+		cpuQty = resource.MustParse("1")
+	}
+	memQty, ok := node.Status.Allocatable[v1.ResourceMemory]
+	if !ok {
+		memQty = resource.MustParse("1Gi")
+	}
+	return float64(cpuQty.MilliValue()) / 1000.0, float64(memQty.Value())
+}
+
+// prometheusMetricsProvider is the original behavior: usage comes from
+// Prometheus queries, but totals now come from node.Status.Allocatable
+// rather than stubbed constants.
+type prometheusMetricsProvider struct{}
+
+func (prometheusMetricsProvider) Name() string { return "prometheus" }
+
+func (prometheusMetricsProvider) NodeStats(_ kubernetes.Interface, node *v1.Node) (NodeStats, error) {
+	return getNodeStats(node)
+}
+
+// kubeletSummaryProvider reads each node's authoritative /stats/summary
+// directly from its kubelet (proxied through the apiserver), avoiding any
+// dependency on node_exporter or Prometheus.
+type kubeletSummaryProvider struct{}
+
+func (kubeletSummaryProvider) Name() string { return "kubelet" }
+
+// summaryResponse mirrors the subset of stats/v1alpha1.Summary this provider
+// needs from the kubelet Summary API.
+type summaryResponse struct {
+	Node struct {
+		CPU struct {
+			UsageNanoCores *uint64 `json:"usageNanoCores"`
+		} `json:"cpu"`
+		Memory struct {
+			AvailableBytes *uint64 `json:"availableBytes"`
+		} `json:"memory"`
+		Fs struct {
+			CapacityBytes *uint64 `json:"capacityBytes"`
+			UsedBytes     *uint64 `json:"usedBytes"`
+		} `json:"fs"`
+		Network struct {
+			Interfaces []struct {
+				RxBytes *uint64 `json:"rxBytes"`
+				TxBytes *uint64 `json:"txBytes"`
+			} `json:"interfaces"`
+		} `json:"network"`
+	} `json:"node"`
+}
+
+func (kubeletSummaryProvider) NodeStats(client kubernetes.Interface, node *v1.Node) (NodeStats, error) {
+	if client == nil {
+		return NodeStats{}, fmt.Errorf("kubelet summary provider requires a Kubernetes client")
+	}
+
+	raw, err := client.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", node.Name, "proxy/stats/summary").
+		DoRaw(context.Background())
+	if err != nil {
+		return NodeStats{}, fmt.Errorf("error fetching kubelet summary for %s: %w", node.Name, err)
+	}
+
+	var summary summaryResponse
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return NodeStats{}, fmt.Errorf("error parsing kubelet summary for %s: %w", node.Name, err)
+	}
+
+	cpuTotal, memTotal := allocatableStats(node)
+	var stats NodeStats
+	stats.CPUTotal = cpuTotal
+	stats.MemTotal = memTotal
+
+	if summary.Node.CPU.UsageNanoCores != nil {
+		cpuUsage := float64(*summary.Node.CPU.UsageNanoCores) / 1e9
+		stats.CPUFree = cpuTotal - cpuUsage
+	} else {
+		stats.CPUFree = cpuTotal
+	}
+
+	if summary.Node.Memory.AvailableBytes != nil {
+		stats.MemFree = float64(*summary.Node.Memory.AvailableBytes)
+	} else {
+		stats.MemFree = memTotal
+	}
+
+	if summary.Node.Fs.CapacityBytes != nil && summary.Node.Fs.UsedBytes != nil {
+		stats.DiskReadTotal = float64(*summary.Node.Fs.CapacityBytes)
+		stats.DiskReadFree = stats.DiskReadTotal - float64(*summary.Node.Fs.UsedBytes)
+		stats.DiskWriteTotal = stats.DiskReadTotal
+		stats.DiskWriteFree = stats.DiskReadFree
+		stats.NodeFSCapacityBytes = stats.DiskReadTotal
+		stats.NodeFSAvailableBytes = stats.DiskReadFree
+		stats.ImageFSCapacityBytes = stats.DiskReadTotal
+		stats.ImageFSAvailableBytes = stats.DiskReadFree
+	}
+
+	var rxTotal, txTotal uint64
+	for _, iface := range summary.Node.Network.Interfaces {
+		if iface.RxBytes != nil {
+			rxTotal += *iface.RxBytes
+		}
+		if iface.TxBytes != nil {
+			txTotal += *iface.TxBytes
+		}
+	}
+	// The Summary API reports cumulative bytes, not a capacity; without a
+	// capacity signal we report demand as usage against an estimated 1Gbps
+	// link (125 MiB/s). getNodeStats' own netSpeed fallback is a different
+	// value.
+	const assumedNetCapacity = 125 * 1024 * 1024
+	stats.NetUpTotal = assumedNetCapacity
+	stats.NetUpFree = assumedNetCapacity - float64(txTotal)
+	stats.NetDownTotal = assumedNetCapacity
+	stats.NetDownFree = assumedNetCapacity - float64(rxTotal)
+
+	return stats, nil
+}
+
+// gopsutilMetricsProvider samples the local machine directly via gopsutil,
+// for single-node/dev mode where neither Prometheus nor a remote kubelet
+// Summary API is reachable. It only produces meaningful results when run on
+// the node being evaluated.
+type gopsutilMetricsProvider struct{}
+
+func (gopsutilMetricsProvider) Name() string { return "gopsutil" }
+
+func (gopsutilMetricsProvider) NodeStats(_ kubernetes.Interface, node *v1.Node) (NodeStats, error) {
+	var stats NodeStats
+
+	cpuTotal, memTotal := allocatableStats(node)
+	stats.CPUTotal = cpuTotal
+	stats.MemTotal = memTotal
+
+	percents, err := cpu.Percent(time.Second, false)
+	if err != nil || len(percents) == 0 {
+		return NodeStats{}, fmt.Errorf("error sampling local CPU usage: %w", err)
+	}
+	stats.CPUFree = cpuTotal * (1 - percents[0]/100.0)
+
+	vmem, err := mem.VirtualMemory()
+	if err != nil {
+		return NodeStats{}, fmt.Errorf("error sampling local memory usage: %w", err)
+	}
+	stats.MemFree = float64(vmem.Available)
+
+	ioBefore, err := disk.IOCounters()
+	if err != nil {
+		return NodeStats{}, fmt.Errorf("error sampling local disk IO: %w", err)
+	}
+	var readBytes, writeBytes uint64
+	for _, c := range ioBefore {
+		readBytes += c.ReadBytes
+		writeBytes += c.WriteBytes
+	}
+	stats.DiskReadTotal = diskCapacityHint()
+	stats.DiskReadFree = stats.DiskReadTotal - float64(readBytes)
+	stats.DiskWriteTotal = diskCapacityHint()
+	stats.DiskWriteFree = stats.DiskWriteTotal - float64(writeBytes)
+
+	netCounters, err := net.IOCounters(false)
+	if err != nil || len(netCounters) == 0 {
+		return NodeStats{}, fmt.Errorf("error sampling local network IO: %w", err)
+	}
+	const assumedNetCapacity = 125 * 1024 * 1024
+	stats.NetUpTotal = assumedNetCapacity
+	stats.NetUpFree = assumedNetCapacity - float64(netCounters[0].BytesSent)
+	stats.NetDownTotal = assumedNetCapacity
+	stats.NetDownFree = assumedNetCapacity - float64(netCounters[0].BytesRecv)
+
+	return stats, nil
+}
+
+// diskCapacityHint returns a rough disk throughput capacity for the local
+// machine, configurable for environments where the default doesn't apply.
+func diskCapacityHint() float64 {
+	if val := os.Getenv("GOPSUTIL_DISK_CAPACITY_BYTES"); val != "" {
+		if parsed, err := strconv.ParseFloat(val, 64); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return 100 * 1024 * 1024
+}
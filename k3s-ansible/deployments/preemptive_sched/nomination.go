@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nomination records that scheduleOrNominate has already evicted victims on
+// nodeName on behalf of a pod and is waiting for them to actually leave,
+// mirroring the kube-scheduler pattern of nominating a node before a pod is
+// actually bound to it. expiresAt bounds how long a stale nomination (e.g.
+// its pod was deleted, or the victims never left) is kept around.
+type nomination struct {
+	nodeName  string
+	victims   []RunningPod
+	expiresAt time.Time
+}
+
+// nominationTracker is a UID-keyed, concurrency-safe store of in-flight
+// nominations, shared across podController workers.
+type nominationTracker struct {
+	mu          sync.Mutex
+	nominations map[types.UID]nomination
+}
+
+func newNominationTracker() *nominationTracker {
+	return &nominationTracker{nominations: make(map[types.UID]nomination)}
+}
+
+// Set records a nomination for uid, valid until ttl elapses.
+func (t *nominationTracker) Set(uid types.UID, nodeName string, victims []RunningPod, ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nominations[uid] = nomination{nodeName: nodeName, victims: victims, expiresAt: time.Now().Add(ttl)}
+}
+
+// Get returns the nomination for uid, if any. An expired nomination is
+// dropped and reported as absent.
+func (t *nominationTracker) Get(uid types.UID) (nomination, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, ok := t.nominations[uid]
+	if !ok {
+		return nomination{}, false
+	}
+	if time.Now().After(n.expiresAt) {
+		delete(t.nominations, uid)
+		return nomination{}, false
+	}
+	return n, true
+}
+
+// Clear removes any nomination held for uid.
+func (t *nominationTracker) Clear(uid types.UID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.nominations, uid)
+}
+
+// patchNominatedNodeName sets pod.Status.NominatedNodeName to nodeName via
+// the status subresource, so other tooling (and a human running kubectl
+// describe) can see where this pod is headed while its preemption victims
+// are still terminating.
+func patchNominatedNodeName(client kubernetes.Interface, pod *v1.Pod, nodeName string) error {
+	updated := pod.DeepCopy()
+	updated.Status.NominatedNodeName = nodeName
+	_, err := client.CoreV1().Pods(updated.Namespace).UpdateStatus(context.Background(), updated, metav1.UpdateOptions{})
+	return err
+}
+
+// allVictimsGone is a single, non-blocking pass over victims, unlike
+// waitForVictimsGone's polling loop: it's meant to be called once per
+// processPod invocation so a pending nomination can be requeued with
+// backoff instead of parking a worker goroutine.
+func allVictimsGone(client kubernetes.Interface, victims []RunningPod) (gone bool, stillPresent string) {
+	for _, v := range victims {
+		_, err := client.CoreV1().Pods(v.Namespace).Get(context.Background(), v.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		return false, v.Namespace + "/" + v.Name
+	}
+	return true, ""
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Metrics exposed on /metrics for the pod controller, mirroring the
+// extender's own metrics.go registration style.
+var (
+	schedulingAttemptDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "preemptive_sched_attempt_duration_seconds",
+		Help: "Time spent attempting to schedule a single pod, from workqueue pop to bind/requeue.",
+	}, []string{"result"})
+	schedulingQueueLength = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "preemptive_sched_queue_length",
+		Help: "Number of pods currently waiting in the priority-fair scheduling queue.",
+	})
+	podsScheduledTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "preemptive_sched_pods_scheduled_total",
+		Help: "Number of pods successfully bound to a node, by namespace.",
+	}, []string{"namespace"})
+)
+
+func init() {
+	prometheus.MustRegister(schedulingAttemptDuration, schedulingQueueLength, podsScheduledTotal)
+}
+
+// schedulerName is the spec.schedulerName this controller claims pods for.
+const schedulerName = "preemptive-scheduler"
+
+// queueItem is one pod waiting to be scheduled.
+type queueItem struct {
+	key       string // namespace/name
+	namespace string
+	priority  int32
+	addedAt   time.Time
+}
+
+// namespaceHeap is a min-heap of queueItems ordered so Pop returns the
+// highest-priority (then oldest) item first; it backs one namespace's
+// slice of pending pods inside priorityFairQueue.
+type namespaceHeap []*queueItem
+
+func (h namespaceHeap) Len() int { return len(h) }
+func (h namespaceHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].addedAt.Before(h[j].addedAt)
+}
+func (h namespaceHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *namespaceHeap) Push(x interface{}) {
+	*h = append(*h, x.(*queueItem))
+}
+func (h *namespaceHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// priorityFairQueue is a workqueue.RateLimitingInterface-shaped queue that
+// pops the highest-priority pod first within a namespace, but round-robins
+// across namespaces so one tenant flooding the queue with high-priority
+// pods can't starve every other namespace's pods indefinitely.
+type priorityFairQueue struct {
+	mu sync.Mutex
+
+	// byNamespace holds each namespace's pending items as a priority heap.
+	byNamespace map[string]*namespaceHeap
+	// order is the round-robin rotation of namespaces with pending work.
+	order []string
+	// inQueue dedupes: a key already queued or being processed is not
+	// re-added. Unlike workqueue, an Add that arrives while the key is being
+	// processed is dropped, not replayed on Done.
+	inQueue     map[string]bool
+	processing  map[string]bool
+	rateLimiter workqueue.RateLimiter
+
+	cond     *sync.Cond
+	shutdown bool
+}
+
+func newPriorityFairQueue() *priorityFairQueue {
+	q := &priorityFairQueue{
+		byNamespace: make(map[string]*namespaceHeap),
+		inQueue:     make(map[string]bool),
+		processing:  make(map[string]bool),
+		rateLimiter: workqueue.DefaultControllerRateLimiter(),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Add enqueues namespace/name with priority, unless it's already queued or
+// currently being processed, in which case the call has no effect; the key
+// is only queued again by an Add made after Done.
+func (q *priorityFairQueue) Add(namespace, name string, priority int32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := namespace + "/" + name
+	if q.inQueue[key] || q.processing[key] {
+		return
+	}
+	q.inQueue[key] = true
+
+	h, ok := q.byNamespace[namespace]
+	if !ok {
+		h = &namespaceHeap{}
+		q.byNamespace[namespace] = h
+		q.order = append(q.order, namespace)
+	}
+	heap.Push(h, &queueItem{key: key, namespace: namespace, priority: priority, addedAt: time.Now()})
+	schedulingQueueLength.Set(float64(q.len()))
+	q.cond.Signal()
+}
+
+// AddRateLimited re-enqueues key after workqueue's configured backoff,
+// for a scheduling attempt that failed and should be retried rather than
+// dropped.
+func (q *priorityFairQueue) AddRateLimited(namespace, name string, priority int32) {
+	key := namespace + "/" + name
+	delay := q.rateLimiter.When(key)
+	time.AfterFunc(delay, func() { q.Add(namespace, name, priority) })
+}
+
+// Forget resets key's rate-limiter backoff, called once a pod has been
+// scheduled successfully or abandoned.
+func (q *priorityFairQueue) Forget(namespace, name string) {
+	q.rateLimiter.Forget(namespace + "/" + name)
+}
+
+// Get blocks until a pod is available and returns it, popping the
+// highest-priority item from the next namespace in round-robin order.
+// shutdown is true once ShutDown has been called and the queue has
+// drained.
+func (q *priorityFairQueue) Get() (namespace, name string, shutdown bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for !q.shutdown && q.len() == 0 {
+		q.cond.Wait()
+	}
+	if q.shutdown && q.len() == 0 {
+		return "", "", true
+	}
+
+	for i := 0; i < len(q.order); i++ {
+		ns := q.order[0]
+		q.order = append(q.order[1:], ns)
+
+		h := q.byNamespace[ns]
+		if h == nil || h.Len() == 0 {
+			continue
+		}
+		item := heap.Pop(h).(*queueItem)
+		if h.Len() == 0 {
+			delete(q.byNamespace, ns)
+			q.removeFromOrderLocked(ns)
+		}
+		delete(q.inQueue, item.key)
+		q.processing[item.key] = true
+		schedulingQueueLength.Set(float64(q.len()))
+		return item.namespace, item.key[len(item.namespace)+1:], false
+	}
+	// Unreachable given the len()==0 wait above, but keeps the compiler happy.
+	return "", "", false
+}
+
+// removeFromOrderLocked drops ns from the round-robin rotation. Callers
+// must hold q.mu.
+func (q *priorityFairQueue) removeFromOrderLocked(ns string) {
+	for i, v := range q.order {
+		if v == ns {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			return
+		}
+	}
+}
+
+// Done marks namespace/name as finished processing, allowing a subsequent
+// Add for the same key to take effect.
+func (q *priorityFairQueue) Done(namespace, name string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.processing, namespace+"/"+name)
+}
+
+// len returns the total number of items queued across all namespaces.
+// Callers must hold q.mu.
+func (q *priorityFairQueue) len() int {
+	total := 0
+	for _, h := range q.byNamespace {
+		total += h.Len()
+	}
+	return total
+}
+
+// ShutDown stops Get from blocking once the queue has drained.
+func (q *priorityFairQueue) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutdown = true
+	q.cond.Broadcast()
+}
+
+// podController replaces the old watchForUnscheduledPods polling loop with
+// a shared-informer/workqueue pipeline: the informer watches pods claiming
+// this scheduler and feeds their keys into a priorityFairQueue, which N
+// worker goroutines drain in parallel.
+type podController struct {
+	client             kubernetes.Interface
+	informer           cache.SharedIndexInformer
+	queue              *priorityFairQueue
+	schedCache         *SchedulerCache
+	alpha              float64
+	enablePreemption   bool
+	evictionThresholds EvictionThresholds
+	workerCount        int
+	graceTimeout       time.Duration
+	schedulingConfig   *SchedulingConfig
+	filterPlugins      []FilterPlugin
+	scorePlugins       []ScorePlugin
+	extenders          []ExtenderConfig
+	recorder           record.EventRecorder
+	nominations        *nominationTracker
+	nominationTTL      time.Duration
+}
+
+// newPodController builds a podController watching Pods with
+// spec.schedulerName=preemptive-scheduler across all namespaces. schedCache
+// must already be constructed; pc.Run starts its Run alongside this
+// controller's pod informer, so both informer pairs sync concurrently.
+func newPodController(client kubernetes.Interface, schedCache *SchedulerCache, alpha float64, enablePreemption bool, thresholds EvictionThresholds, workerCount int, graceTimeout time.Duration, schedulingConfig *SchedulingConfig, filterPlugins []FilterPlugin, scorePlugins []ScorePlugin, extenders []ExtenderConfig, nominationTTL time.Duration) *podController {
+	pc := &podController{
+		client:             client,
+		queue:              newPriorityFairQueue(),
+		schedCache:         schedCache,
+		alpha:              alpha,
+		enablePreemption:   enablePreemption,
+		evictionThresholds: thresholds,
+		workerCount:        workerCount,
+		graceTimeout:       graceTimeout,
+		schedulingConfig:   schedulingConfig,
+		filterPlugins:      filterPlugins,
+		scorePlugins:       scorePlugins,
+		extenders:          extenders,
+		recorder:           newEventRecorder(client),
+		nominations:        newNominationTracker(),
+		nominationTTL:      nominationTTL,
+	}
+
+	selector := fields.OneTermEqualSelector("spec.schedulerName", schedulerName).String()
+
+	pc.informer = cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = selector
+				return client.CoreV1().Pods(metav1.NamespaceAll).List(context.Background(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = selector
+				return client.CoreV1().Pods(metav1.NamespaceAll).Watch(context.Background(), options)
+			},
+		},
+		&v1.Pod{},
+		30*time.Second,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+
+	pc.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    pc.enqueueIfUnscheduled,
+		UpdateFunc: func(_, newObj interface{}) { pc.enqueueIfUnscheduled(newObj) },
+	})
+
+	return pc
+}
+
+// enqueueIfUnscheduled adds obj to the queue if it's a pod that still
+// needs a node and isn't being deleted.
+func (pc *podController) enqueueIfUnscheduled(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil {
+		return
+	}
+	priority := int32(0)
+	if pod.Spec.Priority != nil {
+		priority = *pod.Spec.Priority
+	}
+	pc.queue.Add(pod.Namespace, pod.Name, priority)
+}
+
+// Run starts the pod-queue informer, the SchedulerCache's own Node/Pod
+// informers and metrics refresher, and workerCount worker goroutines,
+// blocking until stopCh is closed.
+func (pc *podController) Run(stopCh <-chan struct{}) {
+	go pc.schedCache.Run(stopCh)
+	go pc.informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, pc.informer.HasSynced) {
+		log.Println("Error: timed out waiting for pod informer cache to sync")
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < pc.workerCount; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			wait.Until(func() { pc.runWorker(workerID) }, time.Second, stopCh)
+		}(i)
+	}
+
+	<-stopCh
+	pc.queue.ShutDown()
+	wg.Wait()
+}
+
+// runWorker pops one pod at a time from the priority-fair queue and
+// attempts to schedule it until the queue shuts down.
+func (pc *podController) runWorker(workerID int) {
+	for {
+		namespace, name, shutdown := pc.queue.Get()
+		if shutdown {
+			return
+		}
+		pc.processPod(workerID, namespace, name)
+	}
+}
+
+// bindNominated is the fast path for a pod that already has an outstanding
+// nomination: it returns the nominated node's name once nom's victims have
+// actually left and the node still passes the Filter phase, "" (with no
+// error) if the victims haven't left yet, and resets the nomination and
+// returns "" if a higher-priority pod raced in and took the freed capacity
+// in the meantime, so the next call falls back to a full scheduling attempt.
+func (pc *podController) bindNominated(workerID int, pod *v1.Pod, podReq PodRequest, nom nomination) (string, error) {
+	gone, stillPresent := allVictimsGone(pc.client, nom.victims)
+	if !gone {
+		log.Printf("Worker %d: nominated node %s for pod %s/%s still waiting on victim %s", workerID, nom.nodeName, pod.Namespace, pod.Name, stillPresent)
+		return "", nil
+	}
+
+	ok, err := nodeStillFits(pc.schedCache, nom.nodeName, pod, podReq, pc.evictionThresholds, pc.filterPlugins)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		pc.nominations.Clear(pod.UID)
+		return "", nil
+	}
+
+	pc.nominations.Clear(pod.UID)
+	return nom.nodeName, nil
+}
+
+// processPod attempts to schedule namespace/name, recording its outcome
+// as scheduling-attempt latency and, when finding or binding a node fails,
+// requeuing it with backoff rather than dropping it. A pod that can't be
+// fetched is not requeued.
+func (pc *podController) processPod(workerID int, namespace, name string) {
+	start := time.Now()
+	result := "error"
+	defer func() {
+		pc.queue.Done(namespace, name)
+		schedulingAttemptDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	}()
+
+	pod, err := pc.client.CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		log.Printf("Worker %d: error fetching pod %s/%s: %v", workerID, namespace, name, err)
+		result = "fetch-error"
+		return
+	}
+	if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil {
+		result = "skipped"
+		pc.queue.Forget(namespace, name)
+		return
+	}
+
+	priority := int32(0)
+	if pod.Spec.Priority != nil {
+		priority = *pod.Spec.Priority
+	}
+
+	podReq := extractPodRequirements(pod)
+
+	var nodeName string
+	if nom, ok := pc.nominations.Get(pod.UID); ok {
+		nodeName, err = pc.bindNominated(workerID, pod, podReq, nom)
+		if err != nil {
+			log.Printf("Worker %d: error fast-pathing nominated pod %s/%s: %v", workerID, namespace, name, err)
+			result = "find-node-error"
+			pc.queue.AddRateLimited(namespace, name, priority)
+			return
+		}
+		if nodeName == "" {
+			// Victims haven't left yet, or the node no longer fits and the
+			// nomination was reset; either way retry shortly.
+			result = "nominated-pending"
+			pc.queue.AddRateLimited(namespace, name, priority)
+			return
+		}
+	} else {
+		var nominated bool
+		nodeName, nominated, err = scheduleOrNominate(pc.schedCache, pc.recorder, pod, podReq, pc.enablePreemption, pc.evictionThresholds, pc.schedulingConfig, pc.filterPlugins, pc.scorePlugins, pc.extenders, pc.nominations, pc.nominationTTL)
+		if err != nil {
+			log.Printf("Worker %d: error finding node for pod %s/%s: %v", workerID, namespace, name, err)
+			result = "find-node-error"
+			pc.queue.AddRateLimited(namespace, name, priority)
+			return
+		}
+		if nominated {
+			log.Printf("Worker %d: nominated node %s for pod %s/%s, awaiting preemption victims", workerID, nodeName, namespace, name)
+			result = "nominated"
+			pc.queue.AddRateLimited(namespace, name, priority)
+			return
+		}
+		if nodeName == "" {
+			log.Printf("Worker %d: no suitable node found for pod %s/%s, will retry", workerID, namespace, name)
+			result = "no-node"
+			pc.queue.AddRateLimited(namespace, name, priority)
+			return
+		}
+	}
+
+	if err := bindPodToNode(pc.client, pod, nodeName); err != nil {
+		log.Printf("Worker %d: error binding pod %s/%s to node %s: %v", workerID, namespace, name, nodeName, err)
+		result = "bind-error"
+		pc.queue.AddRateLimited(namespace, name, priority)
+		return
+	}
+
+	log.Printf("Worker %d: scheduled pod %s/%s on node %s", workerID, namespace, name, nodeName)
+	recordScheduled(pc.recorder, pod, nodeName)
+	pc.queue.Forget(namespace, name)
+	podsScheduledTotal.WithLabelValues(namespace).Inc()
+	result = "scheduled"
+}
@@ -10,14 +10,17 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	v1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -37,6 +40,19 @@ type NodeStats struct {
 	NetUpFree      float64 // Available network upload capacity.
 	NetDownTotal   float64 // Total network download capacity.
 	NetDownFree    float64 // Available network download capacity.
+
+	// Eviction-signal fields, mirroring what kubelet itself monitors to
+	// decide whether to start evicting pods. Zero values mean "unknown"
+	// unless a populated EvictionThresholds signal is actually checking
+	// them (see checkEvictionThresholds).
+	NodeFSAvailableBytes  float64
+	NodeFSCapacityBytes   float64
+	ImageFSAvailableBytes float64
+	ImageFSCapacityBytes  float64
+	PIDsAvailable         float64
+	PIDsCapacity          float64
+	INodesFree            float64
+	INodesCapacity        float64
 }
 
 // PodRequest represents a Pod's resource demands.
@@ -54,20 +70,35 @@ type PodRequest struct {
 type RunningPod struct {
 	Name       string
 	Namespace  string
+	UID        types.UID
 	CPURequest float64
 	MemRequest float64
 	// (Additional resource usage fields could be added here.)
 	Priority int
+	// Labels is carried along so selectVictims' pdbBudgetTracker can match
+	// the candidate against any PodDisruptionBudget covering it.
+	Labels map[string]string
+	// StartTime is when the pod began running (falls back to its creation
+	// timestamp), used to break ties between otherwise-equal victim sets in
+	// favor of the set whose earliest victim started first.
+	StartTime time.Time
 }
 
 // getNodeStats gathers metrics from Prometheus for a given node.
 // It queries for CPU and memory usage as before, and now also for disk and network.
 // For disk and network, it uses two queries each: one for current usage (via rate())
-// and one for the hardware capacity (assumed exposed by metrics).
-func getNodeStats(nodeName string) (NodeStats, error) {
+// and one for the hardware capacity (assumed exposed by metrics). The CPU
+// total comes from the node's own Status.Allocatable rather than stubbed
+// constants, so a Prometheus outage doesn't silently misreport capacity.
+// The memory total is node_memory_MemTotal_bytes when the memory usage
+// query finds the node, and Status.Allocatable when that query fails or
+// finds no sample, or the MemTotal query fails.
+func getNodeStats(node *v1.Node) (NodeStats, error) {
 	var stats NodeStats
 	var err error
 
+	nodeName := node.Name
+	cpuTotal, memTotal := allocatableStats(node)
 	log.Printf("Getting stats for node: %s", nodeName)
 
 	// ---------- CPU Metrics ----------
@@ -75,8 +106,8 @@ func getNodeStats(nodeName string) (NodeStats, error) {
 	cpuMetrics, err := queryPrometheus(cpuQuery)
 	if err != nil {
 		log.Printf("Warning: Failed to fetch CPU metrics: %v", err)
-		stats.CPUTotal = 6.0
-		stats.CPUFree = 2.0
+		stats.CPUTotal = cpuTotal
+		stats.CPUFree = cpuTotal
 	} else {
 		var cpuUsage float64
 		var nodeFound bool
@@ -100,10 +131,10 @@ func getNodeStats(nodeName string) (NodeStats, error) {
 			}
 		}
 		if !nodeFound {
-			stats.CPUTotal = 6.0
-			stats.CPUFree = 2.0
+			stats.CPUTotal = cpuTotal
+			stats.CPUFree = cpuTotal
 		} else {
-			stats.CPUTotal = 6.0
+			stats.CPUTotal = cpuTotal
 			stats.CPUFree = stats.CPUTotal - cpuUsage
 		}
 	}
@@ -113,8 +144,8 @@ func getNodeStats(nodeName string) (NodeStats, error) {
 	memMetrics, err := queryPrometheus(memQuery)
 	if err != nil {
 		log.Printf("Warning: Failed to fetch Memory metrics: %v", err)
-		stats.MemTotal = 8 * 1024 * 1024 * 1024 // 8GB
-		stats.MemFree = 4 * 1024 * 1024 * 1024  // 4GB
+		stats.MemTotal = memTotal
+		stats.MemFree = memTotal
 	} else {
 		var memUsed float64
 		var nodeFound bool
@@ -138,13 +169,13 @@ func getNodeStats(nodeName string) (NodeStats, error) {
 			}
 		}
 		if !nodeFound {
-			stats.MemTotal = 8 * 1024 * 1024 * 1024
-			stats.MemFree = 4 * 1024 * 1024 * 1024
+			stats.MemTotal = memTotal
+			stats.MemFree = memTotal
 		} else {
 			memTotalQuery := "node_memory_MemTotal_bytes"
 			memTotalMetrics, err := queryPrometheus(memTotalQuery)
 			if err != nil {
-				stats.MemTotal = 8 * 1024 * 1024 * 1024
+				stats.MemTotal = memTotal
 			} else {
 				if total, exists := memTotalMetrics[nodeName+":9100"]; exists {
 					stats.MemTotal = total
@@ -373,6 +404,8 @@ func getNodeStats(nodeName string) (NodeStats, error) {
 	stats.NetDownTotal = netSpeed
 	stats.NetDownFree = netSpeed - netDownUsage
 
+	populateEvictionStats(nodeName, &stats)
+
 	log.Printf("Node stats for %s: CPU Total: %.2f, CPU Free: %.2f, Mem Total: %.2f GB, Mem Free: %.2f GB",
 		nodeName, stats.CPUTotal, stats.CPUFree, stats.MemTotal/(1024*1024*1024), stats.MemFree/(1024*1024*1024))
 	log.Printf("Disk Read Total: %.2f, Free: %.2f; Disk Write Total: %.2f, Free: %.2f", stats.DiskReadTotal, stats.DiskReadFree, stats.DiskWriteTotal, stats.DiskWriteFree)
@@ -381,7 +414,14 @@ func getNodeStats(nodeName string) (NodeStats, error) {
 }
 
 // queryPrometheus queries the Prometheus API and returns a map of instance strings to float64 values.
+// When configurePrometheusFederation has been called (see --prometheus-urls),
+// it fans the query out across every configured endpoint with cluster-label
+// scoping and Thanos query params instead of hitting a single endpoint.
 func queryPrometheus(query string) (map[string]float64, error) {
+	if promFederation != nil {
+		return queryPrometheusFederated(query, promFederation)
+	}
+
 	prometheusURL := getPrometheusURL()
 	args := url.Values{}
 	args.Add("query", query)
@@ -485,12 +525,19 @@ func getRunningPods(client kubernetes.Interface, nodeName string) ([]RunningPod,
 		if pod.Spec.Priority != nil {
 			prio = int(*pod.Spec.Priority)
 		}
+		startTime := pod.CreationTimestamp.Time
+		if pod.Status.StartTime != nil {
+			startTime = pod.Status.StartTime.Time
+		}
 		runningPods = append(runningPods, RunningPod{
 			Name:       pod.Name,
 			Namespace:  pod.Namespace,
+			UID:        pod.UID,
 			CPURequest: cpuReq,
 			MemRequest: memReq,
 			Priority:   prio,
+			Labels:     pod.Labels,
+			StartTime:  startTime,
 		})
 	}
 	return runningPods, nil
@@ -507,8 +554,20 @@ func evictPod(client kubernetes.Interface, pod RunningPod) error {
 	return client.PolicyV1().Evictions(eviction.Namespace).Evict(context.Background(), eviction)
 }
 
-// canScheduleMulti checks if a node has sufficient capacity across all resources.
-func canScheduleMulti(pod PodRequest, stats NodeStats, alpha float64) bool {
+// canScheduleMulti checks if a node has sufficient capacity across all
+// resources, returning a short human-readable reason on rejection (e.g.
+// "Insufficient cpu") suitable for aggregating into a FailedScheduling
+// event message. node and thresholds may be the zero value (nil node,
+// empty thresholds) for callers that haven't wired up eviction-aware
+// scheduling yet.
+func canScheduleMulti(pod PodRequest, stats NodeStats, alpha float64, node *v1.Node, thresholds EvictionThresholds) (bool, string) {
+	if blocked, reason := nodeConditionBlocksScheduling(node); blocked {
+		return false, reason
+	}
+	if ok, reason := checkEvictionThresholds(thresholds, pod, stats); !ok {
+		return false, reason
+	}
+
 	resources := []struct {
 		free  float64
 		total float64
@@ -528,16 +587,14 @@ func canScheduleMulti(pod PodRequest, stats NodeStats, alpha float64) bool {
 			continue
 		}
 		if r.free < r.req {
-			log.Printf("Not enough %s: free=%v, req=%v", r.name, r.free, r.req)
-			return false
+			return false, fmt.Sprintf("Insufficient %s", r.name)
 		}
 		expectedUtil := 1 - ((r.free - r.req) / r.total)
 		if expectedUtil > alpha {
-			log.Printf("%s utilization too high: expected %v > threshold %v", r.name, expectedUtil, alpha)
-			return false
+			return false, fmt.Sprintf("%s utilization too high", r.name)
 		}
 	}
-	return true
+	return true, ""
 }
 
 // scoreMultiResource computes a score based on the dominant resource share.
@@ -575,61 +632,6 @@ func scoreMultiResource(pod PodRequest, stats NodeStats, maxScore int) int {
 	return int(score)
 }
 
-// attemptPreemptionMulti evicts lower-priority pods to free enough resources.
-func attemptPreemptionMulti(client kubernetes.Interface, pod PodRequest, nodeName string, stats NodeStats) bool {
-	runningPods, err := getRunningPods(client, nodeName)
-	if err != nil {
-		log.Printf("Error getting running pods: %v", err)
-		return false
-	}
-
-	var candidates []RunningPod
-	for _, rp := range runningPods {
-		if rp.Priority < pod.Priority {
-			candidates = append(candidates, rp)
-		}
-	}
-	if len(candidates) == 0 {
-		log.Println("No candidate pods for preemption found.")
-		return false
-	}
-
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].Priority < candidates[j].Priority
-	})
-
-	freedResources := struct {
-		cpu       float64
-		mem       float64
-		diskRead  float64
-		diskWrite float64
-		netUp     float64
-		netDown   float64
-	}{}
-
-	var victims []RunningPod
-	for _, candidate := range candidates {
-		freedResources.cpu += candidate.CPURequest
-		freedResources.mem += candidate.MemRequest
-		// Extend with additional resources if tracked.
-		victims = append(victims, candidate)
-		canScheduleAfterPreemption := (stats.CPUFree+freedResources.cpu >= pod.CPU) &&
-			(stats.MemFree+freedResources.mem >= pod.Mem)
-		// Add similar checks for disk and network as needed.
-		if canScheduleAfterPreemption {
-			for _, victim := range victims {
-				if err := evictPod(client, victim); err != nil {
-					log.Printf("Failed to evict pod %s/%s: %v", victim.Namespace, victim.Name, err)
-					return false
-				}
-				log.Printf("Evicted pod %s/%s", victim.Namespace, victim.Name)
-			}
-			return true
-		}
-	}
-	return false
-}
-
 func main() {
 	// Command-line flags.
 	nodeName := flag.String("node", "", "Name of the node to check (if empty, will use the first available node)")
@@ -644,8 +646,51 @@ func main() {
 	preemption := flag.Bool("preemption", true, "Enable preemption of lower-priority Pods")
 	interval := flag.Int("interval", 60, "Interval in seconds between checks")
 	watchMode := flag.Bool("watch", true, "Enable watching for unscheduled pods")
+	metricsSource := flag.String("metrics-source", "prometheus", "Primary node metrics source: prometheus, kubelet, or gopsutil (falls back to the others on error)")
+	evictionHard := flag.String("eviction-hard", "memory.available<100Mi,nodefs.available<10%,imagefs.available<15%,nodefs.inodesFree<5%", "Hard eviction thresholds, kubelet syntax (SIGNAL<VALUE[,SIGNAL<VALUE...])")
+	evictionSoft := flag.String("eviction-soft", "", "Soft eviction thresholds, kubelet syntax; logged but not enforced immediately (no grace-period tracking across ticks)")
+	prometheusURLs := flag.String("prometheus-urls", "", "Comma-separated Prometheus/Thanos Query endpoints to fan queries out to and merge (defaults to PROMETHEUS_URL/getPrometheusURL if empty); per-endpoint auth is read from PROMETHEUS_* env vars or PROMETHEUS_ENDPOINT_AUTH_JSON")
+	clusterLabelName := flag.String("cluster-label-name", "", "PromQL label injected into every query to scope it to one cluster behind a federated/Thanos endpoint (defaults to \"cluster\" if --cluster-label-value is set)")
+	clusterLabelValue := flag.String("cluster-label-value", "", "Value for --cluster-label-name, e.g. prod-eu")
+	thanosPartialResponse := flag.Bool("prometheus-thanos-partial-response", false, "Set partial_response=true on every Prometheus query (Thanos Query)")
+	thanosDedup := flag.Bool("prometheus-thanos-dedup", false, "Set dedup=true on every Prometheus query (Thanos Query)")
+	workerCount := flag.Int("worker-count", 4, "Number of parallel workers draining the priority-fair scheduling queue in watch mode")
+	metricsAddr := flag.String("metrics-addr", ":8080", "Address to serve Prometheus metrics on (/metrics)")
+	schedulingConfigPath := flag.String("scheduling-config", "", "Path to a YAML file tuning Score plugin weights (maxScore, scoreWeights); built-in defaults are used if empty")
+	extenderConfigPath := flag.String("extender-config", "", "Path to a JSON file describing scheduler extenders (urlPrefix, verbs, weight, managedResources); none are consulted if empty")
+	preemptionGraceTimeout := flag.Int("preemption-grace-timeout", 30, "Seconds to wait for preemption victims to actually leave a node before re-reading its stats and binding; 0 disables the wait")
+	nominationTTLSeconds := flag.Int("nomination-ttl", 300, "Seconds an in-flight NominatedNodeName preemption is tracked before being expired and retried from scratch (watch mode only)")
+	cacheRefreshInterval := flag.Int("cache-refresh-interval", 15, "Seconds between SchedulerCache re-samples of every node's stats in watch mode, instead of fetching stats per pending pod")
+	assumedPodTTL := flag.Int("assumed-pod-ttl", 120, "Seconds a pod the SchedulerCache assumed bound to a node is kept reserved if the Pod informer never reports it landing there (watch mode only)")
 	flag.Parse()
 
+	configurePrometheusFederation(*prometheusURLs, *clusterLabelName, *clusterLabelValue, *thanosPartialResponse, *thanosDedup)
+
+	schedulingConfig, err := LoadSchedulingConfig(*schedulingConfigPath)
+	if err != nil {
+		log.Fatalf("Error loading --scheduling-config: %v", err)
+	}
+	extenders, err := LoadExtenderConfigs(*extenderConfigPath)
+	if err != nil {
+		log.Fatalf("Error loading --extender-config: %v", err)
+	}
+	filterPlugins := defaultFilterPlugins(*alpha)
+	scorePlugins := defaultScorePlugins()
+	graceTimeout := time.Duration(*preemptionGraceTimeout) * time.Second
+	nominationTTL := time.Duration(*nominationTTLSeconds) * time.Second
+
+	metricsProvider := buildMetricsProvider(*metricsSource)
+
+	evictionThresholds, err := parseEvictionThresholds(*evictionHard)
+	if err != nil {
+		log.Fatalf("Error parsing --eviction-hard: %v", err)
+	}
+	if _, err := parseEvictionThresholds(*evictionSoft); err != nil {
+		log.Fatalf("Error parsing --eviction-soft: %v", err)
+	} else if *evictionSoft != "" {
+		log.Printf("Soft eviction thresholds %q parsed but not enforced (this scheduler checks hard thresholds only)", *evictionSoft)
+	}
+
 	// Create Kubernetes client.
 	config, err := rest.InClusterConfig()
 	if err != nil {
@@ -663,42 +708,48 @@ func main() {
 		log.Fatalf("Error creating Kubernetes client: %v", err)
 	}
 
-	// Start a goroutine to watch for unscheduled pods if watch mode is enabled
+	// Serve Prometheus metrics (scheduling-attempt latency, queue length,
+	// pods-scheduled counters) alongside whatever watch mode is doing.
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+
+	// Start the informer-driven pod controller if watch mode is enabled,
+	// replacing the old list-poll loop with a shared informer feeding a
+	// priority-and-fairness queue drained by --worker-count workers.
 	if *watchMode {
-		log.Println("Starting to watch for unscheduled pods...")
-		go watchForUnscheduledPods(client, *alpha, *preemption)
+		log.Println("Starting informer-driven pod controller...")
+		schedCache := NewSchedulerCache(client, metricsProvider, time.Duration(*cacheRefreshInterval)*time.Second, time.Duration(*assumedPodTTL)*time.Second)
+		controller := newPodController(client, schedCache, *alpha, *preemption, evictionThresholds, *workerCount, graceTimeout, schedulingConfig, filterPlugins, scorePlugins, extenders, nominationTTL)
+		go controller.Run(wait.NeverStop)
+	}
+
+	// Build the Pod request and a synthetic Pod carrying it, so the same
+	// Filter/Score plugins watch mode uses (affinity, tolerations, topology
+	// spread, volume binding) are exercised here too; fields a CLI-driven
+	// check has no way to supply (labels, affinity, tolerations) are simply
+	// absent, which every plugin above treats as "unconstrained".
+	podReq := PodRequest{
+		CPU:       *cpuReq,
+		Mem:       *memReq,
+		DiskRead:  *diskReadReq,
+		DiskWrite: *diskWriteReq,
+		NetUp:     *netUpReq,
+		NetDown:   *netDownReq,
+		Priority:  *podPriority,
+	}
+	syntheticPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "preemptive-sched-check", Namespace: "default"},
+		Spec: v1.PodSpec{
+			Priority: func() *int32 { p := int32(*podPriority); return &p }(),
+		},
 	}
 
 	for {
-		targetNode := *nodeName
-		if targetNode == "" {
-			nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
-			if err != nil {
-				log.Printf("Error listing nodes: %v", err)
-				time.Sleep(time.Duration(*interval) * time.Second)
-				continue
-			}
-			if len(nodes.Items) == 0 {
-				log.Printf("No nodes found in the cluster")
-				time.Sleep(time.Duration(*interval) * time.Second)
-				continue
-			}
-			targetNode = nodes.Items[0].Name
-			var nodeIP string
-			for _, addr := range nodes.Items[0].Status.Addresses {
-				if addr.Type == v1.NodeInternalIP || addr.Type == v1.NodeExternalIP {
-					nodeIP = addr.Address
-					break
-				}
-			}
-			if nodeIP != "" {
-				log.Printf("No node specified, using node: %s (IP: %s)", targetNode, nodeIP)
-				targetNode = nodeIP
-			} else {
-				log.Printf("No node specified, using node: %s", targetNode)
-			}
-		}
-
 		// Test Prometheus connection.
 		testQuery := "up"
 		testMetrics, err := queryPrometheus(testQuery)
@@ -708,105 +759,45 @@ func main() {
 			log.Printf("Prometheus connection test successful. Metrics: %+v", testMetrics)
 		}
 
-		stats, err := getNodeStats(targetNode)
-		if err != nil {
-			log.Printf("Error getting node stats: %v", err)
-			time.Sleep(time.Duration(*interval) * time.Second)
-			continue
-		}
-
-		// Build the Pod request.
-		podReq := PodRequest{
-			CPU:       *cpuReq,
-			Mem:       *memReq,
-			DiskRead:  *diskReadReq,
-			DiskWrite: *diskWriteReq,
-			NetUp:     *netUpReq,
-			NetDown:   *netDownReq,
-			Priority:  *podPriority,
-		}
-
-		if canScheduleMulti(podReq, stats, *alpha) {
-			log.Printf("Node %s can schedule the Pod.", targetNode)
-		} else {
-			log.Printf("Node %s lacks sufficient resources.", targetNode)
-			if *preemption {
-				log.Printf("Preemption enabled; attempting to free resources on node %s.", targetNode)
-				if attemptPreemptionMulti(client, podReq, targetNode, stats) {
-					stats, err = getNodeStats(targetNode)
-					if err != nil {
-						log.Printf("Error re-fetching node stats: %v", err)
-						time.Sleep(time.Duration(*interval) * time.Second)
-						continue
-					}
-					if canScheduleMulti(podReq, stats, *alpha) {
-						log.Printf("After preemption, node %s can now schedule the Pod.", targetNode)
-					} else {
-						log.Printf("Even after preemption, node %s still cannot schedule the Pod.", targetNode)
-					}
-				} else {
-					log.Printf("Preemption failed on node %s.", targetNode)
-				}
-			} else {
-				log.Printf("Preemption disabled; cannot schedule Pod on node %s.", targetNode)
-			}
-		}
-
-		log.Printf("Sleeping for %d seconds before next check...", *interval)
-		time.Sleep(time.Duration(*interval) * time.Second)
-	}
-}
-
-// watchForUnscheduledPods watches for pods that have no node assigned and attempts to schedule them
-func watchForUnscheduledPods(client kubernetes.Interface, alpha float64, enablePreemption bool) {
-	for {
-		// Get all pods in the cluster
-		pods, err := client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
-			FieldSelector: "spec.schedulerName=preemptive-scheduler,spec.nodeName=",
-		})
-		if err != nil {
-			log.Printf("Error listing pods: %v", err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-
-		// Process each unscheduled pod
-		for _, pod := range pods.Items {
-			log.Printf("Found unscheduled pod: %s/%s", pod.Namespace, pod.Name)
-
-			// Skip pods that are being deleted
-			if pod.DeletionTimestamp != nil {
-				log.Printf("Pod %s/%s is being deleted, skipping", pod.Namespace, pod.Name)
-				continue
-			}
-
-			// Get pod resource requirements
-			podReq := extractPodRequirements(&pod)
-
-			// Find a suitable node for the pod
-			nodeName, err := findNodeForPod(client, podReq, alpha, enablePreemption)
+		var candidateNodes []v1.Node
+		if *nodeName != "" {
+			node, err := client.CoreV1().Nodes().Get(context.Background(), *nodeName, metav1.GetOptions{})
 			if err != nil {
-				log.Printf("Error finding node for pod %s/%s: %v", pod.Namespace, pod.Name, err)
+				log.Printf("Error getting node %s: %v", *nodeName, err)
+				time.Sleep(time.Duration(*interval) * time.Second)
 				continue
 			}
-
-			if nodeName == "" {
-				log.Printf("No suitable node found for pod %s/%s", pod.Namespace, pod.Name)
+			candidateNodes = []v1.Node{*node}
+		} else {
+			nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				log.Printf("Error listing nodes: %v", err)
+				time.Sleep(time.Duration(*interval) * time.Second)
 				continue
 			}
-
-			// Bind the pod to the node
-			err = bindPodToNode(client, &pod, nodeName)
-			if err != nil {
-				log.Printf("Error binding pod %s/%s to node %s: %v", pod.Namespace, pod.Name, nodeName, err)
+			if len(nodes.Items) == 0 {
+				log.Printf("No nodes found in the cluster")
+				time.Sleep(time.Duration(*interval) * time.Second)
 				continue
 			}
+			candidateNodes = nodes.Items
+			log.Printf("No node specified, evaluating all %d node(s) in the cluster", len(candidateNodes))
+		}
 
-			log.Printf("Successfully scheduled pod %s/%s on node %s", pod.Namespace, pod.Name, nodeName)
+		// recorder is nil here: syntheticPod is a diagnostic stand-in, never
+		// actually created in the API server, so there's nothing to emit an
+		// Event against.
+		chosenNode, err := runSchedulingCycle(client, nil, candidateNodes, syntheticPod, podReq, metricsProvider, *alpha, *preemption, evictionThresholds, graceTimeout, schedulingConfig, filterPlugins, scorePlugins, extenders)
+		if err != nil {
+			log.Printf("Error running scheduling cycle: %v", err)
+		} else if chosenNode == "" {
+			log.Printf("No candidate node (out of %d) can schedule the Pod, even with preemption=%v.", len(candidateNodes), *preemption)
+		} else {
+			log.Printf("Node %s can schedule the Pod.", chosenNode)
 		}
 
-		// Sleep before checking again
-		time.Sleep(1 * time.Second)
+		log.Printf("Sleeping for %d seconds before next check...", *interval)
+		time.Sleep(time.Duration(*interval) * time.Second)
 	}
 }
 
@@ -844,58 +835,6 @@ func extractPodRequirements(pod *v1.Pod) PodRequest {
 	}
 }
 
-// findNodeForPod finds a suitable node for the pod
-func findNodeForPod(client kubernetes.Interface, podReq PodRequest, alpha float64, enablePreemption bool) (string, error) {
-	// Get all nodes
-	nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		return "", fmt.Errorf("error listing nodes: %v", err)
-	}
-
-	// Check each node
-	for _, node := range nodes.Items {
-		nodeName := node.Name
-
-		// Skip nodes that are not ready or are unschedulable
-		if !isNodeReady(&node) || node.Spec.Unschedulable {
-			log.Printf("Node %s is not ready or is unschedulable, skipping", nodeName)
-			continue
-		}
-
-		// Get node stats
-		stats, err := getNodeStats(nodeName)
-		if err != nil {
-			log.Printf("Error getting stats for node %s: %v", nodeName, err)
-			continue
-		}
-
-		// Check if the node can schedule the pod
-		if canScheduleMulti(podReq, stats, alpha) {
-			return nodeName, nil
-		}
-
-		// If preemption is enabled, try to free up resources
-		if enablePreemption {
-			log.Printf("Attempting preemption on node %s", nodeName)
-			if attemptPreemptionMulti(client, podReq, nodeName, stats) {
-				// Re-check if the node can now schedule the pod
-				stats, err = getNodeStats(nodeName)
-				if err != nil {
-					log.Printf("Error re-fetching stats for node %s: %v", nodeName, err)
-					continue
-				}
-
-				if canScheduleMulti(podReq, stats, alpha) {
-					return nodeName, nil
-				}
-			}
-		}
-	}
-
-	// No suitable node found
-	return "", nil
-}
-
 // isNodeReady checks if a node is in Ready condition
 func isNodeReady(node *v1.Node) bool {
 	for _, condition := range node.Status.Conditions {
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultFederationClusterLabel is the label Thanos/federated Prometheus
+// deployments conventionally attach to identify the source cluster of a
+// sample, mirroring the convention used by the multiresource scheduler's
+// own Prometheus client.
+const defaultFederationClusterLabel = "cluster"
+
+// prometheusEndpoint is one Prometheus (or Thanos Query) target that
+// queryPrometheus fans a query out to, along with whatever credentials are
+// needed to reach it.
+type prometheusEndpoint struct {
+	URL string
+
+	BearerToken string
+	BasicUser   string
+	BasicPass   string
+
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSInsecureSkipVerify bool
+}
+
+// prometheusFederationConfig holds the set of endpoints and the
+// cluster-label scoping/Thanos params every query should be issued with.
+// A nil config (promFederation never set) means queryPrometheus falls back
+// to the single getPrometheusURL() endpoint with no label injected, which
+// is the original single-cluster behavior; a non-nil config with no
+// endpoints makes every query fail.
+type prometheusFederationConfig struct {
+	Endpoints []prometheusEndpoint
+
+	// ClusterLabelName/ClusterLabelValue, when both set, are injected into
+	// the first selector of every query (e.g. cluster="prod-eu", see
+	// injectClusterSelector) so a query against a central Prometheus that
+	// federates several clusters only matches series from the intended one.
+	// Later selectors in the same query are not scoped.
+	ClusterLabelName  string
+	ClusterLabelValue string
+
+	ThanosPartialResponse bool
+	ThanosDedup           bool
+}
+
+// promFederation is populated by configurePrometheusFederation during
+// flag parsing in main. It stays nil for callers (including existing
+// tests) that never call configurePrometheusFederation, in which case
+// queryPrometheus behaves exactly as it did before federation support was
+// added.
+var promFederation *prometheusFederationConfig
+
+// configurePrometheusFederation builds the federation config from
+// --prometheus-urls/--cluster-label-name/--cluster-label-value/
+// --prometheus-thanos-partial-response/--prometheus-thanos-dedup. Per-endpoint
+// auth isn't practical to thread through a comma-separated flag, so it's
+// read from environment variables instead (the same place PROMETHEUS_URL
+// already lives): either one shared PROMETHEUS_* credential applied to
+// every endpoint, or a PROMETHEUS_ENDPOINT_AUTH_JSON array keyed by URL for
+// setups where each cluster's Prometheus needs different credentials.
+func configurePrometheusFederation(urlsFlag, clusterLabelName, clusterLabelValue string, thanosPartial, thanosDedup bool) {
+	urls := strings.Split(urlsFlag, ",")
+	var cleaned []string
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u != "" {
+			cleaned = append(cleaned, u)
+		}
+	}
+	if len(cleaned) == 0 {
+		cleaned = []string{getPrometheusURL()}
+	}
+
+	sharedAuth := prometheusEndpoint{
+		BearerToken:           os.Getenv("PROMETHEUS_BEARER_TOKEN"),
+		BasicUser:             os.Getenv("PROMETHEUS_BASIC_USER"),
+		BasicPass:             os.Getenv("PROMETHEUS_BASIC_PASSWORD"),
+		TLSCAFile:             os.Getenv("PROMETHEUS_TLS_CA_FILE"),
+		TLSCertFile:           os.Getenv("PROMETHEUS_TLS_CERT_FILE"),
+		TLSKeyFile:            os.Getenv("PROMETHEUS_TLS_KEY_FILE"),
+		TLSInsecureSkipVerify: envBool("PROMETHEUS_TLS_INSECURE_SKIP_VERIFY", false),
+	}
+	if f := os.Getenv("PROMETHEUS_BEARER_TOKEN_FILE"); f != "" && sharedAuth.BearerToken == "" {
+		if b, err := ioutil.ReadFile(f); err == nil {
+			sharedAuth.BearerToken = strings.TrimSpace(string(b))
+		} else {
+			log.Printf("Warning: failed to read PROMETHEUS_BEARER_TOKEN_FILE %s: %v", f, err)
+		}
+	}
+	perEndpointAuth := loadPerEndpointAuth()
+
+	endpoints := make([]prometheusEndpoint, 0, len(cleaned))
+	for _, u := range cleaned {
+		ep := sharedAuth
+		if override, ok := perEndpointAuth[u]; ok {
+			ep = override
+		}
+		ep.URL = u
+		endpoints = append(endpoints, ep)
+	}
+
+	if clusterLabelValue != "" && clusterLabelName == "" {
+		clusterLabelName = defaultFederationClusterLabel
+	}
+
+	promFederation = &prometheusFederationConfig{
+		Endpoints:             endpoints,
+		ClusterLabelName:      clusterLabelName,
+		ClusterLabelValue:     clusterLabelValue,
+		ThanosPartialResponse: thanosPartial,
+		ThanosDedup:           thanosDedup,
+	}
+	log.Printf("Configured Prometheus federation: %d endpoint(s), cluster label %s=%q, thanos partial_response=%v dedup=%v",
+		len(endpoints), clusterLabelName, clusterLabelValue, thanosPartial, thanosDedup)
+}
+
+// loadPerEndpointAuth parses PROMETHEUS_ENDPOINT_AUTH_JSON, a JSON array of
+// {"url": "...", "bearer_token": "...", "basic_user": "...", "basic_password": "...",
+// "tls_ca_file": "...", "tls_cert_file": "...", "tls_key_file": "...",
+// "tls_insecure_skip_verify": true}, for deployments where different
+// federated clusters' Prometheus endpoints require different credentials.
+func loadPerEndpointAuth() map[string]prometheusEndpoint {
+	raw := os.Getenv("PROMETHEUS_ENDPOINT_AUTH_JSON")
+	if raw == "" {
+		return nil
+	}
+	var entries []struct {
+		URL                   string `json:"url"`
+		BearerToken           string `json:"bearer_token"`
+		BasicUser             string `json:"basic_user"`
+		BasicPassword         string `json:"basic_password"`
+		TLSCAFile             string `json:"tls_ca_file"`
+		TLSCertFile           string `json:"tls_cert_file"`
+		TLSKeyFile            string `json:"tls_key_file"`
+		TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify"`
+	}
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		log.Printf("Warning: failed to parse PROMETHEUS_ENDPOINT_AUTH_JSON: %v", err)
+		return nil
+	}
+	out := make(map[string]prometheusEndpoint, len(entries))
+	for _, e := range entries {
+		out[e.URL] = prometheusEndpoint{
+			URL:                   e.URL,
+			BearerToken:           e.BearerToken,
+			BasicUser:             e.BasicUser,
+			BasicPass:             e.BasicPassword,
+			TLSCAFile:             e.TLSCAFile,
+			TLSCertFile:           e.TLSCertFile,
+			TLSKeyFile:            e.TLSKeyFile,
+			TLSInsecureSkipVerify: e.TLSInsecureSkipVerify,
+		}
+	}
+	return out
+}
+
+// envBool parses the named environment variable as a bool, returning def
+// if it's unset or unparseable.
+func envBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return parsed
+}
+
+// federatedClusterSelector returns the PromQL label matcher for cfg's
+// cluster-label scoping, or "" if cfg is nil or no scoping is configured.
+func federatedClusterSelector(cfg *prometheusFederationConfig) string {
+	if cfg == nil || cfg.ClusterLabelName == "" || cfg.ClusterLabelValue == "" {
+		return ""
+	}
+	return fmt.Sprintf(`%s="%s"`, cfg.ClusterLabelName, cfg.ClusterLabelValue)
+}
+
+// promIdentifier matches a PromQL metric or function name.
+var promIdentifier = regexp.MustCompile(`[a-zA-Z_:][a-zA-Z0-9_:]*`)
+
+// injectClusterSelector merges selector into query's first `{...}` block.
+// A query with no `{...}` block gets a fresh one attached to its first
+// metric name (the first identifier not followed by "("), so
+// rate(x[5m]) becomes rate(x{selector}[5m]).
+func injectClusterSelector(query, selector string) string {
+	if selector == "" {
+		return query
+	}
+	idx := strings.Index(query, "{")
+	if idx == -1 {
+		for _, loc := range promIdentifier.FindAllStringIndex(query, -1) {
+			if !strings.HasPrefix(strings.TrimLeft(query[loc[1]:], " "), "(") {
+				return query[:loc[1]] + "{" + selector + "}" + query[loc[1]:]
+			}
+		}
+		return fmt.Sprintf("%s{%s}", query, selector)
+	}
+	return query[:idx+1] + selector + "," + query[idx+1:]
+}
+
+// endpointHTTPClient builds an *http.Client configured with ep's TLS
+// material, or the default client if none is set.
+func endpointHTTPClient(ep prometheusEndpoint) (*http.Client, error) {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: 10 * time.Second,
+	}
+	if ep.TLSCAFile == "" && ep.TLSCertFile == "" && !ep.TLSInsecureSkipVerify {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: ep.TLSInsecureSkipVerify}
+	if ep.TLSCAFile != "" {
+		pemData, err := ioutil.ReadFile(ep.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading TLS CA file %s: %w", ep.TLSCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("no certificates found in TLS CA file %s", ep.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if ep.TLSCertFile != "" && ep.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(ep.TLSCertFile, ep.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading TLS client cert/key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	return client, nil
+}
+
+// queryPrometheusEndpoint runs query against a single endpoint, applying
+// its auth and cfg's Thanos query params, and returns the raw
+// metric->value map, keyed by the "instance" label like the
+// single-endpoint queryPrometheus, but prefixed with "<cluster>/" when the
+// sample carries the cluster label.
+func queryPrometheusEndpoint(ep prometheusEndpoint, query string, cfg *prometheusFederationConfig) (map[string]float64, error) {
+	args := url.Values{}
+	args.Add("query", query)
+	if cfg != nil {
+		if cfg.ThanosPartialResponse {
+			args.Add("partial_response", "true")
+		}
+		if cfg.ThanosDedup {
+			args.Add("dedup", "true")
+		}
+	}
+	constructedURL := fmt.Sprintf("%s?%s", ep.URL, args.Encode())
+
+	log.Printf("Querying Prometheus: %s", constructedURL)
+	client, err := endpointHTTPClient(ep)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", constructedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if ep.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+ep.BearerToken)
+	} else if ep.BasicUser != "" {
+		req.SetBasicAuth(ep.BasicUser, ep.BasicPass)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making GET request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+	var result struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Value  []interface{}     `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("error unmarshaling JSON: %v", err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("query returned non-success status: %v", result.Status)
+	}
+
+	clusterLabel := defaultFederationClusterLabel
+	if cfg != nil && cfg.ClusterLabelName != "" {
+		clusterLabel = cfg.ClusterLabelName
+	}
+
+	metrics := make(map[string]float64)
+	for _, r := range result.Data.Result {
+		key := r.Metric["instance"]
+		if key == "" {
+			key = fmt.Sprintf("metric_%d", len(metrics))
+		}
+		if len(r.Value) < 2 {
+			continue
+		}
+		valueStr, ok := r.Value[1].(string)
+		if !ok {
+			continue
+		}
+		// A Thanos endpoint that federates several clusters can return the
+		// same instance label from more than one cluster; disambiguate by
+		// prefixing with the cluster label so the merge step below doesn't
+		// silently clobber one cluster's sample with another's.
+		if cluster := r.Metric[clusterLabel]; cluster != "" {
+			key = cluster + "/" + key
+		}
+		metrics[key] = parseFloat(valueStr)
+	}
+	return metrics, nil
+}
+
+// queryPrometheusFederated fans query out to every endpoint in cfg
+// (scoped with cfg's cluster-label selector, if any) and merges the
+// results. A later endpoint's sample for an already-seen key overwrites
+// the earlier one, logged as a collision, since genuinely distinct
+// clusters are already disambiguated by the cluster-label prefix applied
+// in queryPrometheusEndpoint.
+func queryPrometheusFederated(query string, cfg *prometheusFederationConfig) (map[string]float64, error) {
+	scopedQuery := injectClusterSelector(query, federatedClusterSelector(cfg))
+
+	merged := make(map[string]float64)
+	var lastErr error
+	var successes int
+	for _, ep := range cfg.Endpoints {
+		result, err := queryPrometheusEndpoint(ep, scopedQuery, cfg)
+		if err != nil {
+			log.Printf("Warning: Prometheus endpoint %s failed: %v", ep.URL, err)
+			lastErr = err
+			continue
+		}
+		successes++
+		for k, v := range result {
+			if _, exists := merged[k]; exists {
+				log.Printf("Warning: instance key %q returned by more than one Prometheus endpoint, keeping %s's value", k, ep.URL)
+			}
+			merged[k] = v
+		}
+	}
+	if successes == 0 {
+		return nil, fmt.Errorf("all %d Prometheus endpoint(s) failed, last error: %v", len(cfg.Endpoints), lastErr)
+	}
+	return merged, nil
+}
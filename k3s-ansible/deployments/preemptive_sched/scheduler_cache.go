@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// This file replaces "list every Node and call the metrics provider once
+// per pending pod" (O(pending pods x nodes) API and metrics calls per
+// second) with a SchedulerCache: a local, informer-backed mirror of Node
+// and Pod state plus a periodically refreshed NodeStats snapshot, so a
+// burst of pending pods is scheduled against memory instead of hammering
+// the API server and whatever backs MetricsProvider.
+
+// assumedPod is a pod the scheduler has decided to bind to a node but
+// hasn't yet observed land there via the Pod informer; its demand is
+// subtracted from the NodeInfo's cached stats so a second pending pod
+// scheduled moments later doesn't double-book the same capacity.
+type assumedPod struct {
+	req       PodRequest
+	expiresAt time.Time
+}
+
+// NodeInfo is the cache's per-node view: the Node object itself, the last
+// NodeStats observed by the periodic refresher, and any pods assumed bound
+// here since that refresh. Each NodeInfo has its own lock so scheduling
+// attempts for pods headed to different nodes don't contend with each
+// other.
+type NodeInfo struct {
+	mu      sync.Mutex
+	node    *v1.Node
+	stats   NodeStats
+	assumed map[types.UID]assumedPod
+}
+
+// Snapshot returns ni's node and its stats with every still-live assumed
+// pod's demand subtracted.
+func (ni *NodeInfo) Snapshot() (*v1.Node, NodeStats) {
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+
+	stats := ni.stats
+	now := time.Now()
+	for uid, a := range ni.assumed {
+		if now.After(a.expiresAt) {
+			delete(ni.assumed, uid)
+			continue
+		}
+		stats.CPUFree -= a.req.CPU
+		stats.MemFree -= a.req.Mem
+		stats.DiskReadFree -= a.req.DiskRead
+		stats.DiskWriteFree -= a.req.DiskWrite
+		stats.NetUpFree -= a.req.NetUp
+		stats.NetDownFree -= a.req.NetDown
+	}
+	return ni.node, stats
+}
+
+// assume records req as bound to this node until ttl elapses or it's
+// explicitly forgotten, whichever comes first.
+func (ni *NodeInfo) assume(uid types.UID, req PodRequest, ttl time.Duration) {
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+	ni.assumed[uid] = assumedPod{req: req, expiresAt: time.Now().Add(ttl)}
+}
+
+// forget drops uid's assumed reservation, called once the Pod informer
+// observes it actually landed on this node (so the next metrics refresh
+// accounts for it for real). A pod deleted before it lands is not
+// forgotten; its reservation lasts until the assumed TTL expires.
+func (ni *NodeInfo) forget(uid types.UID) {
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+	delete(ni.assumed, uid)
+}
+
+// SchedulerCache is an informer-backed mirror of cluster Node/Pod state,
+// maintaining one NodeInfo per node so a pending pod is scheduled against
+// an in-memory snapshot instead of a fresh Nodes().List and a per-node
+// metrics call.
+type SchedulerCache struct {
+	client          kubernetes.Interface
+	metricsProvider MetricsProvider
+	refreshInterval time.Duration
+	assumedTTL      time.Duration
+
+	nodeInformer cache.SharedIndexInformer
+	podInformer  cache.SharedIndexInformer
+
+	mu    sync.RWMutex
+	nodes map[string]*NodeInfo
+}
+
+// NewSchedulerCache builds a SchedulerCache that refreshes every node's
+// NodeStats from metricsProvider every refreshInterval, and expires a
+// pod assumed bound to a node after assumedTTL if the Pod informer never
+// reports it actually landing there (e.g. the bind silently failed).
+func NewSchedulerCache(client kubernetes.Interface, metricsProvider MetricsProvider, refreshInterval, assumedTTL time.Duration) *SchedulerCache {
+	sc := &SchedulerCache{
+		client:          client,
+		metricsProvider: metricsProvider,
+		refreshInterval: refreshInterval,
+		assumedTTL:      assumedTTL,
+		nodes:           make(map[string]*NodeInfo),
+	}
+
+	sc.nodeInformer = cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.CoreV1().Nodes().List(context.Background(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().Nodes().Watch(context.Background(), options)
+			},
+		},
+		&v1.Node{},
+		30*time.Second,
+		cache.Indexers{},
+	)
+	sc.nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    sc.upsertNode,
+		UpdateFunc: func(_, newObj interface{}) { sc.upsertNode(newObj) },
+		DeleteFunc: sc.deleteNode,
+	})
+
+	sc.podInformer = cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.CoreV1().Pods(metav1.NamespaceAll).List(context.Background(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().Pods(metav1.NamespaceAll).Watch(context.Background(), options)
+			},
+		},
+		&v1.Pod{},
+		30*time.Second,
+		cache.Indexers{},
+	)
+	sc.podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    sc.reconcilePod,
+		UpdateFunc: func(_, newObj interface{}) { sc.reconcilePod(newObj) },
+	})
+
+	return sc
+}
+
+// upsertNode records node's latest spec/status and immediately re-samples
+// its stats, so a newly joined node is schedulable without waiting for the
+// next refreshInterval tick.
+func (sc *SchedulerCache) upsertNode(obj interface{}) {
+	node, ok := obj.(*v1.Node)
+	if !ok {
+		return
+	}
+
+	sc.mu.Lock()
+	ni, ok := sc.nodes[node.Name]
+	if !ok {
+		ni = &NodeInfo{assumed: make(map[types.UID]assumedPod)}
+		sc.nodes[node.Name] = ni
+	}
+	sc.mu.Unlock()
+
+	ni.mu.Lock()
+	ni.node = node
+	ni.mu.Unlock()
+
+	sc.refreshNodeStats(ni)
+}
+
+// deleteNode drops a removed node from the cache entirely.
+func (sc *SchedulerCache) deleteNode(obj interface{}) {
+	node, ok := obj.(*v1.Node)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		node, ok = tombstone.Obj.(*v1.Node)
+		if !ok {
+			return
+		}
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.nodes, node.Name)
+}
+
+// reconcilePod drops any assumed reservation matching pod once it's
+// actually bound to a node, so the assumption isn't double-counted
+// against that node's real usage once the next metrics refresh picks it
+// up organically.
+func (sc *SchedulerCache) reconcilePod(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return
+	}
+
+	sc.mu.RLock()
+	ni, ok := sc.nodes[pod.Spec.NodeName]
+	sc.mu.RUnlock()
+	if !ok {
+		return
+	}
+	ni.forget(pod.UID)
+}
+
+// refreshNodeStats re-samples ni's NodeStats from sc.metricsProvider.
+func (sc *SchedulerCache) refreshNodeStats(ni *NodeInfo) {
+	ni.mu.Lock()
+	node := ni.node
+	ni.mu.Unlock()
+	if node == nil {
+		return
+	}
+
+	stats, err := sc.metricsProvider.NodeStats(sc.client, node)
+	if err != nil {
+		log.Printf("SchedulerCache: error refreshing stats for node %s: %v", node.Name, err)
+		return
+	}
+
+	ni.mu.Lock()
+	ni.stats = stats
+	ni.mu.Unlock()
+}
+
+// refreshAllNodeStats re-samples every known node's stats.
+func (sc *SchedulerCache) refreshAllNodeStats() {
+	sc.mu.RLock()
+	infos := make([]*NodeInfo, 0, len(sc.nodes))
+	for _, ni := range sc.nodes {
+		infos = append(infos, ni)
+	}
+	sc.mu.RUnlock()
+
+	for _, ni := range infos {
+		sc.refreshNodeStats(ni)
+	}
+}
+
+// Run starts the Node/Pod informers and the periodic metrics refresher,
+// blocking until stopCh is closed.
+func (sc *SchedulerCache) Run(stopCh <-chan struct{}) {
+	go sc.nodeInformer.Run(stopCh)
+	go sc.podInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, sc.nodeInformer.HasSynced, sc.podInformer.HasSynced) {
+		log.Println("Error: timed out waiting for scheduler cache informers to sync")
+		return
+	}
+
+	wait.Until(sc.refreshAllNodeStats, sc.refreshInterval, stopCh)
+}
+
+// Snapshot returns every known node along with its cached stats (assumed
+// pods already subtracted), for a scheduling attempt to filter and score
+// without touching the API server or a metrics backend. Entries whose Node
+// object isn't set yet (node == nil briefly after Add) are omitted; a node
+// whose stats haven't been refreshed successfully yet is returned with
+// zero-value NodeStats.
+func (sc *SchedulerCache) Snapshot() ([]v1.Node, map[string]NodeStats) {
+	sc.mu.RLock()
+	infos := make([]*NodeInfo, 0, len(sc.nodes))
+	for _, ni := range sc.nodes {
+		infos = append(infos, ni)
+	}
+	sc.mu.RUnlock()
+
+	nodes := make([]v1.Node, 0, len(infos))
+	stats := make(map[string]NodeStats, len(infos))
+	for _, ni := range infos {
+		node, s := ni.Snapshot()
+		if node == nil {
+			continue
+		}
+		nodes = append(nodes, *node)
+		stats[node.Name] = s
+	}
+	return nodes, stats
+}
+
+// AssumePod records req as bound to nodeName until the cache's Pod
+// informer observes it landing there for real (or assumedTTL elapses), so
+// a concurrent scheduling attempt for a different pending pod sees the
+// reduced capacity immediately instead of waiting for the next metrics
+// refresh. A nodeName the cache doesn't know about is silently ignored.
+func (sc *SchedulerCache) AssumePod(nodeName string, uid types.UID, req PodRequest) {
+	sc.mu.RLock()
+	ni, ok := sc.nodes[nodeName]
+	sc.mu.RUnlock()
+	if !ok {
+		return
+	}
+	ni.assume(uid, req, sc.assumedTTL)
+}
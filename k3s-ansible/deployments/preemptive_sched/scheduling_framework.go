@@ -0,0 +1,855 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+// This file replaces "pick the first available node and evaluate only it"
+// with a proper Filter/Score/Bind scheduling cycle run over every Ready
+// node, matching the shape (if not the full feature set) of the real
+// Kubernetes scheduling framework.
+
+// FilterPlugin decides whether a node is eligible to run a pod at all.
+type FilterPlugin interface {
+	Name() string
+	Filter(args filterArgs) (ok bool, reason string)
+}
+
+// filterArgs bundles everything a FilterPlugin might need; plugins use
+// only the fields relevant to them.
+type filterArgs struct {
+	client     kubernetes.Interface
+	pod        *v1.Pod
+	podReq     PodRequest
+	node       *v1.Node
+	stats      NodeStats
+	thresholds EvictionThresholds
+	allNodes   []v1.Node
+}
+
+// ScorePlugin ranks an already-filtered node; higher is more preferred.
+type ScorePlugin interface {
+	Name() string
+	Score(args scoreArgs) int
+}
+
+type scoreArgs struct {
+	pod      *v1.Pod
+	podReq   PodRequest
+	node     *v1.Node
+	stats    NodeStats
+	maxScore int
+}
+
+// SchedulingConfig holds the per-plugin score weights an operator can tune
+// without recompiling, loaded from --scheduling-config.
+type SchedulingConfig struct {
+	MaxScore     int                `yaml:"maxScore"`
+	ScoreWeights map[string]float64 `yaml:"scoreWeights"`
+}
+
+// defaultSchedulingConfig mirrors the historical behavior (scoreMultiResource
+// alone decides placement) by weighting it at 1.0 and giving the new
+// scorers smaller, complementary weights.
+func defaultSchedulingConfig() *SchedulingConfig {
+	return &SchedulingConfig{
+		MaxScore: 100,
+		ScoreWeights: map[string]float64{
+			"resource":           1.0,
+			"leastRequested":     0.5,
+			"balancedAllocation": 0.5,
+			"imageLocality":      0.25,
+		},
+	}
+}
+
+// LoadSchedulingConfig reads a SchedulingConfig from a YAML file. An empty
+// path returns defaultSchedulingConfig() so --scheduling-config is optional.
+func LoadSchedulingConfig(path string) (*SchedulingConfig, error) {
+	cfg := defaultSchedulingConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scheduling config %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing scheduling config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// registeredFilterPlugins and registeredScorePlugins hold plugins added via
+// RegisterFilterPlugin/RegisterScorePlugin, on top of the built-ins below.
+// They're package-level because the plugin chain is assembled once at
+// startup (see defaultFilterPlugins/defaultScorePlugins), before any
+// main()-local state exists for a caller to thread a registry through.
+var (
+	registeredFilterPlugins []FilterPlugin
+	registeredScorePlugins  []ScorePlugin
+)
+
+// RegisterFilterPlugin appends a custom FilterPlugin to the end of the
+// Filter phase's plugin chain, so callers embedding this scheduler as a
+// library can add predicates (e.g. a custom hardware constraint) without
+// forking defaultFilterPlugins or the main scheduling loop. Must be called
+// before defaultFilterPlugins runs (typically from an init() or early in
+// main()); it is not safe to call concurrently with scheduling.
+func RegisterFilterPlugin(p FilterPlugin) {
+	registeredFilterPlugins = append(registeredFilterPlugins, p)
+}
+
+// RegisterScorePlugin is RegisterFilterPlugin's Score-phase counterpart.
+// A registered plugin's Name() must also appear in the SchedulingConfig's
+// ScoreWeights (or --scheduling-config) to contribute to the final score;
+// aggregateScore silently weights an unlisted plugin at 0.
+func RegisterScorePlugin(p ScorePlugin) {
+	registeredScorePlugins = append(registeredScorePlugins, p)
+}
+
+// defaultFilterPlugins returns the Filter phase's plugin chain, in the
+// order they're run (cheapest/most-likely-to-reject first), followed by
+// any plugins added via RegisterFilterPlugin.
+func defaultFilterPlugins(alpha float64) []FilterPlugin {
+	plugins := []FilterPlugin{
+		resourceFilterPlugin{alpha: alpha},
+		taintTolerationFilterPlugin{},
+		nodeAffinityFilterPlugin{},
+		podAffinityFilterPlugin{},
+		topologySpreadFilterPlugin{},
+		volumeBindingFilterPlugin{},
+	}
+	return append(plugins, registeredFilterPlugins...)
+}
+
+// defaultScorePlugins returns the Score phase's plugin chain, followed by
+// any plugins added via RegisterScorePlugin.
+func defaultScorePlugins() []ScorePlugin {
+	plugins := []ScorePlugin{
+		resourceScorePlugin{},
+		leastRequestedScorePlugin{},
+		balancedAllocationScorePlugin{},
+		imageLocalityScorePlugin{},
+	}
+	return append(plugins, registeredScorePlugins...)
+}
+
+// schedulableCandidate is a node that already passed the Filter phase
+// without needing preemption.
+type schedulableCandidate struct {
+	node  *v1.Node
+	stats NodeStats
+}
+
+// preemptionCandidate is a node that only fits after evicting victims.
+type preemptionCandidate struct {
+	node        *v1.Node
+	victims     []RunningPod
+	violatesPDB bool
+}
+
+// classifyNodes runs the Filter phase over every Ready, schedulable node in
+// nodes, splitting the result into nodes that fit pod outright and nodes
+// that would fit after evicting a selectVictims-computed victim set (when
+// enablePreemption is set and the only failing filter was "resource");
+// neither list's nodes have been evicted from yet. It's shared by
+// runSchedulingCycle (which waits synchronously for eviction to take
+// effect) and scheduleOrNominate (which doesn't). statsFn supplies each
+// node's NodeStats, letting callers source it from a live MetricsProvider
+// call (runSchedulingCycle) or a SchedulerCache snapshot (scheduleOrNominate)
+// without classifyNodes caring which.
+func classifyNodes(client kubernetes.Interface, nodes []v1.Node, pod *v1.Pod, podReq PodRequest, statsFn func(*v1.Node) (NodeStats, error), enablePreemption bool, thresholds EvictionThresholds, filters []FilterPlugin) (candidates []schedulableCandidate, preemptionCandidates []preemptionCandidate, failureReasons map[string]int) {
+	failureReasons = make(map[string]int)
+	for i := range nodes {
+		node := &nodes[i]
+		if !isNodeReady(node) || node.Spec.Unschedulable {
+			failureReasons["node(s) were not ready"]++
+			continue
+		}
+
+		stats, err := statsFn(node)
+		if err != nil {
+			log.Printf("Error getting stats for node %s: %v", node.Name, err)
+			failureReasons["node(s) had no metrics available"]++
+			continue
+		}
+
+		ok, failedPlugin, reason := runFilters(filters, filterArgs{client: client, pod: pod, podReq: podReq, node: node, stats: stats, thresholds: thresholds, allNodes: nodes})
+		if ok {
+			candidates = append(candidates, schedulableCandidate{node: node, stats: stats})
+			continue
+		}
+
+		if enablePreemption && failedPlugin == "resource" {
+			runningPods, err := getRunningPods(client, node.Name)
+			if err != nil {
+				log.Printf("Error listing running pods on node %s for preemption: %v", node.Name, err)
+				failureReasons[reason]++
+				continue
+			}
+			if victims, violatesPDB, feasible := selectVictims(client, podReq, stats, runningPods); feasible {
+				preemptionCandidates = append(preemptionCandidates, preemptionCandidate{node: node, victims: victims, violatesPDB: violatesPDB})
+				continue
+			}
+		}
+		log.Printf("Node %s rejected by %s filter: %s", node.Name, failedPlugin, reason)
+		failureReasons[reason]++
+	}
+	return candidates, preemptionCandidates, failureReasons
+}
+
+// sortByLeastDisruption orders preemptionCandidates so the least disruptive
+// one (PDB-safe over PDB-violating, then fewest victims, highest minimum
+// victim priority, earliest-starting victims) comes first.
+func sortByLeastDisruption(preemptionCandidates []preemptionCandidate) {
+	sort.Slice(preemptionCandidates, func(i, j int) bool {
+		return betterPreemptionCandidate(preemptionCandidates[i].victims, preemptionCandidates[j].victims, preemptionCandidates[i].violatesPDB, preemptionCandidates[j].violatesPDB)
+	})
+}
+
+// pickBestCandidate runs the Score phase over candidates, adds each
+// extender's weighted /prioritize contribution (logged but otherwise
+// non-fatal on failure, so a flaky extender can't take scheduling down),
+// and returns the highest-scoring node's name ("" if candidates is empty).
+func pickBestCandidate(candidates []schedulableCandidate, pod *v1.Pod, podReq PodRequest, cfg *SchedulingConfig, scorers []ScorePlugin, extenders []ExtenderConfig) string {
+	extenderScores, err := runExtenderPrioritize(extenders, pod, podReq, candidates, cfg.MaxScore)
+	if err != nil {
+		log.Printf("Warning: extender /prioritize failed, continuing with in-process scores only: %v", err)
+		extenderScores = nil
+	}
+
+	var bestNode string
+	var bestScore = math.Inf(-1)
+	for _, c := range candidates {
+		total := aggregateScore(scorers, cfg, scoreArgs{pod: pod, podReq: podReq, node: c.node, stats: c.stats, maxScore: cfg.MaxScore})
+		total += extenderScores[c.node.Name]
+		log.Printf("Node %s scored %.2f", c.node.Name, total)
+		if total > bestScore {
+			bestScore = total
+			bestNode = c.node.Name
+		}
+	}
+	return bestNode
+}
+
+// runSchedulingCycle runs the Filter phase over every Ready, schedulable
+// node in nodes, attempting preemption on nodes that only failed the
+// resource filter when enablePreemption is set, then runs the Score phase
+// over whatever nodes passed and returns the highest-scoring one's name.
+// It returns "" with a nil error if no node is schedulable, after emitting a
+// FailedScheduling event (and PodScheduled=False condition) on pod
+// aggregating every rejected node's reason, kube-scheduler-style. recorder
+// may be nil, e.g. for callers that don't have a Pod object worth emitting
+// Events against. graceTimeout bounds how long it waits for a preempted
+// node's victims to actually leave before re-reading stats and considering
+// that node a candidate; 0 skips the wait entirely. This blocks until
+// victims leave or graceTimeout elapses, which is fine for the CLI
+// diagnostic path in main() but not for the watch-mode controller — see
+// scheduleOrNominate for the non-blocking equivalent used there.
+func runSchedulingCycle(client kubernetes.Interface, recorder record.EventRecorder, nodes []v1.Node, pod *v1.Pod, podReq PodRequest, metricsProvider MetricsProvider, alpha float64, enablePreemption bool, thresholds EvictionThresholds, graceTimeout time.Duration, cfg *SchedulingConfig, filters []FilterPlugin, scorers []ScorePlugin, extenders []ExtenderConfig) (string, error) {
+	statsFn := func(node *v1.Node) (NodeStats, error) { return metricsProvider.NodeStats(client, node) }
+	candidates, preemptionCandidates, failureReasons := classifyNodes(client, nodes, pod, podReq, statsFn, enablePreemption, thresholds, filters)
+
+	// Only fall back to preempting when no node is schedulable as-is. Try
+	// preemption candidates in order of least disruption; if a candidate's
+	// victims don't actually leave the node within graceTimeout, abandon it
+	// and try the next one rather than binding against resources that
+	// haven't been freed yet.
+	if len(candidates) == 0 && len(preemptionCandidates) > 0 {
+		sortByLeastDisruption(preemptionCandidates)
+
+		forceMode := podReq.Priority >= systemCriticalThreshold()
+		for _, pc := range preemptionCandidates {
+			victims, err := callExtenderPreempt(extenders, pod, podReq, map[string][]RunningPod{pc.node.Name: pc.victims})
+			if err != nil {
+				log.Printf("Extender /preempt rejected node %s: %v", pc.node.Name, err)
+				continue
+			}
+			if len(victims[pc.node.Name]) == 0 {
+				log.Printf("Node %s vetoed by an extender's /preempt callback", pc.node.Name)
+				continue
+			}
+
+			if err := evictVictims(client, recorder, pod, pc.node.Name, victims[pc.node.Name], forceMode); err != nil {
+				log.Printf("Preemption on node %s failed: %v", pc.node.Name, err)
+				continue
+			}
+			if gone, stillPresent := waitForVictimsGone(client, victims[pc.node.Name], graceTimeout); !gone {
+				log.Printf("Abandoning node %s: victim %s did not leave within %s", pc.node.Name, stillPresent, graceTimeout)
+				continue
+			}
+
+			stats, err := metricsProvider.NodeStats(client, pc.node)
+			if err != nil {
+				log.Printf("Error re-fetching stats for node %s after preemption: %v", pc.node.Name, err)
+				continue
+			}
+			if ok, failedPlugin, reason := runFilters(filters, filterArgs{client: client, pod: pod, podReq: podReq, node: pc.node, stats: stats, thresholds: thresholds, allNodes: nodes}); ok {
+				candidates = append(candidates, schedulableCandidate{node: pc.node, stats: stats})
+				break
+			} else {
+				log.Printf("Node %s still rejected by %s filter after preemption: %s", pc.node.Name, failedPlugin, reason)
+				failureReasons[reason]++
+			}
+		}
+	}
+
+	candidates, err := runExtenderFilters(extenders, pod, podReq, candidates)
+	if err != nil {
+		return "", fmt.Errorf("extender /filter failed: %w", err)
+	}
+	if len(candidates) == 0 {
+		recordFailedScheduling(client, recorder, pod, formatFailureSummary(len(nodes), failureReasons))
+		return "", nil
+	}
+	return pickBestCandidate(candidates, pod, podReq, cfg, scorers, extenders), nil
+}
+
+// scheduleOrNominate is the watch-mode, non-blocking counterpart to
+// runSchedulingCycle: it reads nodes and their stats from schedCache
+// instead of listing Nodes and querying a MetricsProvider directly, so a
+// burst of pending pods doesn't turn into O(pending pods x nodes) API and
+// metrics calls. If a node fits pod outright it's scored, assumed bound on
+// schedCache (so the next pending pod sees the reduced capacity before the
+// next refresh tick), and returned for immediate binding (nominated=false).
+// If only preemption would make a node fit, it evicts the least-disruptive
+// candidate's victims, assumes pod there too, records a nomination for
+// pod.UID in tracker (so a later scheduling attempt can fast-path straight
+// to a bind check instead of re-running the whole Filter phase), patches
+// pod.Status.NominatedNodeName, and returns immediately with
+// nominated=true instead of blocking on the victims actually leaving. It
+// emits a FailedScheduling event (and PodScheduled=False condition) on pod
+// when no node fits even with preemption; recorder may be nil.
+func scheduleOrNominate(schedCache *SchedulerCache, recorder record.EventRecorder, pod *v1.Pod, podReq PodRequest, enablePreemption bool, thresholds EvictionThresholds, cfg *SchedulingConfig, filters []FilterPlugin, scorers []ScorePlugin, extenders []ExtenderConfig, tracker *nominationTracker, nominationTTL time.Duration) (nodeName string, nominated bool, err error) {
+	client := schedCache.client
+	nodes, statsByName := schedCache.Snapshot()
+	statsFn := func(node *v1.Node) (NodeStats, error) {
+		stats, ok := statsByName[node.Name]
+		if !ok {
+			return NodeStats{}, fmt.Errorf("no cached stats for node %s yet", node.Name)
+		}
+		return stats, nil
+	}
+
+	candidates, preemptionCandidates, failureReasons := classifyNodes(client, nodes, pod, podReq, statsFn, enablePreemption, thresholds, filters)
+	candidates, err = runExtenderFilters(extenders, pod, podReq, candidates)
+	if err != nil {
+		return "", false, fmt.Errorf("extender /filter failed: %w", err)
+	}
+	if len(candidates) > 0 {
+		best := pickBestCandidate(candidates, pod, podReq, cfg, scorers, extenders)
+		schedCache.AssumePod(best, pod.UID, podReq)
+		return best, false, nil
+	}
+	if len(preemptionCandidates) == 0 {
+		recordFailedScheduling(client, recorder, pod, formatFailureSummary(len(nodes), failureReasons))
+		return "", false, nil
+	}
+
+	sortByLeastDisruption(preemptionCandidates)
+	best := preemptionCandidates[0]
+
+	victims, err := callExtenderPreempt(extenders, pod, podReq, map[string][]RunningPod{best.node.Name: best.victims})
+	if err != nil {
+		return "", false, fmt.Errorf("extender /preempt rejected node %s: %w", best.node.Name, err)
+	}
+	if len(victims[best.node.Name]) == 0 {
+		recordFailedScheduling(client, recorder, pod, fmt.Sprintf("0/%d nodes are available: preemption vetoed by an extender on node %s.", len(nodes), best.node.Name))
+		return "", false, nil
+	}
+
+	forceMode := podReq.Priority >= systemCriticalThreshold()
+	if err := evictVictims(client, recorder, pod, best.node.Name, victims[best.node.Name], forceMode); err != nil {
+		return "", false, fmt.Errorf("preemption on node %s failed: %w", best.node.Name, err)
+	}
+
+	schedCache.AssumePod(best.node.Name, pod.UID, podReq)
+	tracker.Set(pod.UID, best.node.Name, victims[best.node.Name], nominationTTL)
+	if err := patchNominatedNodeName(client, pod, best.node.Name); err != nil {
+		log.Printf("Warning: failed to patch NominatedNodeName=%s on pod %s/%s: %v", best.node.Name, pod.Namespace, pod.Name, err)
+	}
+	return best.node.Name, true, nil
+}
+
+// nodeStillFits re-runs the Filter phase for pod against the single node
+// named nodeName, read from schedCache, used by the nominated-node fast
+// path once a preemption's victims have actually left: a higher-priority
+// pod may have raced in and taken the freed capacity, in which case the
+// nomination must be abandoned rather than binding against resources that
+// are no longer free.
+func nodeStillFits(schedCache *SchedulerCache, nodeName string, pod *v1.Pod, podReq PodRequest, thresholds EvictionThresholds, filters []FilterPlugin) (bool, error) {
+	nodes, statsByName := schedCache.Snapshot()
+
+	var node *v1.Node
+	for i := range nodes {
+		if nodes[i].Name == nodeName {
+			node = &nodes[i]
+			break
+		}
+	}
+	if node == nil {
+		return false, nil
+	}
+
+	stats, ok := statsByName[nodeName]
+	if !ok {
+		return false, fmt.Errorf("no cached stats for node %s yet", nodeName)
+	}
+
+	ok, failedPlugin, reason := runFilters(filters, filterArgs{client: schedCache.client, pod: pod, podReq: podReq, node: node, stats: stats, thresholds: thresholds, allNodes: nodes})
+	if !ok {
+		log.Printf("Nominated node %s no longer fits pod %s/%s: rejected by %s filter: %s", nodeName, pod.Namespace, pod.Name, failedPlugin, reason)
+	}
+	return ok, nil
+}
+
+// runFilters runs plugins in order, short-circuiting (and naming itself
+// via Name()) on the first rejection.
+func runFilters(plugins []FilterPlugin, args filterArgs) (ok bool, failedPlugin, reason string) {
+	for _, p := range plugins {
+		if ok, reason := p.Filter(args); !ok {
+			return false, p.Name(), reason
+		}
+	}
+	return true, "", ""
+}
+
+// aggregateScore combines every ScorePlugin's output into a single
+// weighted score, using cfg.ScoreWeights (falling back to 0 for any
+// plugin left out of the config, and to 1.0 total if every weight is 0).
+func aggregateScore(scorers []ScorePlugin, cfg *SchedulingConfig, args scoreArgs) float64 {
+	var weightedTotal, weightSum float64
+	for _, s := range scorers {
+		weight, ok := cfg.ScoreWeights[s.Name()]
+		if !ok {
+			continue
+		}
+		weightedTotal += float64(s.Score(args)) * weight
+		weightSum += weight
+	}
+	if weightSum == 0 {
+		return 0
+	}
+	return weightedTotal / weightSum
+}
+
+// ---------- Filter plugins ----------
+
+// resourceFilterPlugin wraps the existing canScheduleMulti resource/
+// eviction-threshold/node-condition check.
+type resourceFilterPlugin struct{ alpha float64 }
+
+func (resourceFilterPlugin) Name() string { return "resource" }
+
+func (p resourceFilterPlugin) Filter(args filterArgs) (bool, string) {
+	return canScheduleMulti(args.podReq, args.stats, p.alpha, args.node, args.thresholds)
+}
+
+// taintTolerationFilterPlugin rejects a node if any of its NoSchedule or
+// NoExecute taints isn't tolerated by the pod, mirroring the kubelet's own
+// TaintToleration admission rule.
+type taintTolerationFilterPlugin struct{}
+
+func (taintTolerationFilterPlugin) Name() string { return "taintToleration" }
+
+func (taintTolerationFilterPlugin) Filter(args filterArgs) (bool, string) {
+	for _, taint := range args.node.Spec.Taints {
+		if taint.Effect != v1.TaintEffectNoSchedule && taint.Effect != v1.TaintEffectNoExecute {
+			continue
+		}
+		if !tolerated(args.pod.Spec.Tolerations, taint) {
+			return false, fmt.Sprintf("untolerated taint %s=%s:%s", taint.Key, taint.Value, taint.Effect)
+		}
+	}
+	return true, ""
+}
+
+// tolerated reports whether any toleration in tolerations covers taint.
+func tolerated(tolerations []v1.Toleration, taint v1.Taint) bool {
+	for _, t := range tolerations {
+		if t.Effect != "" && t.Effect != taint.Effect {
+			continue
+		}
+		switch t.Operator {
+		case v1.TolerationOpExists:
+			if t.Key == "" || t.Key == taint.Key {
+				return true
+			}
+		case "", v1.TolerationOpEqual:
+			if t.Key == taint.Key && t.Value == taint.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nodeAffinityFilterPlugin rejects a node that doesn't satisfy the pod's
+// required node affinity (the label-based subset of NodeSelectorTerms;
+// field selectors like metadata.name aren't evaluated).
+type nodeAffinityFilterPlugin struct{}
+
+func (nodeAffinityFilterPlugin) Name() string { return "nodeAffinity" }
+
+func (nodeAffinityFilterPlugin) Filter(args filterArgs) (bool, string) {
+	affinity := args.pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true, ""
+	}
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if matchNodeSelectorTerms(terms, args.node.Labels) {
+		return true, ""
+	}
+	return false, "does not match required node affinity"
+}
+
+// matchNodeSelectorTerms reports whether labels satisfies at least one of
+// terms (terms are OR'd; each term's expressions are AND'd), per the
+// NodeSelectorTerm contract.
+func matchNodeSelectorTerms(terms []v1.NodeSelectorTerm, nodeLabels map[string]string) bool {
+	if len(terms) == 0 {
+		return false
+	}
+	for _, term := range terms {
+		if matchNodeSelectorTerm(term, nodeLabels) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchNodeSelectorTerm(term v1.NodeSelectorTerm, nodeLabels map[string]string) bool {
+	if len(term.MatchFields) > 0 {
+		return false
+	}
+	for _, expr := range term.MatchExpressions {
+		value, present := nodeLabels[expr.Key]
+		switch expr.Operator {
+		case v1.NodeSelectorOpIn:
+			if !present || !containsString(expr.Values, value) {
+				return false
+			}
+		case v1.NodeSelectorOpNotIn:
+			if present && containsString(expr.Values, value) {
+				return false
+			}
+		case v1.NodeSelectorOpExists:
+			if !present {
+				return false
+			}
+		case v1.NodeSelectorOpDoesNotExist:
+			if present {
+				return false
+			}
+		default:
+			// Gt/Lt and field selectors aren't supported; treat as non-matching
+			// rather than silently ignoring an operator the pod author relied on.
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// podAffinityFilterPlugin approximates pod (anti-)affinity by treating
+// every topology key as "same node" (this scheduler doesn't track a node's
+// region/zone topology), checking required terms against pods already
+// bound to the candidate node.
+type podAffinityFilterPlugin struct{}
+
+func (podAffinityFilterPlugin) Name() string { return "podAffinity" }
+
+func (podAffinityFilterPlugin) Filter(args filterArgs) (bool, string) {
+	affinity := args.pod.Spec.Affinity
+	if affinity == nil || (affinity.PodAffinity == nil && affinity.PodAntiAffinity == nil) {
+		return true, ""
+	}
+
+	nodePods, err := args.client.CoreV1().Pods(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + args.node.Name,
+	})
+	if err != nil {
+		log.Printf("Warning: podAffinity filter could not list pods on node %s, assuming it matches: %v", args.node.Name, err)
+		return true, ""
+	}
+
+	if affinity.PodAffinity != nil {
+		for _, term := range affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			if !anyPodMatchesAffinityTerm(term, args.pod.Namespace, nodePods.Items) {
+				return false, "no existing pod on the node satisfies required pod affinity"
+			}
+		}
+	}
+	if affinity.PodAntiAffinity != nil {
+		for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			if anyPodMatchesAffinityTerm(term, args.pod.Namespace, nodePods.Items) {
+				return false, "an existing pod on the node violates required pod anti-affinity"
+			}
+		}
+	}
+	return true, ""
+}
+
+func anyPodMatchesAffinityTerm(term v1.PodAffinityTerm, podNamespace string, candidates []v1.Pod) bool {
+	selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+	if err != nil {
+		log.Printf("Warning: invalid pod affinity label selector: %v", err)
+		return false
+	}
+	namespaces := map[string]bool{podNamespace: true}
+	for _, ns := range term.Namespaces {
+		namespaces[ns] = true
+	}
+	for _, candidate := range candidates {
+		if !namespaces[candidate.Namespace] {
+			continue
+		}
+		if selector.Matches(labels.Set(candidate.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// topologySpreadFilterPlugin rejects a candidate node if placing the pod
+// there would push a DoNotSchedule constraint's skew (the gap between the
+// most- and least-loaded topology domain) beyond maxSkew.
+type topologySpreadFilterPlugin struct{}
+
+func (topologySpreadFilterPlugin) Name() string { return "topologySpread" }
+
+func (topologySpreadFilterPlugin) Filter(args filterArgs) (bool, string) {
+	for _, constraint := range args.pod.Spec.TopologySpreadConstraints {
+		if constraint.WhenUnsatisfiable != v1.DoNotSchedule {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+		if err != nil {
+			log.Printf("Warning: invalid topology spread label selector: %v", err)
+			continue
+		}
+
+		domainValue, ok := args.node.Labels[constraint.TopologyKey]
+		if !ok {
+			continue
+		}
+
+		counts, err := countPodsPerTopologyDomain(args.client, constraint.TopologyKey, args.allNodes, selector)
+		if err != nil {
+			log.Printf("Warning: could not compute topology spread counts for key %s, allowing: %v", constraint.TopologyKey, err)
+			continue
+		}
+
+		minCount := counts[domainValue]
+		for _, c := range counts {
+			if c < minCount {
+				minCount = c
+			}
+		}
+		if int32(counts[domainValue]+1-minCount) > constraint.MaxSkew {
+			return false, fmt.Sprintf("would violate topology spread constraint on %s (maxSkew=%d)", constraint.TopologyKey, constraint.MaxSkew)
+		}
+	}
+	return true, ""
+}
+
+// countPodsPerTopologyDomain returns, for each distinct value of
+// topologyKey across nodes, how many pods matching selector (in any
+// namespace and any phase) are bound to nodes with that value.
+func countPodsPerTopologyDomain(client kubernetes.Interface, topologyKey string, nodes []v1.Node, selector labels.Selector) (map[string]int, error) {
+	nodeToDomain := make(map[string]string, len(nodes))
+	counts := make(map[string]int, len(nodes))
+	for _, n := range nodes {
+		if domain, ok := n.Labels[topologyKey]; ok {
+			nodeToDomain[n.Name] = domain
+			if _, seen := counts[domain]; !seen {
+				counts[domain] = 0
+			}
+		}
+	}
+
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if domain, ok := nodeToDomain[pod.Spec.NodeName]; ok {
+			counts[domain]++
+		}
+	}
+	return counts, nil
+}
+
+// volumeBindingFilterPlugin rejects a node that conflicts with the node
+// affinity of a PersistentVolume already bound to one of the pod's PVCs
+// (the common case for local/topology-restricted volumes).
+type volumeBindingFilterPlugin struct{}
+
+func (volumeBindingFilterPlugin) Name() string { return "volumeBinding" }
+
+func (p volumeBindingFilterPlugin) Filter(args filterArgs) (bool, string) {
+	for _, vol := range args.pod.Spec.Volumes {
+		if vol.PersistentVolumeClaim == nil {
+			continue
+		}
+		pvc, err := args.client.CoreV1().PersistentVolumeClaims(args.pod.Namespace).Get(context.Background(), vol.PersistentVolumeClaim.ClaimName, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("Warning: volumeBinding filter could not fetch PVC %s/%s, allowing: %v", args.pod.Namespace, vol.PersistentVolumeClaim.ClaimName, err)
+			continue
+		}
+		if pvc.Spec.VolumeName == "" {
+			continue // unbound, nothing to check yet
+		}
+		pv, err := args.client.CoreV1().PersistentVolumes().Get(context.Background(), pvc.Spec.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("Warning: volumeBinding filter could not fetch PV %s, allowing: %v", pvc.Spec.VolumeName, err)
+			continue
+		}
+		if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+			continue
+		}
+		if !matchNodeSelectorTerms(pv.Spec.NodeAffinity.Required.NodeSelectorTerms, args.node.Labels) {
+			return false, fmt.Sprintf("PersistentVolume %s's node affinity excludes this node", pv.Name)
+		}
+	}
+	return true, ""
+}
+
+// resourceUsage holds the fraction (in [0, 1]) of each tracked resource
+// that would be in use on a node if podReq were placed there.
+type resourceUsage struct {
+	cpu, mem, diskRead, diskWrite, netUp, netDown float64
+}
+
+// computeResourceUsage projects podReq onto stats and clamps each
+// resulting usage fraction to [0, 1], so every score plugin agrees on
+// what "usage" means.
+func computeResourceUsage(podReq PodRequest, stats NodeStats) resourceUsage {
+	clamp := func(v float64) float64 { return math.Max(0.0, math.Min(1.0, v)) }
+
+	usage := func(free, total, req float64) float64 {
+		if total <= 0 {
+			return 0
+		}
+		return clamp((total - free + req) / total)
+	}
+
+	return resourceUsage{
+		cpu:       usage(stats.CPUFree, stats.CPUTotal, podReq.CPU),
+		mem:       usage(stats.MemFree, stats.MemTotal, podReq.Mem),
+		diskRead:  usage(stats.DiskReadFree, stats.DiskReadTotal, podReq.DiskRead),
+		diskWrite: usage(stats.DiskWriteFree, stats.DiskWriteTotal, podReq.DiskWrite),
+		netUp:     usage(stats.NetUpFree, stats.NetUpTotal, podReq.NetUp),
+		netDown:   usage(stats.NetDownFree, stats.NetDownTotal, podReq.NetDown),
+	}
+}
+
+// ---------- Score plugins ----------
+
+// resourceScorePlugin wraps the existing dominant-share scoreMultiResource.
+type resourceScorePlugin struct{}
+
+func (resourceScorePlugin) Name() string { return "resource" }
+
+func (resourceScorePlugin) Score(args scoreArgs) int {
+	return scoreMultiResource(args.podReq, args.stats, args.maxScore)
+}
+
+// leastRequestedScorePlugin favors nodes with the most headroom left
+// across all six tracked resources after the pod lands, like upstream
+// Kubernetes's LeastRequested priority generalized beyond cpu/mem.
+type leastRequestedScorePlugin struct{}
+
+func (leastRequestedScorePlugin) Name() string { return "leastRequested" }
+
+func (leastRequestedScorePlugin) Score(args scoreArgs) int {
+	u := computeResourceUsage(args.podReq, args.stats)
+	avgUsage := (u.cpu + u.mem + u.diskRead + u.diskWrite + u.netUp + u.netDown) / 6.0
+	return int((1.0 - avgUsage) * float64(args.maxScore))
+}
+
+// balancedAllocationScorePlugin favors nodes where the pod's placement
+// keeps all six tracked resources at a similar utilization, avoiding nodes
+// that would become lopsided (e.g. CPU-starved but memory-idle).
+type balancedAllocationScorePlugin struct{}
+
+func (balancedAllocationScorePlugin) Name() string { return "balancedAllocation" }
+
+func (balancedAllocationScorePlugin) Score(args scoreArgs) int {
+	u := computeResourceUsage(args.podReq, args.stats)
+	usages := []float64{u.cpu, u.mem, u.diskRead, u.diskWrite, u.netUp, u.netDown}
+
+	var mean float64
+	for _, v := range usages {
+		mean += v
+	}
+	mean /= float64(len(usages))
+
+	var variance float64
+	for _, v := range usages {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(usages))
+
+	return int((1.0 - math.Sqrt(variance)) * float64(args.maxScore))
+}
+
+// imageLocalityScorePlugin favors nodes that already have the pod's
+// container images pulled, by checking node.Status.Images, sparing a slow
+// image pull on the hot path of pod startup.
+type imageLocalityScorePlugin struct{}
+
+func (imageLocalityScorePlugin) Name() string { return "imageLocality" }
+
+func (imageLocalityScorePlugin) Score(args scoreArgs) int {
+	if len(args.pod.Spec.Containers) == 0 {
+		return args.maxScore / 2
+	}
+
+	present := 0
+	for _, container := range args.pod.Spec.Containers {
+		if nodeHasImage(args.node, container.Image) {
+			present++
+		}
+	}
+	fraction := float64(present) / float64(len(args.pod.Spec.Containers))
+	return int(fraction * float64(args.maxScore))
+}
+
+func nodeHasImage(node *v1.Node, image string) bool {
+	for _, img := range node.Status.Images {
+		for _, name := range img.Names {
+			if name == image || strings.HasSuffix(name, "/"+image) {
+				return true
+			}
+		}
+	}
+	return false
+}
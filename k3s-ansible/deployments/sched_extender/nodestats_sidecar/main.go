@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"sched_extender/pkg/sched_extension"
+)
+
+func main() {
+	var port int
+	flag.IntVar(&port, "port", 9101, "Port to serve /nodestats on")
+	flag.Parse()
+
+	http.HandleFunc("/nodestats", sched_extension.NodeStatsSidecarHandler)
+
+	log.Printf("Starting gopsutil NodeStats sidecar on port %d", port)
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil); err != nil {
+		log.Fatalf("sidecar HTTP server error: %v", err)
+	}
+}
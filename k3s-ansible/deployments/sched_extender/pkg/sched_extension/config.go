@@ -0,0 +1,106 @@
+package sched_extension
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// ResourceWeights lets operators bias scoring toward particular resources
+// without recompiling.
+type ResourceWeights struct {
+	CPU  float64 `yaml:"cpu"`
+	Mem  float64 `yaml:"mem"`
+	Disk float64 `yaml:"disk"`
+	Net  float64 `yaml:"net"`
+}
+
+// FilterThresholds carries the minimum free-fraction a node must retain,
+// per resource, after admitting a pod, for use by CanScheduleMulti.
+type FilterThresholds struct {
+	MinCPUFreeFraction  float64 `yaml:"minCPUFreeFraction"`
+	MinMemFreeFraction  float64 `yaml:"minMemFreeFraction"`
+	MinDiskFreeFraction float64 `yaml:"minDiskFreeFraction"`
+	MinNetFreeFraction  float64 `yaml:"minNetFreeFraction"`
+}
+
+// Config holds the extender's scoring/filtering configuration. It is
+// loaded once from MULTIRESOURCE_CONFIG and hot-reloaded on change instead
+// of requiring a pod restart.
+type Config struct {
+	Alpha            float64          `yaml:"alpha"`
+	MaxScore         int              `yaml:"maxScore"`
+	Weights          ResourceWeights  `yaml:"weights"`
+	FilterThresholds FilterThresholds `yaml:"filterThresholds"`
+}
+
+// defaultConfig mirrors the historical env-var defaults (alpha=0.8,
+// maxScore=100) so a missing MULTIRESOURCE_CONFIG behaves the same as
+// before this change.
+func defaultConfig() *Config {
+	return &Config{
+		Alpha:    0.8,
+		MaxScore: 100,
+		Weights:  ResourceWeights{CPU: 0.4, Mem: 0.3, Disk: 0.15, Net: 0.15},
+	}
+}
+
+// LoadConfig reads and parses a Config from a YAML file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file %s: %v", path, err)
+	}
+	cfg := defaultConfig()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// watchConfig watches path for writes and calls onReload with the newly
+// parsed Config each time it changes. It runs until the watcher errors out
+// irrecoverably, logging reload failures without giving up (a bad write
+// mid-save should not crash the extender).
+func watchConfig(path string, onReload func(*Config)) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Warning: could not start config watcher for %s: %v", path, err)
+		return
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(path); err != nil {
+		log.Printf("Warning: could not watch config file %s: %v", path, err)
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				log.Printf("Error reloading config from %s: %v", path, err)
+				configReloadsVec.WithLabelValues("error").Inc()
+				continue
+			}
+			log.Printf("Reloaded MultiResource config from %s: %+v", path, *cfg)
+			configReloadsVec.WithLabelValues("success").Inc()
+			onReload(cfg)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Config watcher error for %s: %v", path, err)
+		}
+	}
+}
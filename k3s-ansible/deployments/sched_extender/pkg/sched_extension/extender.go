@@ -1,6 +1,7 @@
 package sched_extension
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,53 +9,217 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	schedulerapi "k8s.io/kube-scheduler/extender/v1"
 )
 
 // MultiResourceExtender implements the scheduler extender interface
 type MultiResourceExtender struct {
-	alpha    float64
-	maxScore int
+	// config holds the live scoring/filtering configuration. It is read
+	// fresh on every Filter/Prioritize call and swapped atomically by
+	// watchConfig when MULTIRESOURCE_CONFIG changes on disk, so a reload
+	// never blocks or drops an in-flight request.
+	config atomic.Pointer[Config]
 	// A thread-safe cache of NodeStats keyed by node name.
 	cache sync.RWMutex
 	// Map from node name to NodeStats.
 	nodeStats map[string]NodeStats
+	// Map from node name to its recent NodeStatsHistory window, used for
+	// trend-aware scoring.
+	nodeHistory map[string]NodeStatsHistory
+	// lastStatsRefresh records when nodeStats was last fully repopulated, for
+	// the extender_cache_staleness_seconds gauge.
+	lastStatsRefresh time.Time
+
+	// k8sClient is used to resolve node internal IPs for the sidecar
+	// fallback source; nil if no in-cluster/kubeconfig credentials were
+	// available, in which case the sidecar source is skipped.
+	k8sClient kubernetes.Interface
+	// statsSources is tried in order by refreshViaFallbackSources when the
+	// batched Prometheus collection in updateAllNodeStats can't discover
+	// any nodes at all.
+	statsSources StatsSourceChain
+
+	// sharedCache lets every replica read a consistent NodeStats snapshot
+	// regardless of which one won the leader election; nil if neither
+	// Redis nor a Kubernetes client is available, in which case this
+	// replica always acts as its own leader.
+	sharedCache SharedCache
+	// isLeader is true for the single replica currently running
+	// startNodeStatsUpdater; followers serve requests out of sharedCache
+	// instead of querying Prometheus themselves.
+	isLeader atomic.Bool
+}
+
+// buildK8sClient returns an in-cluster client, falling back to KUBECONFIG
+// or ~/.kube/config, or nil if neither is available.
+func buildK8sClient() kubernetes.Interface {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		kubeconfig := os.Getenv("KUBECONFIG")
+		if kubeconfig == "" {
+			kubeconfig = os.Getenv("HOME") + "/.kube/config"
+		}
+		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+		if err != nil {
+			log.Printf("Warning: no Kubernetes credentials available, sidecar stats source disabled: %v", err)
+			return nil
+		}
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Printf("Warning: error creating Kubernetes client: %v", err)
+		return nil
+	}
+	return client
+}
+
+// sidecarPort returns the port the gopsutil sidecar listens on, configurable
+// via MULTIRESOURCE_SIDECAR_PORT (default 9101).
+func sidecarPort() int {
+	port := 9101
+	if val := os.Getenv("MULTIRESOURCE_SIDECAR_PORT"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			port = parsed
+		}
+	}
+	return port
 }
 
 // NewMultiResourceExtender creates a new scheduler extender for multi-resource scheduling
 func NewMultiResourceExtender() *MultiResourceExtender {
-	// Read alpha and maxScore from environment variables.
-	alpha := 0.8
+	initial := defaultConfig()
+	// MULTIRESOURCE_ALPHA/MULTIRESOURCE_MAXSCORE remain supported so an
+	// operator without a config file keeps the old env-var behavior.
 	if val := os.Getenv("MULTIRESOURCE_ALPHA"); val != "" {
 		if a, err := strconv.ParseFloat(val, 64); err == nil {
-			alpha = a
+			initial.Alpha = a
 		}
 	}
-	maxScore := 100
 	if val := os.Getenv("MULTIRESOURCE_MAXSCORE"); val != "" {
 		if ms, err := strconv.Atoi(val); err == nil {
-			maxScore = ms
+			initial.MaxScore = ms
+		}
+	}
+
+	configPath := os.Getenv("MULTIRESOURCE_CONFIG")
+	if configPath != "" {
+		if cfg, err := LoadConfig(configPath); err != nil {
+			log.Printf("Warning: could not load config from %s, using defaults/env vars: %v", configPath, err)
+		} else {
+			initial = cfg
 		}
 	}
 
 	ext := &MultiResourceExtender{
-		alpha:     alpha,
-		maxScore:  maxScore,
-		nodeStats: make(map[string]NodeStats),
+		nodeStats:   make(map[string]NodeStats),
+		nodeHistory: make(map[string]NodeStatsHistory),
+		k8sClient:   buildK8sClient(),
 	}
+	ext.config.Store(initial)
+	ext.statsSources = StatsSourceChain{
+		PrometheusSource{},
+		SidecarSource{Port: sidecarPort(), NodeIP: ext.nodeInternalIP},
+	}
+	ext.sharedCache = buildSharedCache(ext.k8sClient)
 
-	// Start background updater to refresh NodeStats cache.
-	go ext.startNodeStatsUpdater()
+	if configPath != "" {
+		go watchConfig(configPath, func(cfg *Config) {
+			ext.config.Store(cfg)
+		})
+	}
+
+	// Only one replica should hammer Prometheus: without a Kubernetes
+	// client there's no way to run leader election, so this replica just
+	// assumes it's alone. With one, it contends for the Lease and only
+	// runs the updater while it holds it.
+	if ext.k8sClient == nil {
+		ext.isLeader.Store(true)
+		go ext.startNodeStatsUpdater(context.Background())
+	} else {
+		go runLeaderElection(context.Background(), ext.k8sClient,
+			func(ctx context.Context) {
+				ext.isLeader.Store(true)
+				ext.startNodeStatsUpdater(ctx)
+			},
+			func() {
+				ext.isLeader.Store(false)
+			},
+		)
+	}
 
 	return ext
 }
 
+// nodeInternalIP resolves a node's InternalIP via the Kubernetes API, for
+// use by the sidecar StatsSource.
+func (e *MultiResourceExtender) nodeInternalIP(nodeName string) (string, error) {
+	if e.k8sClient == nil {
+		return "", fmt.Errorf("no Kubernetes client available")
+	}
+	node, err := e.k8sClient.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("getting node %s: %v", nodeName, err)
+	}
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == v1.NodeInternalIP {
+			return addr.Address, nil
+		}
+	}
+	return "", fmt.Errorf("node %s has no InternalIP address", nodeName)
+}
+
+// refreshViaFallbackSources is used when the batched cluster-wide
+// Prometheus query in updateAllNodeStats fails outright (so no node names
+// could even be discovered): it lists nodes from the Kubernetes API and
+// falls each one through the sidecar source, then defaults.
+func (e *MultiResourceExtender) refreshViaFallbackSources() {
+	if e.k8sClient == nil {
+		log.Printf("Warning: Prometheus unreachable and no Kubernetes client for fallback sources")
+		return
+	}
+	nodes, err := e.k8sClient.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Warning: error listing nodes for fallback stats collection: %v", err)
+		return
+	}
+	for _, node := range nodes.Items {
+		// Skip the Prometheus source (already known unreachable this cycle).
+		stats := e.statsSources[1:].GetNodeStats(node.Name)
+		e.cache.Lock()
+		e.nodeStats[node.Name] = stats
+		e.cache.Unlock()
+		e.writeSharedCache(node.Name, stats)
+	}
+
+	e.recordCacheMetrics()
+}
+
+// writeSharedCache publishes stats for a node to the shared cache so
+// follower replicas can serve requests without querying Prometheus
+// themselves. It is a no-op if no shared cache is configured.
+func (e *MultiResourceExtender) writeSharedCache(nodeName string, stats NodeStats) {
+	if e.sharedCache == nil {
+		return
+	}
+	if err := e.sharedCache.Set(nodeName, stats); err != nil {
+		log.Printf("Warning: error writing shared cache entry for node %s: %v", nodeName, err)
+	}
+}
+
 // Filter filters out nodes that cannot run the pod
 func (e *MultiResourceExtender) Filter(args schedulerapi.ExtenderArgs) *schedulerapi.ExtenderFilterResult {
+	start := time.Now()
+	defer func() { filterDuration.Observe(time.Since(start).Seconds()) }()
+
 	pod := args.Pod
 	nodes := args.Nodes
 	var filteredNodes []v1.Node
@@ -66,12 +231,14 @@ func (e *MultiResourceExtender) Filter(args schedulerapi.ExtenderArgs) *schedule
 		stats, err := e.getNodeStatsFromCache(node.Name)
 		if err != nil {
 			failedNodes[node.Name] = fmt.Sprintf("error retrieving stats: %v", err)
+			nodesFilteredTotal.WithLabelValues("stats_unavailable").Inc()
 			continue
 		}
 
 		podReq := ExtractPodRequirements(pod)
-		if !CanScheduleMulti(podReq, stats, e.alpha) {
+		if !CanScheduleMulti(podReq, stats, e.config.Load().Alpha) {
 			failedNodes[node.Name] = "insufficient resources"
+			nodesFilteredTotal.WithLabelValues("insufficient_resources").Inc()
 			continue
 		}
 
@@ -93,6 +260,9 @@ func (e *MultiResourceExtender) Filter(args schedulerapi.ExtenderArgs) *schedule
 
 // Prioritize assigns scores to nodes based on their resource availability
 func (e *MultiResourceExtender) Prioritize(args schedulerapi.ExtenderArgs) *schedulerapi.HostPriorityList {
+	start := time.Now()
+	defer func() { prioritizeDuration.Observe(time.Since(start).Seconds()) }()
+
 	pod := args.Pod
 	nodes := args.Nodes
 
@@ -112,7 +282,9 @@ func (e *MultiResourceExtender) Prioritize(args schedulerapi.ExtenderArgs) *sche
 			continue
 		}
 
-		score := ScoreMultiResource(podReq, stats, e.maxScore)
+		history := e.getNodeHistoryFromCache(node.Name)
+		score := ScoreMultiResourceTrendAware(podReq, stats, history, e.config.Load().MaxScore)
+		nodeScore.WithLabelValues(node.Name).Set(float64(score))
 		priorityList = append(priorityList, schedulerapi.HostPriority{
 			Host:  node.Name,
 			Score: int64(score),
@@ -122,53 +294,187 @@ func (e *MultiResourceExtender) Prioritize(args schedulerapi.ExtenderArgs) *sche
 	return &priorityList
 }
 
-// startNodeStatsUpdater periodically updates the NodeStats cache for all nodes.
-func (e *MultiResourceExtender) startNodeStatsUpdater() {
+// startNodeStatsUpdater periodically updates the NodeStats cache for all
+// nodes until ctx is canceled, which happens when this replica loses
+// leadership (see runLeaderElection).
+func (e *MultiResourceExtender) startNodeStatsUpdater(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
 	// Initial update
 	e.updateAllNodeStats()
+	e.updateAllNodeStatsHistory()
 
 	for {
-		<-ticker.C
-		e.updateAllNodeStats()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.updateAllNodeStats()
+			e.updateAllNodeStatsHistory()
+		}
 	}
 }
 
-// updateAllNodeStats updates stats for all nodes
+// historyWindow/historyStep control the trend window polled by
+// updateAllNodeStatsHistory: the last 15 minutes at 30s resolution.
+const (
+	historyWindow = 15 * time.Minute
+	historyStep   = 30 * time.Second
+)
+
+// historyRangeQueries are the range-query equivalents of statQueries used
+// to populate each node's NodeStatsHistory.
+var historyRangeQueries = map[string]string{
+	"cpu":       "sum(rate(node_cpu_seconds_total{mode!=\"idle\"}[5m])) by (instance)",
+	"mem":       "node_memory_MemTotal_bytes - node_memory_MemAvailable_bytes",
+	"diskRead":  "rate(node_disk_read_bytes_total[5m])",
+	"diskWrite": "rate(node_disk_written_bytes_total[5m])",
+	"netUp":     "rate(node_network_transmit_bytes_total[5m])",
+	"netDown":   "rate(node_network_receive_bytes_total[5m])",
+}
+
+// updateAllNodeStatsHistory populates a short trend window per node via
+// range queries, once per tick, so Prioritize can penalize nodes whose
+// utilization is climbing even if their instantaneous free resources
+// still look sufficient.
+func (e *MultiResourceExtender) updateAllNodeStatsHistory() {
+	end := time.Now()
+	start := end.Add(-historyWindow)
+
+	newHistory := make(map[string]NodeStatsHistory)
+	for name, query := range historyRangeQueries {
+		series, err := QueryPrometheusRange(query, start, end, historyStep)
+		if err != nil {
+			log.Printf("Warning: Failed to fetch %s history: %v", name, err)
+			continue
+		}
+		for instance, samples := range series {
+			nodeName := strings.Split(instance, ":")[0]
+			h := newHistory[nodeName]
+			switch name {
+			case "cpu":
+				h.CPU = samples
+			case "mem":
+				h.Mem = samples
+			case "diskRead":
+				h.DiskRead = samples
+			case "diskWrite":
+				h.DiskWrite = samples
+			case "netUp":
+				h.NetUp = samples
+			case "netDown":
+				h.NetDown = samples
+			}
+			newHistory[nodeName] = h
+		}
+	}
+
+	e.cache.Lock()
+	for nodeName, h := range newHistory {
+		e.nodeHistory[nodeName] = h
+	}
+	e.cache.Unlock()
+}
+
+// getNodeHistoryFromCache retrieves the trend window for a node, returning
+// an empty history (no penalty applied) if none has been collected yet.
+func (e *MultiResourceExtender) getNodeHistoryFromCache(nodeName string) NodeStatsHistory {
+	e.cache.RLock()
+	defer e.cache.RUnlock()
+	return e.nodeHistory[nodeName]
+}
+
+// fetchCycleTimeout bounds how long a single updateAllNodeStats pass may take,
+// so a slow or unreachable Prometheus cannot stall the scheduler cache.
+func fetchCycleTimeout() time.Duration {
+	if val := os.Getenv("SCHED_FETCH_CYCLE_TIMEOUT"); val != "" {
+		if secs, err := strconv.Atoi(val); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return 20 * time.Second
+}
+
+// updateAllNodeStats refreshes stats for every node in a single pass. Rather
+// than calling GetNodeStats per node (which would repeat the same
+// cluster-wide queries once per node), it runs each PromQL once through the
+// shared worker pool and dispatches the instance-labeled results to the
+// right NodeStats entry.
 func (e *MultiResourceExtender) updateAllNodeStats() {
-	// Here we would normally query the Kubernetes API to get all nodes
-	// For simplicity, we'll use the Prometheus query to discover nodes
-	cpuQuery := "sum(rate(node_cpu_seconds_total{mode!=\"idle\"}[5m])) by (instance)"
-	cpuMetrics, err := QueryPrometheus(cpuQuery)
-	if err != nil {
-		log.Printf("Warning: Failed to fetch CPU metrics: %v", err)
+	ctx, cancel := context.WithTimeout(context.Background(), fetchCycleTimeout())
+	defer cancel()
+
+	results := runQueryPool(ctx, statQueries)
+
+	cpuResult, ok := results["cpu"]
+	if !ok || cpuResult.err != nil {
+		log.Printf("Warning: Failed to fetch CPU metrics: %v", cpuResult.err)
+		e.refreshViaFallbackSources()
 		return
 	}
 
-	// Extract node names from the metrics
-	for instance := range cpuMetrics {
+	// Discover node names from the CPU metrics' instance labels.
+	for instance := range cpuResult.metrics {
 		nodeName := strings.Split(instance, ":")[0]
-		stats, err := GetNodeStats(nodeName)
-		if err != nil {
-			log.Printf("Updater: error getting stats for node %s: %v", nodeName, err)
-			continue
-		}
+		stats := buildNodeStats(nodeName, results)
 		e.cache.Lock()
 		e.nodeStats[nodeName] = stats
 		e.cache.Unlock()
+		e.writeSharedCache(nodeName, stats)
 	}
+
+	e.recordCacheMetrics()
+}
+
+// recordCacheMetrics updates the extender_cache_size and
+// extender_cache_staleness_seconds gauges from the current cache state.
+func (e *MultiResourceExtender) recordCacheMetrics() {
+	e.cache.Lock()
+	e.lastStatsRefresh = time.Now()
+	size := len(e.nodeStats)
+	e.cache.Unlock()
+	cacheSize.Set(float64(size))
+	cacheStaleness.Set(0)
 }
 
-// getNodeStatsFromCache retrieves NodeStats for a node from the local cache.
+// getNodeStatsFromCache retrieves NodeStats for a node. The leader (or a
+// lone replica with no shared cache) serves out of its own local cache,
+// falling through the full StatsSource chain (Prometheus, then the
+// gopsutil sidecar, then defaults) on a miss. A follower instead reads the
+// shared cache so every replica schedules against the same snapshot,
+// rejecting entries older than MULTIRESOURCE_MAX_STAGE_AGE rather than
+// scheduling on stale data.
 func (e *MultiResourceExtender) getNodeStatsFromCache(nodeName string) (NodeStats, error) {
+	if !e.isLeader.Load() && e.sharedCache != nil {
+		stats, writtenAt, ok := e.sharedCache.Get(nodeName)
+		if !ok {
+			return NodeStats{}, fmt.Errorf("no shared cache entry for node %s", nodeName)
+		}
+		if age := time.Since(writtenAt); age > maxStaleAge() {
+			return NodeStats{}, fmt.Errorf("shared cache entry for node %s is stale (%s old)", nodeName, age)
+		}
+		return stats, nil
+	}
+
 	e.cache.RLock()
 	stats, exists := e.nodeStats[nodeName]
+	lastRefresh := e.lastStatsRefresh
 	e.cache.RUnlock()
-	if !exists {
+	if exists {
+		if !lastRefresh.IsZero() {
+			cacheStaleness.Set(time.Since(lastRefresh).Seconds())
+		}
+		return stats, nil
+	}
+
+	if len(e.statsSources) == 0 {
 		return NodeStats{}, fmt.Errorf("node stats not available for %s", nodeName)
 	}
+	stats = e.statsSources.GetNodeStats(nodeName)
+	e.cache.Lock()
+	e.nodeStats[nodeName] = stats
+	e.cache.Unlock()
 	return stats, nil
 }
 
@@ -191,6 +497,8 @@ func SetupHTTPServer(port int) *http.Server {
 		w.Write([]byte("OK"))
 	})
 
+	mux.Handle("/metrics", promhttp.Handler())
+
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
 		Handler: mux,
@@ -0,0 +1,77 @@
+package sched_extension
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// leaseName is the Lease object replicas coordinate on, configurable via
+// MULTIRESOURCE_LEASE_NAME so multiple extender deployments can share a
+// namespace without colliding.
+func leaseName() string {
+	if name := os.Getenv("MULTIRESOURCE_LEASE_NAME"); name != "" {
+		return name
+	}
+	return "multiresource-extender"
+}
+
+// leaseIdentity identifies this replica in the Lease, preferring the pod
+// name the Downward API injects and falling back to the hostname.
+func leaseIdentity() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	return fmt.Sprintf("multiresource-extender-%d", time.Now().UnixNano())
+}
+
+// runLeaderElection blocks running the leader election loop until ctx is
+// canceled. onStartedLeading is called (in its own goroutine, per
+// client-go convention) when this replica becomes leader; onStoppedLeading
+// is called when it loses or gives up leadership, so the caller can stop
+// any leader-only background work.
+func runLeaderElection(ctx context.Context, client kubernetes.Interface, onStartedLeading func(context.Context), onStoppedLeading func()) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      leaseName(),
+			Namespace: extenderNamespace(),
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: leaseIdentity(),
+		},
+	}
+
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Printf("%s became leader, starting node stats updater", leaseIdentity())
+				onStartedLeading(ctx)
+			},
+			OnStoppedLeading: func() {
+				log.Printf("%s stopped being leader", leaseIdentity())
+				onStoppedLeading()
+			},
+			OnNewLeader: func(identity string) {
+				if identity != leaseIdentity() {
+					log.Printf("New extender leader elected: %s", identity)
+				}
+			},
+		},
+	})
+}
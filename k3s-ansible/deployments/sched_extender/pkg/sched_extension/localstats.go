@@ -0,0 +1,104 @@
+package sched_extension
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// CollectLocalNodeStats builds a NodeStats from the machine it runs on
+// using gopsutil, so a small sidecar on each node can serve real numbers
+// even when Prometheus/node_exporter is unreachable. It is deliberately
+// coarse: one aggregate sample across all disks/interfaces, matching the
+// granularity GetNodeStats already works with.
+func CollectLocalNodeStats() (NodeStats, error) {
+	var stats NodeStats
+
+	cpuCounts, err := cpu.Counts(true)
+	if err != nil {
+		return stats, err
+	}
+	cpuPercents, err := cpu.Percent(0, false)
+	if err != nil {
+		return stats, err
+	}
+	stats.CPUTotal = float64(cpuCounts)
+	usedFraction := 0.0
+	if len(cpuPercents) > 0 {
+		usedFraction = cpuPercents[0] / 100.0
+	}
+	stats.CPUFree = stats.CPUTotal * (1 - usedFraction)
+
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return stats, err
+	}
+	stats.MemTotal = float64(vm.Total)
+	stats.MemFree = float64(vm.Available)
+
+	ioBefore, err := disk.IOCounters()
+	if err != nil {
+		return stats, err
+	}
+	var diskReadRate, diskWriteRate float64
+	for _, io := range ioBefore {
+		diskReadRate += float64(io.ReadBytes)
+		diskWriteRate += float64(io.WriteBytes)
+	}
+	// Without a second sample we only have cumulative counters; treat them
+	// as a conservative proxy for current throughput and assume headroom
+	// at 3x the observed cumulative rate, mirroring GetNodeStats' estimate.
+	stats.DiskReadTotal = 3 * diskReadRate
+	stats.DiskReadFree = stats.DiskReadTotal - diskReadRate
+	if stats.DiskReadFree < 0 {
+		stats.DiskReadFree = 0
+	}
+	stats.DiskWriteTotal = 3 * diskWriteRate
+	stats.DiskWriteFree = stats.DiskWriteTotal - diskWriteRate
+	if stats.DiskWriteFree < 0 {
+		stats.DiskWriteFree = 0
+	}
+
+	netCounters, err := net.IOCounters(false)
+	if err != nil {
+		return stats, err
+	}
+	var netUpRate, netDownRate float64
+	if len(netCounters) > 0 {
+		netUpRate = float64(netCounters[0].BytesSent)
+		netDownRate = float64(netCounters[0].BytesRecv)
+	}
+	stats.NetUpTotal = 5 * netUpRate
+	stats.NetUpFree = stats.NetUpTotal - netUpRate
+	if stats.NetUpFree < 0 {
+		stats.NetUpFree = 0
+	}
+	stats.NetDownTotal = 5 * netDownRate
+	stats.NetDownFree = stats.NetDownTotal - netDownRate
+	if stats.NetDownFree < 0 {
+		stats.NetDownFree = 0
+	}
+
+	return stats, nil
+}
+
+// NodeStatsSidecarHandler serves /nodestats with the local machine's
+// current NodeStats as JSON. It is meant to run as a DaemonSet sidecar on
+// every node, polled by SidecarSource when Prometheus is unreachable.
+func NodeStatsSidecarHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := CollectLocalNodeStats()
+	if err != nil {
+		log.Printf("Error collecting local node stats: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		log.Printf("Error encoding local node stats: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package sched_extension
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics exposed on /metrics so operators can alert on extender health
+// without scraping logs.
+var (
+	filterDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "extender_filter_duration_seconds",
+		Help: "Time spent in a single /filter call.",
+	})
+	prioritizeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "extender_prioritize_duration_seconds",
+		Help: "Time spent in a single /prioritize call.",
+	})
+	nodesFilteredTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "extender_nodes_filtered_total",
+		Help: "Number of nodes dropped by Filter, by reason.",
+	}, []string{"reason"})
+	nodeScore = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "extender_node_score",
+		Help: "Most recent Prioritize score given to a node.",
+	}, []string{"node"})
+	prometheusQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "extender_prometheus_query_duration_seconds",
+		Help: "Time spent waiting on a single Prometheus query.",
+	}, []string{"query"})
+	cacheStaleness = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "extender_cache_staleness_seconds",
+		Help: "Seconds since the node stats cache was last refreshed.",
+	})
+	cacheSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "extender_cache_size",
+		Help: "Number of nodes currently held in the node stats cache.",
+	})
+	configReloadsVec = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "config_reloads_total",
+		Help: "Number of MULTIRESOURCE_CONFIG hot-reload attempts, by result (success/error).",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		filterDuration,
+		prioritizeDuration,
+		nodesFilteredTotal,
+		nodeScore,
+		prometheusQueryDuration,
+		cacheStaleness,
+		cacheSize,
+		configReloadsVec,
+	)
+}
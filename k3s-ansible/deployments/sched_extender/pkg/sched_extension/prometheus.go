@@ -1,6 +1,7 @@
 package sched_extension
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,9 +9,74 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+// defaultClusterLabel is the label Thanos/federated Prometheus deployments
+// conventionally attach to identify the source cluster of a sample.
+const defaultClusterLabel = "cluster"
+
+// clusterLabelName/clusterLabelValue scope every query this package issues
+// to one cluster when PROMETHEUS_URL points at a federated/Thanos endpoint
+// that aggregates samples from several, by injecting a matcher into each
+// query's first selector (see injectClusterSelector). Both empty means no
+// scoping is applied, matching single-cluster deployments.
+func clusterLabelName() string {
+	name := os.Getenv("PROMETHEUS_CLUSTER_LABEL_NAME")
+	if name == "" && os.Getenv("PROMETHEUS_CLUSTER_LABEL_VALUE") != "" {
+		name = defaultClusterLabel
+	}
+	return name
+}
+
+func clusterLabelValue() string {
+	return os.Getenv("PROMETHEUS_CLUSTER_LABEL_VALUE")
+}
+
+// promIdentifier matches a PromQL metric or function name.
+var promIdentifier = regexp.MustCompile(`[a-zA-Z_:][a-zA-Z0-9_:]*`)
+
+// injectClusterSelector merges the configured cluster label matcher into
+// query's first `{...}` selector block, or, for a query with none, attaches
+// a fresh block to its first metric name (the first identifier not followed
+// by "("), so rate(x[5m]) becomes rate(x{cluster="v"}[5m]). Later
+// selectors in the same query are not scoped. query is unchanged if no
+// cluster label is configured.
+func injectClusterSelector(query string) string {
+	name, value := clusterLabelName(), clusterLabelValue()
+	if name == "" || value == "" {
+		return query
+	}
+	idx := strings.Index(query, "{")
+	if idx == -1 {
+		for _, loc := range promIdentifier.FindAllStringIndex(query, -1) {
+			if !strings.HasPrefix(strings.TrimLeft(query[loc[1]:], " "), "(") {
+				return query[:loc[1]] + fmt.Sprintf("{%s=%q}", name, value) + query[loc[1]:]
+			}
+		}
+		return query
+	}
+	matcher := fmt.Sprintf("%s=%q,", name, value)
+	return query[:idx+1] + matcher + query[idx+1:]
+}
+
+// thanosParams adds the Thanos query-frontend params partial_response and
+// dedup to q when PROMETHEUS_THANOS_PARTIAL/PROMETHEUS_THANOS_DEDUP are set,
+// letting this package talk to a Thanos querier instead of a plain
+// Prometheus server without any other code changes.
+func thanosParams(q url.Values) {
+	if partial, _ := strconv.ParseBool(os.Getenv("PROMETHEUS_THANOS_PARTIAL")); partial {
+		q.Set("partial_response", "true")
+	}
+	if dedup, _ := strconv.ParseBool(os.Getenv("PROMETHEUS_THANOS_DEDUP")); dedup {
+		q.Set("dedup", "true")
+	}
+}
+
 // queryPrometheus sends a query to the Prometheus server and returns the result.
 func QueryPrometheus(query string) (map[string]float64, error) {
 	prometheusURL := os.Getenv("PROMETHEUS_URL")
@@ -20,7 +86,11 @@ func QueryPrometheus(query string) (map[string]float64, error) {
 
 	// Fix: Remove the "/api/v1/query" from the PROMETHEUS_URL if it's already included
 	prometheusBaseURL := strings.TrimSuffix(prometheusURL, "/api/v1/query")
-	queryURL := fmt.Sprintf("%s/api/v1/query?query=%s", prometheusBaseURL, url.QueryEscape(query))
+	query = injectClusterSelector(query)
+	q := url.Values{}
+	q.Set("query", query)
+	thanosParams(q)
+	queryURL := fmt.Sprintf("%s/api/v1/query?%s", prometheusBaseURL, q.Encode())
 	log.Printf("Querying Prometheus URL: %s", queryURL)
 
 	resp, err := http.Get(queryURL)
@@ -114,290 +184,314 @@ func QueryPrometheus(query string) (map[string]float64, error) {
 	return metrics, nil
 }
 
-// getNodeStats gathers metrics from Prometheus for a given node.
-func GetNodeStats(nodeName string) (NodeStats, error) {
-	var stats NodeStats
-	var err error
+// QueryPrometheusRange runs query over [start, end] at the given step using
+// Prometheus's /api/v1/query_range endpoint, returning each instance's
+// sample series in chronological order. It is used to collect short
+// trend windows (e.g. the last 15 minutes) in a single round-trip instead
+// of repeated instant queries.
+func QueryPrometheusRange(query string, start, end time.Time, step time.Duration) (map[string][]float64, error) {
+	prometheusURL := os.Getenv("PROMETHEUS_URL")
+	if prometheusURL == "" {
+		prometheusURL = "http://prometheus-server.default.svc.cluster.local:80"
+	}
+	prometheusBaseURL := strings.TrimSuffix(prometheusURL, "/api/v1/query")
 
-	log.Printf("Fetching stats for node: %s", nodeName)
+	q := url.Values{}
+	q.Set("query", injectClusterSelector(query))
+	q.Set("start", strconv.FormatInt(start.Unix(), 10))
+	q.Set("end", strconv.FormatInt(end.Unix(), 10))
+	q.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	thanosParams(q)
+	queryURL := fmt.Sprintf("%s/api/v1/query_range?%s", prometheusBaseURL, q.Encode())
+	log.Printf("Querying Prometheus range URL: %s", queryURL)
 
-	// ---------- CPU Metrics ----------
-	cpuQuery := "sum(rate(node_cpu_seconds_total{mode!=\"idle\"}[5m])) by (instance)"
-	cpuMetrics, err := QueryPrometheus(cpuQuery)
+	resp, err := http.Get(queryURL)
 	if err != nil {
-		log.Printf("Warning: Failed to fetch CPU metrics: %v", err)
-		stats.CPUTotal = 6.0
-		stats.CPUFree = 2.0
-	} else {
-		var cpuUsage float64
-		var nodeFound bool
-		instanceKey := nodeName + ":9100"
-		if usage, exists := cpuMetrics[instanceKey]; exists {
-			cpuUsage = usage
-			nodeFound = true
-		} else {
-			for instance, usage := range cpuMetrics {
-				if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-					cpuUsage = usage
-					nodeFound = true
-					break
-				}
-			}
-			if !nodeFound && len(cpuMetrics) > 0 {
-				for _, usage := range cpuMetrics {
-					cpuUsage = usage
-					nodeFound = true
-					break
-				}
-			}
-		}
-		if !nodeFound {
-			log.Printf("Warning: Node %s not found in CPU metrics, using default values", nodeName)
-			stats.CPUTotal = 6.0
-			stats.CPUFree = 2.0
-		} else {
-			stats.CPUTotal = 6.0
-			stats.CPUFree = stats.CPUTotal - cpuUsage
-			if stats.CPUFree < 0 {
-				stats.CPUFree = 0
-			}
-		}
+		return nil, fmt.Errorf("error querying Prometheus range: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
 	}
 
-	// ---------- Memory Metrics ----------
-	memTotalQuery := "node_memory_MemTotal_bytes"
-	memFreeQuery := "node_memory_MemAvailable_bytes"
-	memTotalMetrics, err := QueryPrometheus(memTotalQuery)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("Warning: Failed to fetch memory total metrics: %v", err)
-		stats.MemTotal = 32 * 1024 * 1024 * 1024
-	} else {
-		var memTotal float64
-		var nodeFound bool
-		instanceKey := nodeName + ":9100"
-		if total, exists := memTotalMetrics[instanceKey]; exists {
-			memTotal = total
-			nodeFound = true
-		} else {
-			for instance, total := range memTotalMetrics {
-				if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-					memTotal = total
-					nodeFound = true
-					break
-				}
-			}
-			if !nodeFound && len(memTotalMetrics) > 0 {
-				for _, total := range memTotalMetrics {
-					memTotal = total
-					nodeFound = true
-					break
-				}
-			}
-		}
-		if !nodeFound {
-			log.Printf("Warning: Node %s not found in memory metrics, using default values", nodeName)
-			stats.MemTotal = 32 * 1024 * 1024 * 1024
-		} else {
-			stats.MemTotal = memTotal
-		}
+		return nil, fmt.Errorf("error reading Prometheus range response: %v", err)
 	}
 
-	memFreeMetrics, err := QueryPrometheus(memFreeQuery)
-	if err != nil {
-		log.Printf("Warning: Failed to fetch memory free metrics: %v", err)
-		stats.MemFree = 16 * 1024 * 1024 * 1024
-	} else {
-		var memFree float64
-		var nodeFound bool
-		instanceKey := nodeName + ":9100"
-		if free, exists := memFreeMetrics[instanceKey]; exists {
-			memFree = free
-			nodeFound = true
-		} else {
-			for instance, free := range memFreeMetrics {
-				if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-					memFree = free
-					nodeFound = true
-					break
-				}
+	var result struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][]interface{}   `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("error unmarshaling Prometheus range response: %v", err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("Prometheus range query failed: %s", string(body))
+	}
+	if result.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unexpected result type: %s", result.Data.ResultType)
+	}
+
+	series := make(map[string][]float64, len(result.Data.Result))
+	for _, r := range result.Data.Result {
+		instance := r.Metric["instance"]
+		if instance == "" {
+			continue
+		}
+		samples := make([]float64, 0, len(r.Values))
+		for _, v := range r.Values {
+			if len(v) != 2 {
+				continue
 			}
-			if !nodeFound && len(memFreeMetrics) > 0 {
-				for _, free := range memFreeMetrics {
-					memFree = free
-					nodeFound = true
-					break
-				}
+			valueStr, ok := v[1].(string)
+			if !ok {
+				continue
 			}
+			var f float64
+			if _, err := fmt.Sscanf(valueStr, "%f", &f); err != nil {
+				continue
+			}
+			samples = append(samples, f)
 		}
-		if !nodeFound {
-			log.Printf("Warning: Node %s not found in memory metrics, using default values", nodeName)
-			stats.MemFree = 16 * 1024 * 1024 * 1024
-		} else {
-			stats.MemFree = memFree
-		}
+		series[instance] = samples
 	}
+	return series, nil
+}
 
-	// ---------- Disk I/O Metrics ----------
-	// Query disk read rate (bytes per second)
-	diskReadQuery := "rate(node_disk_read_bytes_total[5m])"
-	diskReadMetrics, err := QueryPrometheus(diskReadQuery)
-	if err != nil {
-		log.Printf("Warning: Failed to fetch disk read metrics: %v", err)
-		stats.DiskReadTotal = 500 * 1024 * 1024 // 500 MB/s default
-		stats.DiskReadFree = 300 * 1024 * 1024  // 300 MB/s default
-	} else {
-		diskReadTotal := 0.0
+// statQuery names one of the fixed-resource PromQL calls GetNodeStats depends on.
+type statQuery struct {
+	name  string
+	query string
+}
 
-		// Sum up disk read rates across all disks for this node
-		for instance, readRate := range diskReadMetrics {
-			if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-				diskReadTotal += readRate
-			}
+// statQueries lists the PromQL calls needed to build a NodeStats for any node.
+// They are cluster-wide aggregations (grouped "by (instance)"), so a single
+// run covers every node at once instead of being repeated per node.
+var statQueries = []statQuery{
+	{"cpu", "sum(rate(node_cpu_seconds_total{mode!=\"idle\"}[5m])) by (instance)"},
+	{"memTotal", "node_memory_MemTotal_bytes"},
+	{"memFree", "node_memory_MemAvailable_bytes"},
+	{"diskRead", "rate(node_disk_read_bytes_total[5m])"},
+	{"diskWrite", "rate(node_disk_written_bytes_total[5m])"},
+	{"netUp", "rate(node_network_transmit_bytes_total[5m])"},
+	{"netDown", "rate(node_network_receive_bytes_total[5m])"},
+}
+
+// queryResult carries back one statQuery's outcome for dispatch by the caller.
+type queryResult struct {
+	name    string
+	metrics map[string]float64
+	err     error
+}
+
+// fetchConcurrency returns the size of the worker pool used to fan out
+// Prometheus queries, configurable via SCHED_FETCH_CONCURRENCY (default 8).
+func fetchConcurrency() int {
+	concurrency := 8
+	if val := os.Getenv("SCHED_FETCH_CONCURRENCY"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			concurrency = parsed
 		}
+	}
+	return concurrency
+}
 
-		if diskReadTotal == 0 && len(diskReadMetrics) > 0 {
-			// If node not found but metrics exist, use first available
-			for _, readRate := range diskReadMetrics {
-				diskReadTotal = readRate
-				break
+// runQueryPool executes the given statQueries concurrently across a bounded
+// worker pool, honoring ctx for cancellation/timeout, and returns a result
+// per query name. It is the shared fan-out used by both GetNodeStats and the
+// cluster-wide collector in updateAllNodeStats.
+func runQueryPool(ctx context.Context, queries []statQuery) map[string]queryResult {
+	jobs := make(chan statQuery)
+	resultsCh := make(chan queryResult, len(queries))
+
+	var wg sync.WaitGroup
+	for i := 0; i < fetchConcurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range jobs {
+				select {
+				case <-ctx.Done():
+					resultsCh <- queryResult{name: q.name, err: ctx.Err()}
+				default:
+					queryStart := time.Now()
+					metrics, err := QueryPrometheus(q.query)
+					prometheusQueryDuration.WithLabelValues(q.name).Observe(time.Since(queryStart).Seconds())
+					resultsCh <- queryResult{name: q.name, metrics: metrics, err: err}
+				}
 			}
-		}
+		}()
+	}
 
-		if diskReadTotal == 0 {
-			log.Printf("Warning: No disk read metrics found for node %s, using default values", nodeName)
-			stats.DiskReadTotal = 500 * 1024 * 1024 // 500 MB/s
-			stats.DiskReadFree = 300 * 1024 * 1024  // 300 MB/s
-		} else {
-			// Set total capacity at 3x the current rate (estimation)
-			stats.DiskReadTotal = 3 * diskReadTotal
-			// Free capacity is total minus the current rate
-			stats.DiskReadFree = stats.DiskReadTotal - diskReadTotal
-			if stats.DiskReadFree < 0 {
-				stats.DiskReadFree = 0
+	go func() {
+		defer close(jobs)
+		for _, q := range queries {
+			select {
+			case jobs <- q:
+			case <-ctx.Done():
+				return
 			}
 		}
-	}
+	}()
 
-	// Query disk write rate (bytes per second)
-	diskWriteQuery := "rate(node_disk_written_bytes_total[5m])"
-	diskWriteMetrics, err := QueryPrometheus(diskWriteQuery)
-	if err != nil {
-		log.Printf("Warning: Failed to fetch disk write metrics: %v", err)
-		stats.DiskWriteTotal = 200 * 1024 * 1024 // 200 MB/s default
-		stats.DiskWriteFree = 100 * 1024 * 1024  // 100 MB/s default
-	} else {
-		diskWriteTotal := 0.0
+	go func() {
+		wg.Wait()
+		close(resultsCh)
+	}()
 
-		// Sum up disk write rates across all disks for this node
-		for instance, writeRate := range diskWriteMetrics {
-			if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-				diskWriteTotal += writeRate
-			}
+	results := make(map[string]queryResult, len(queries))
+	for r := range resultsCh {
+		results[r.name] = r
+	}
+	return results
+}
+
+// valueForNode picks the metric value for nodeName out of a cluster-wide
+// metrics map, falling back to a fuzzy instance match and finally to any
+// available sample, mirroring the lookup GetNodeStats has always used.
+func valueForNode(nodeName string, metrics map[string]float64) (float64, bool) {
+	if v, exists := metrics[nodeName+":9100"]; exists {
+		return v, true
+	}
+	for instance, v := range metrics {
+		if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
+			return v, true
 		}
+	}
+	for _, v := range metrics {
+		return v, true
+	}
+	return 0, false
+}
+
+// buildNodeStats assembles a NodeStats for nodeName out of already-fetched
+// cluster-wide metric maps, applying the same defaults GetNodeStats has
+// always fallen back to when a node or query is missing.
+func buildNodeStats(nodeName string, results map[string]queryResult) NodeStats {
+	var stats NodeStats
 
-		if diskWriteTotal == 0 && len(diskWriteMetrics) > 0 {
-			// If node not found but metrics exist, use first available
-			for _, writeRate := range diskWriteMetrics {
-				diskWriteTotal = writeRate
-				break
+	if r, ok := results["cpu"]; ok && r.err == nil {
+		if usage, found := valueForNode(nodeName, r.metrics); found {
+			stats.CPUTotal = 6.0
+			stats.CPUFree = stats.CPUTotal - usage
+			if stats.CPUFree < 0 {
+				stats.CPUFree = 0
 			}
+		} else {
+			stats.CPUTotal, stats.CPUFree = 6.0, 2.0
 		}
+	} else {
+		stats.CPUTotal, stats.CPUFree = 6.0, 2.0
+	}
 
-		if diskWriteTotal == 0 {
-			log.Printf("Warning: No disk write metrics found for node %s, using default values", nodeName)
-			stats.DiskWriteTotal = 200 * 1024 * 1024 // 200 MB/s
-			stats.DiskWriteFree = 100 * 1024 * 1024  // 100 MB/s
+	if r, ok := results["memTotal"]; ok && r.err == nil {
+		if total, found := valueForNode(nodeName, r.metrics); found {
+			stats.MemTotal = total
 		} else {
-			// Set total capacity at 3x the current rate (estimation)
-			stats.DiskWriteTotal = 3 * diskWriteTotal
-			// Free capacity is total minus the current rate
-			stats.DiskWriteFree = stats.DiskWriteTotal - diskWriteTotal
-			if stats.DiskWriteFree < 0 {
-				stats.DiskWriteFree = 0
-			}
+			stats.MemTotal = 32 * 1024 * 1024 * 1024
 		}
+	} else {
+		stats.MemTotal = 32 * 1024 * 1024 * 1024
 	}
 
-	// ---------- Network Metrics ----------
-	// Query network upload rate (bytes per second)
-	netUpQuery := "rate(node_network_transmit_bytes_total[5m])"
-	netUpMetrics, err := QueryPrometheus(netUpQuery)
-	if err != nil {
-		log.Printf("Warning: Failed to fetch network upload metrics: %v", err)
-		stats.NetUpTotal = 100 * 1024 * 1024 // 100 MB/s default
-		stats.NetUpFree = 80 * 1024 * 1024   // 80 MB/s default
+	if r, ok := results["memFree"]; ok && r.err == nil {
+		if free, found := valueForNode(nodeName, r.metrics); found {
+			stats.MemFree = free
+		} else {
+			stats.MemFree = 16 * 1024 * 1024 * 1024
+		}
 	} else {
-		netUpTotal := 0.0
+		stats.MemFree = 16 * 1024 * 1024 * 1024
+	}
 
-		// Sum up network transmit rates across all interfaces for this node
-		for instance, upRate := range netUpMetrics {
-			if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-				netUpTotal += upRate
+	if r, ok := results["diskRead"]; ok && r.err == nil {
+		if rate, found := valueForNode(nodeName, r.metrics); found {
+			stats.DiskReadTotal = 3 * rate
+			stats.DiskReadFree = stats.DiskReadTotal - rate
+			if stats.DiskReadFree < 0 {
+				stats.DiskReadFree = 0
 			}
+		} else {
+			stats.DiskReadTotal, stats.DiskReadFree = 500*1024*1024, 300*1024*1024
 		}
+	} else {
+		stats.DiskReadTotal, stats.DiskReadFree = 500*1024*1024, 300*1024*1024
+	}
 
-		if netUpTotal == 0 && len(netUpMetrics) > 0 {
-			// If node not found but metrics exist, use first available
-			for _, upRate := range netUpMetrics {
-				netUpTotal = upRate
-				break
+	if r, ok := results["diskWrite"]; ok && r.err == nil {
+		if rate, found := valueForNode(nodeName, r.metrics); found {
+			stats.DiskWriteTotal = 3 * rate
+			stats.DiskWriteFree = stats.DiskWriteTotal - rate
+			if stats.DiskWriteFree < 0 {
+				stats.DiskWriteFree = 0
 			}
+		} else {
+			stats.DiskWriteTotal, stats.DiskWriteFree = 200*1024*1024, 100*1024*1024
 		}
+	} else {
+		stats.DiskWriteTotal, stats.DiskWriteFree = 200*1024*1024, 100*1024*1024
+	}
 
-		if netUpTotal == 0 {
-			log.Printf("Warning: No network upload metrics found for node %s, using default values", nodeName)
-			stats.NetUpTotal = 100 * 1024 * 1024 // 100 MB/s
-			stats.NetUpFree = 80 * 1024 * 1024   // 80 MB/s
-		} else {
-			// Set total capacity at 5x the current rate (estimation)
-			stats.NetUpTotal = 5 * netUpTotal
-			// Free capacity is total minus the current rate
-			stats.NetUpFree = stats.NetUpTotal - netUpTotal
+	if r, ok := results["netUp"]; ok && r.err == nil {
+		if rate, found := valueForNode(nodeName, r.metrics); found {
+			stats.NetUpTotal = 5 * rate
+			stats.NetUpFree = stats.NetUpTotal - rate
 			if stats.NetUpFree < 0 {
 				stats.NetUpFree = 0
 			}
+		} else {
+			stats.NetUpTotal, stats.NetUpFree = 100*1024*1024, 80*1024*1024
 		}
-	}
-
-	// Query network download rate (bytes per second)
-	netDownQuery := "rate(node_network_receive_bytes_total[5m])"
-	netDownMetrics, err := QueryPrometheus(netDownQuery)
-	if err != nil {
-		log.Printf("Warning: Failed to fetch network download metrics: %v", err)
-		stats.NetDownTotal = 200 * 1024 * 1024 // 200 MB/s default
-		stats.NetDownFree = 150 * 1024 * 1024  // 150 MB/s default
 	} else {
-		netDownTotal := 0.0
+		stats.NetUpTotal, stats.NetUpFree = 100*1024*1024, 80*1024*1024
+	}
 
-		// Sum up network receive rates across all interfaces for this node
-		for instance, downRate := range netDownMetrics {
-			if strings.Contains(instance, nodeName) || strings.Contains(nodeName, strings.Split(instance, ":")[0]) {
-				netDownTotal += downRate
+	if r, ok := results["netDown"]; ok && r.err == nil {
+		if rate, found := valueForNode(nodeName, r.metrics); found {
+			stats.NetDownTotal = 5 * rate
+			stats.NetDownFree = stats.NetDownTotal - rate
+			if stats.NetDownFree < 0 {
+				stats.NetDownFree = 0
 			}
+		} else {
+			stats.NetDownTotal, stats.NetDownFree = 200*1024*1024, 150*1024*1024
 		}
+	} else {
+		stats.NetDownTotal, stats.NetDownFree = 200*1024*1024, 150*1024*1024
+	}
 
-		if netDownTotal == 0 && len(netDownMetrics) > 0 {
-			// If node not found but metrics exist, use first available
-			for _, downRate := range netDownMetrics {
-				netDownTotal = downRate
-				break
-			}
-		}
+	return stats
+}
 
-		if netDownTotal == 0 {
-			log.Printf("Warning: No network download metrics found for node %s, using default values", nodeName)
-			stats.NetDownTotal = 200 * 1024 * 1024 // 200 MB/s
-			stats.NetDownFree = 150 * 1024 * 1024  // 150 MB/s
-		} else {
-			// Set total capacity at 5x the current rate (estimation)
-			stats.NetDownTotal = 5 * netDownTotal
-			// Free capacity is total minus the current rate
-			stats.NetDownFree = stats.NetDownTotal - netDownTotal
-			if stats.NetDownFree < 0 {
-				stats.NetDownFree = 0
-			}
+// getNodeStats gathers metrics from Prometheus for a given node, fanning the
+// underlying queries out across the shared worker pool. It returns an error
+// when the CPU query fails (Prometheus treated as unreachable, as in
+// updateAllNodeStats), so a StatsSourceChain moves on to its next source;
+// other failed queries fall back to buildNodeStats' defaults.
+func GetNodeStats(nodeName string) (NodeStats, error) {
+	log.Printf("Fetching stats for node: %s", nodeName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	results := runQueryPool(ctx, statQueries)
+	for name, r := range results {
+		if r.err != nil {
+			log.Printf("Warning: Failed to fetch %s metrics: %v", name, r.err)
 		}
 	}
+	if r, ok := results["cpu"]; !ok || r.err != nil {
+		return NodeStats{}, fmt.Errorf("Prometheus unreachable: CPU query for node %s failed", nodeName)
+	}
 
-	return stats, nil
+	return buildNodeStats(nodeName, results), nil
 }
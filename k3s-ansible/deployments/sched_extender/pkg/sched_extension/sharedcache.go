@@ -0,0 +1,167 @@
+package sched_extension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SharedCache lets multiple extender replicas agree on one NodeStats
+// snapshot: only the leader writes and the followers read (the leader
+// serves from its own local cache, which holds the values it writes), so
+// Filter/Prioritize see the same view regardless of which pod handles the
+// request.
+type SharedCache interface {
+	// Get returns the cached stats for a node and when they were written.
+	// ok is false if the node has no entry at all.
+	Get(nodeName string) (stats NodeStats, writtenAt time.Time, ok bool)
+	// Set writes stats for a node, stamped with the current time.
+	Set(nodeName string, stats NodeStats) error
+}
+
+// sharedCacheEntry is the JSON envelope stored per node, carrying the write
+// time alongside the stats so readers can apply the staleness guard.
+type sharedCacheEntry struct {
+	Stats     NodeStats `json:"stats"`
+	WrittenAt time.Time `json:"writtenAt"`
+}
+
+// maxStaleAge returns how old a shared cache entry may be before a follower
+// treats it as missing rather than scheduling on stale data, configured via
+// MULTIRESOURCE_MAX_STAGE_AGE (default 2m).
+func maxStaleAge() time.Duration {
+	if val := os.Getenv("MULTIRESOURCE_MAX_STAGE_AGE"); val != "" {
+		if d, err := time.ParseDuration(val); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 2 * time.Minute
+}
+
+// buildSharedCache picks Redis when MULTIRESOURCE_REDIS_ADDR is set,
+// otherwise a ConfigMap in the extender's own namespace; it returns nil if
+// neither a Redis address nor a Kubernetes client is available, in which
+// case followers fall back to the per-replica StatsSource chain.
+func buildSharedCache(client kubernetes.Interface) SharedCache {
+	if addr := os.Getenv("MULTIRESOURCE_REDIS_ADDR"); addr != "" {
+		return NewRedisCache(addr)
+	}
+	if client == nil {
+		return nil
+	}
+	return NewConfigMapCache(client, extenderNamespace())
+}
+
+// extenderNamespace is the namespace the extender itself runs in, used for
+// both the leader election Lease and the ConfigMap shared cache.
+func extenderNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	return "default"
+}
+
+// ConfigMapCache stores one JSON-encoded sharedCacheEntry per node as a key
+// in a single ConfigMap, avoiding the need for any extra infrastructure.
+type ConfigMapCache struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapCache builds a ConfigMapCache backed by
+// "multiresource-node-stats" in namespace.
+func NewConfigMapCache(client kubernetes.Interface, namespace string) *ConfigMapCache {
+	return &ConfigMapCache{client: client, namespace: namespace, name: "multiresource-node-stats"}
+}
+
+func (c *ConfigMapCache) Get(nodeName string) (NodeStats, time.Time, bool) {
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(context.Background(), c.name, metav1.GetOptions{})
+	if err != nil {
+		return NodeStats{}, time.Time{}, false
+	}
+	raw, ok := cm.Data[nodeName]
+	if !ok {
+		return NodeStats{}, time.Time{}, false
+	}
+	var entry sharedCacheEntry
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+		return NodeStats{}, time.Time{}, false
+	}
+	return entry.Stats, entry.WrittenAt, true
+}
+
+func (c *ConfigMapCache) Set(nodeName string, stats NodeStats) error {
+	entry := sharedCacheEntry{Stats: stats, WrittenAt: time.Now()}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling shared cache entry for %s: %v", nodeName, err)
+	}
+
+	ctx := context.Background()
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: c.name, Namespace: c.namespace},
+			Data:       map[string]string{},
+		}
+		cm.Data[nodeName] = string(raw)
+		_, err = c.client.CoreV1().ConfigMaps(c.namespace).Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("getting shared cache ConfigMap: %v", err)
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[nodeName] = string(raw)
+	_, err = c.client.CoreV1().ConfigMaps(c.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// RedisCache stores each node's entry under its own key, which scales
+// better than the ConfigMap's single-object-per-write-contention model for
+// clusters with many nodes and frequent refreshes.
+type RedisCache struct {
+	client *redis.Client
+}
+
+// NewRedisCache connects to an in-cluster Redis at addr.
+func NewRedisCache(addr string) *RedisCache {
+	return &RedisCache{client: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func (r *RedisCache) key(nodeName string) string {
+	return fmt.Sprintf("multiresource:nodestats:%s", nodeName)
+}
+
+func (r *RedisCache) Get(nodeName string) (NodeStats, time.Time, bool) {
+	raw, err := r.client.Get(context.Background(), r.key(nodeName)).Result()
+	if err != nil {
+		return NodeStats{}, time.Time{}, false
+	}
+	var entry sharedCacheEntry
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+		return NodeStats{}, time.Time{}, false
+	}
+	return entry.Stats, entry.WrittenAt, true
+}
+
+func (r *RedisCache) Set(nodeName string, stats NodeStats) error {
+	entry := sharedCacheEntry{Stats: stats, WrittenAt: time.Now()}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling shared cache entry for %s: %v", nodeName, err)
+	}
+	return r.client.Set(context.Background(), r.key(nodeName), raw, maxStaleAge()*2).Err()
+}
@@ -0,0 +1,101 @@
+package sched_extension
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// StatsSource abstracts where a single node's NodeStats comes from, so the
+// extender can try Prometheus first and degrade gracefully instead of
+// silently handing out the same hardcoded constants to every caller.
+type StatsSource interface {
+	// Name identifies the source for logging.
+	Name() string
+	// GetNodeStats returns stats for nodeName, or an error if this source
+	// could not produce them.
+	GetNodeStats(nodeName string) (NodeStats, error)
+}
+
+// PrometheusSource is the existing Prometheus-backed collector.
+type PrometheusSource struct{}
+
+func (PrometheusSource) Name() string { return "prometheus" }
+
+func (PrometheusSource) GetNodeStats(nodeName string) (NodeStats, error) {
+	return GetNodeStats(nodeName)
+}
+
+// SidecarSource queries a per-node gopsutil sidecar (see localstats.go)
+// over HTTP, using NodeIP to resolve the node's internal IP.
+type SidecarSource struct {
+	Port   int
+	NodeIP func(nodeName string) (string, error)
+}
+
+func (s SidecarSource) Name() string { return "gopsutil-sidecar" }
+
+func (s SidecarSource) GetNodeStats(nodeName string) (NodeStats, error) {
+	if s.NodeIP == nil {
+		return NodeStats{}, fmt.Errorf("sidecar source has no node IP resolver")
+	}
+	ip, err := s.NodeIP(nodeName)
+	if err != nil {
+		return NodeStats{}, fmt.Errorf("resolving IP for node %s: %v", nodeName, err)
+	}
+
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s:%d/nodestats", ip, s.Port))
+	if err != nil {
+		return NodeStats{}, fmt.Errorf("querying sidecar on node %s: %v", nodeName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return NodeStats{}, fmt.Errorf("sidecar on node %s returned status %d", nodeName, resp.StatusCode)
+	}
+
+	var stats NodeStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return NodeStats{}, fmt.Errorf("decoding sidecar response for node %s: %v", nodeName, err)
+	}
+	return stats, nil
+}
+
+// defaultNodeStats returns the conservative constants the extender has
+// always used as an absolute last resort, when no StatsSource in the
+// chain could produce real numbers.
+func defaultNodeStats() NodeStats {
+	return NodeStats{
+		CPUTotal:       6.0,
+		CPUFree:        2.0,
+		MemTotal:       32 * 1024 * 1024 * 1024,
+		MemFree:        16 * 1024 * 1024 * 1024,
+		DiskReadTotal:  500 * 1024 * 1024,
+		DiskReadFree:   300 * 1024 * 1024,
+		DiskWriteTotal: 200 * 1024 * 1024,
+		DiskWriteFree:  100 * 1024 * 1024,
+		NetUpTotal:     100 * 1024 * 1024,
+		NetUpFree:      80 * 1024 * 1024,
+		NetDownTotal:   200 * 1024 * 1024,
+		NetDownFree:    150 * 1024 * 1024,
+	}
+}
+
+// StatsSourceChain tries each source in order, falling back to the next on
+// error, and finally to defaultNodeStats if every source fails.
+type StatsSourceChain []StatsSource
+
+func (chain StatsSourceChain) GetNodeStats(nodeName string) NodeStats {
+	for _, src := range chain {
+		stats, err := src.GetNodeStats(nodeName)
+		if err == nil {
+			return stats
+		}
+		log.Printf("StatsSource %s failed for node %s: %v", src.Name(), nodeName, err)
+	}
+	log.Printf("Warning: all stats sources failed for node %s, using defaults", nodeName)
+	return defaultNodeStats()
+}
@@ -2,6 +2,7 @@ package sched_extension
 
 import (
 	"log"
+	"os"
 	"strconv"
 	"strings"
 
@@ -68,8 +69,15 @@ func parseResourceAnnotation(pod *v1.Pod, key string, defaultValue float64) floa
 	return defaultValue
 }
 
-// estimateIORequirements estimates I/O requirements based on pod properties
+// estimateIORequirements estimates I/O requirements based on pod properties.
+// If pod is a re-creation of a workload we've already learned a profile for
+// (see workload_profile.go), its own historical p95 usage is used instead of
+// the annotations and image-name heuristics below.
 func estimateIORequirements(pod *v1.Pod) (diskRead, diskWrite, netUp, netDown float64) {
+	if profile, ok := lookupWorkloadProfile(pod); ok {
+		return profile.DiskRead, profile.DiskWrite, profile.NetUp, profile.NetDown
+	}
+
 	// Default values
 	diskRead = 10 * 1024 * 1024 // 10 MB/s
 	diskWrite = 5 * 1024 * 1024 // 5 MB/s
@@ -246,3 +254,98 @@ func max(a, b float64) float64 {
 	}
 	return b
 }
+
+// NodeStatsHistory holds a short window of recent samples per tracked
+// resource for a node, used to detect trends (e.g. CPU climbing toward
+// saturation) that an instantaneous snapshot would miss.
+type NodeStatsHistory struct {
+	CPU       []float64
+	Mem       []float64
+	DiskRead  []float64
+	DiskWrite []float64
+	NetUp     []float64
+	NetDown   []float64
+}
+
+// slope computes the simple linear regression slope of samples against
+// their index (0, 1, 2, ...), i.e. how fast the series is rising or
+// falling per sample. A positive slope means the resource is trending
+// toward higher usage.
+func slope(samples []float64) float64 {
+	n := len(samples)
+	if n < 2 {
+		return 0
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range samples {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	nf := float64(n)
+	denominator := nf*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (nf*sumXY - sumX*sumY) / denominator
+}
+
+// trendWeightEnv reads MULTIRESOURCE_TREND_WEIGHT, defaulting to 0.2. It
+// controls how strongly a rising utilization trend penalizes a node's
+// score even when its instantaneous free resources look sufficient.
+func trendWeightEnv() float64 {
+	weight := 0.2
+	if val := os.Getenv("MULTIRESOURCE_TREND_WEIGHT"); val != "" {
+		if parsed, err := strconv.ParseFloat(val, 64); err == nil {
+			weight = parsed
+		}
+	}
+	return weight
+}
+
+// ScoreMultiResourceTrendAware extends ScoreMultiResource by penalizing
+// nodes whose resource utilization is trending upward: a node that
+// currently has room but is climbing toward saturation scores worse than
+// one with the same free resources but a flat or falling trend.
+func ScoreMultiResourceTrendAware(podReq PodRequest, stats NodeStats, history NodeStatsHistory, maxScore int) int {
+	baseScore := ScoreMultiResource(podReq, stats, maxScore)
+
+	trends := []struct {
+		samples []float64
+		total   float64
+	}{
+		{history.CPU, stats.CPUTotal},
+		{history.Mem, stats.MemTotal},
+		{history.DiskRead, stats.DiskReadTotal},
+		{history.DiskWrite, stats.DiskWriteTotal},
+		{history.NetUp, stats.NetUpTotal},
+		{history.NetDown, stats.NetDownTotal},
+	}
+
+	worstNormalizedSlope := 0.0
+	for _, t := range trends {
+		if t.total <= 0 || len(t.samples) < 2 {
+			continue
+		}
+		// Normalize the slope (units/sample) against total capacity so it
+		// is comparable across CPU cores, bytes of memory, and bytes/sec.
+		normalized := slope(t.samples) / t.total
+		if normalized > worstNormalizedSlope {
+			worstNormalizedSlope = normalized
+		}
+	}
+
+	penalty := int(float64(maxScore) * trendWeightEnv() * worstNormalizedSlope)
+	score := baseScore - penalty
+	if score < 0 {
+		score = 0
+	}
+	if score > maxScore {
+		score = maxScore
+	}
+	return score
+}
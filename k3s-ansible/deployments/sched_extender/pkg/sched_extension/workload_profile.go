@@ -0,0 +1,283 @@
+package sched_extension
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// workloadIDAnnotation lets an operator pin a pod's workload identity
+// explicitly (e.g. for a CronJob whose generated pod names/owners change
+// every run) instead of relying on the owner-reference fallback.
+const workloadIDAnnotation = "scheduler.extender/workload-id"
+
+// WorkloadProfile holds the learned steady-state I/O demand for a workload,
+// derived from its own historical usage rather than the image-name
+// heuristics in estimateIORequirements.
+type WorkloadProfile struct {
+	DiskRead  float64   `json:"diskRead"`
+	DiskWrite float64   `json:"diskWrite"`
+	NetUp     float64   `json:"netUp"`
+	NetDown   float64   `json:"netDown"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// workloadIdentity returns the key a WorkloadProfile is tracked under: the
+// workload-id annotation if the operator set one, otherwise the owning
+// controller's kind/name scoped to the pod's namespace so every pod a
+// ReplicaSet/StatefulSet re-creates resolves to the same profile. A
+// CronJob's pods are owned by a Job with a new name each run, so they share
+// a profile only through the annotation.
+// Returns false if the pod has neither, since a bare unowned pod is never
+// "re-created" and has no history worth learning from.
+func workloadIdentity(pod *v1.Pod) (string, bool) {
+	if id := pod.Annotations[workloadIDAnnotation]; id != "" {
+		return pod.Namespace + "/" + id, true
+	}
+	for _, owner := range pod.OwnerReferences {
+		return pod.Namespace + "/" + owner.Kind + "/" + owner.Name, true
+	}
+	return "", false
+}
+
+// profilePath returns where learned workload profiles are persisted,
+// configurable via SCHED_EXTENDER_PROFILE_CACHE. Empty disables persistence
+// (profiles are still learned in memory for the life of the process).
+func profilePath() string {
+	return os.Getenv("SCHED_EXTENDER_PROFILE_CACHE")
+}
+
+// profileHistoryWindows are the lookback windows learnWorkloadProfile
+// queries when learning a workload's profile: a short window that reacts to
+// recent behavior and a long one that smooths over it. The higher of the
+// windows' p95 values is kept for each metric.
+var profileHistoryWindows = []time.Duration{1 * time.Hour, 24 * time.Hour}
+
+// profileStore caches learned WorkloadProfiles in memory, backed by an
+// on-disk JSON snapshot so profiles survive scheduler restarts instead of
+// being re-learned from scratch on every rollout.
+type profileStore struct {
+	mu       sync.RWMutex
+	path     string
+	profiles map[string]WorkloadProfile
+}
+
+var (
+	defaultProfileStoreOnce sync.Once
+	defaultProfileStore     *profileStore
+)
+
+// getProfileStore returns the process-wide profileStore, loading it from
+// disk on first use.
+func getProfileStore() *profileStore {
+	defaultProfileStoreOnce.Do(func() {
+		defaultProfileStore = newProfileStore(profilePath())
+	})
+	return defaultProfileStore
+}
+
+func newProfileStore(path string) *profileStore {
+	s := &profileStore{path: path, profiles: make(map[string]WorkloadProfile)}
+	s.load()
+	return s
+}
+
+// load restores profiles from disk, if a cache file exists. A missing or
+// unreadable file just leaves the store empty rather than failing startup.
+func (s *profileStore) load() {
+	if s.path == "" {
+		return
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	var profiles map[string]WorkloadProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		log.Printf("Warning: error parsing workload profile cache %s: %v", s.path, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles = profiles
+}
+
+// save persists the current profiles to disk, if a cache path is configured.
+func (s *profileStore) save() {
+	if s.path == "" {
+		return
+	}
+	s.mu.RLock()
+	data, err := json.Marshal(s.profiles)
+	s.mu.RUnlock()
+	if err != nil {
+		log.Printf("Warning: error marshaling workload profile cache: %v", err)
+		return
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		log.Printf("Warning: error writing workload profile cache to %s: %v", s.path, err)
+	}
+}
+
+func (s *profileStore) get(id string) (WorkloadProfile, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.profiles[id]
+	return p, ok
+}
+
+func (s *profileStore) set(id string, p WorkloadProfile) {
+	s.mu.Lock()
+	s.profiles[id] = p
+	s.mu.Unlock()
+	s.save()
+}
+
+// mean returns the arithmetic mean of samples, or 0 for an empty slice.
+func mean(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	return sum / float64(len(samples))
+}
+
+// aggregateSeries reduces samples down to a single representative value
+// using the requested aggregation mode ("avg", "p95", or "max"; anything
+// else falls back to "avg").
+func aggregateSeries(samples []float64, agg string) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	switch agg {
+	case "max":
+		m := samples[0]
+		for _, s := range samples[1:] {
+			if s > m {
+				m = s
+			}
+		}
+		return m
+	case "p95":
+		sorted := append([]float64(nil), samples...)
+		sort.Float64s(sorted)
+		idx := int(float64(len(sorted)-1) * 0.95)
+		return sorted[idx]
+	default:
+		return mean(samples)
+	}
+}
+
+// QueryPrometheusRangeAggregated runs query over [start, end] at step and
+// reduces each instance's series down to a single float via agg
+// ("avg"/"p95"/"max"), so callers doing workload profiling don't each have
+// to re-implement series aggregation on top of QueryPrometheusRange.
+func QueryPrometheusRangeAggregated(query string, start, end time.Time, step time.Duration, agg string) (map[string]float64, error) {
+	series, err := QueryPrometheusRange(query, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+	aggregated := make(map[string]float64, len(series))
+	for instance, samples := range series {
+		aggregated[instance] = aggregateSeries(samples, agg)
+	}
+	return aggregated, nil
+}
+
+// workloadProfileQueries names the PromQL used to learn a workload's
+// historical I/O demand from its own container metrics, selected by the
+// pod label the cluster's monitoring stack attaches and grouped by pod and
+// instance, since QueryPrometheusRange keys its series by instance.
+var workloadProfileQueries = map[string]string{
+	"diskRead":  `sum(rate(container_fs_reads_bytes_total{pod=~"%s"}[5m])) by (pod, instance)`,
+	"diskWrite": `sum(rate(container_fs_writes_bytes_total{pod=~"%s"}[5m])) by (pod, instance)`,
+	"netUp":     `sum(rate(container_network_transmit_bytes_total{pod=~"%s"}[5m])) by (pod, instance)`,
+	"netDown":   `sum(rate(container_network_receive_bytes_total{pod=~"%s"}[5m])) by (pod, instance)`,
+}
+
+// podNamePattern turns a pod's first owner's name into a regex matching every
+// pod that owner has created (e.g. a pod of ReplicaSet "web-7d9f6" ->
+// "web-7d9f6.*"), so the profile query covers the owner's full history, not
+// just this one pod incarnation. A pod with no owner matches its own name.
+func podNamePattern(pod *v1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		return strings.TrimSuffix(owner.Name, "-") + ".*"
+	}
+	return pod.Name
+}
+
+// learnWorkloadProfile queries Prometheus for pod's own historical disk/
+// network usage over profileHistoryWindows and returns the p95 across
+// whichever window reports the higher demand, erring on the side of
+// over-provisioning a known-bursty workload rather than under-provisioning it.
+func learnWorkloadProfile(pod *v1.Pod) (WorkloadProfile, error) {
+	pattern := podNamePattern(pod)
+	profile := WorkloadProfile{UpdatedAt: time.Now()}
+
+	end := time.Now()
+	for _, window := range profileHistoryWindows {
+		start := end.Add(-window)
+		for metric, queryTemplate := range workloadProfileQueries {
+			query := fmt.Sprintf(queryTemplate, pattern)
+			values, err := QueryPrometheusRangeAggregated(query, start, end, 5*time.Minute, "p95")
+			if err != nil {
+				return WorkloadProfile{}, err
+			}
+			v, _ := valueForNode(pod.Spec.NodeName, values)
+			switch metric {
+			case "diskRead":
+				profile.DiskRead = max(profile.DiskRead, v)
+			case "diskWrite":
+				profile.DiskWrite = max(profile.DiskWrite, v)
+			case "netUp":
+				profile.NetUp = max(profile.NetUp, v)
+			case "netDown":
+				profile.NetDown = max(profile.NetDown, v)
+			}
+		}
+	}
+
+	return profile, nil
+}
+
+// lookupWorkloadProfile returns the learned I/O profile for pod's workload,
+// refreshing it from Prometheus on a cache miss and persisting the result so
+// later pods from the same workload reuse it without re-querying. Returns
+// false if pod isn't part of a re-creatable workload or no profile could be
+// learned for it (e.g. it has no history yet).
+func lookupWorkloadProfile(pod *v1.Pod) (WorkloadProfile, bool) {
+	id, ok := workloadIdentity(pod)
+	if !ok {
+		return WorkloadProfile{}, false
+	}
+
+	store := getProfileStore()
+	if profile, cached := store.get(id); cached {
+		return profile, true
+	}
+
+	profile, err := learnWorkloadProfile(pod)
+	if err != nil {
+		log.Printf("Warning: could not learn workload profile for %s: %v", id, err)
+		return WorkloadProfile{}, false
+	}
+	if profile.DiskRead == 0 && profile.DiskWrite == 0 && profile.NetUp == 0 && profile.NetDown == 0 {
+		// No history yet (e.g. first pod of a brand new workload); let the
+		// image-name heuristics handle it instead of caching an all-zero profile.
+		return WorkloadProfile{}, false
+	}
+
+	store.set(id, profile)
+	return profile, true
+}